@@ -17,6 +17,7 @@ limitations under the License.
 package validating
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"strings"
@@ -593,6 +594,10 @@ func (r *fakeSchemaResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.S
 	return r.schemaToReturn, nil
 }
 
+func (r *fakeSchemaResolver) ResolveSchemaWithContext(_ context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchema(gvk)
+}
+
 func toBeEmpty(warnings []v1.ExpressionWarning, t *testing.T) {
 	if len(warnings) != 0 {
 		t.Fatalf("expected empty but got %v", warnings)
@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+)
+
+// ErrStaleDiscovery is wrapped and returned by a ClientDiscoveryResolver with
+// RequireFresh if aggregated discovery reports the group-version that a
+// schema was resolved from as stale.
+var ErrStaleDiscovery = errors.New("stale discovery")
+
+// StaleGroupVersions returns the group-versions that aggregated discovery
+// reports as stale, e.g. those of an aggregated apiserver that did not respond
+// lately, whose documents may be outdated or missing. A schema of a stale
+// group-version may still resolve from the last document the apiserver
+// aggregated. The OpenAPI v3 documents are laid out by group-version the
+// same way with and without aggregated discovery, so this is the only
+// information that aggregation adds for resolution.
+// If Discovery does not implement discovery.AggregatedDiscoveryInterface, or
+// the server does not support aggregated discovery, no group-version is
+// reported as stale.
+func (r *ClientDiscoveryResolver) StaleGroupVersions() (sets.Set[schema.GroupVersion], error) {
+	result := sets.New[schema.GroupVersion]()
+	aggregated, ok := r.Discovery.(discovery.AggregatedDiscoveryInterface)
+	if !ok {
+		return result, nil
+	}
+	_, _, failed, err := aggregated.GroupsAndMaybeResources()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list the stale group versions: %w: %w", ErrDiscoveryUnavailable, err)
+	}
+	for gv, err := range failed {
+		var stale discovery.StaleGroupVersionError
+		if errors.As(err, &stale) {
+			result.Insert(gv)
+		}
+	}
+	return result, nil
+}
+
+// IsStale checks if aggregated discovery reports the group-version as stale,
+// see StaleGroupVersions.
+func (r *ClientDiscoveryResolver) IsStale(gv schema.GroupVersion) (bool, error) {
+	stale, err := r.StaleGroupVersions()
+	if err != nil {
+		return false, err
+	}
+	return stale.Has(gv), nil
+}
+
+// checkFresh returns an error wrapping ErrStaleDiscovery if the group-version
+// of the schema resolved from source is stale, which is the substituted
+// group-version if another version was substituted for the requested one.
+func (r *ClientDiscoveryResolver) checkFresh(source ResolveSource) error {
+	stale, err := r.IsStale(source.GVK.GroupVersion())
+	if err != nil {
+		return err
+	}
+	if stale {
+		return staleError(source.GVK)
+	}
+	return nil
+}
+
+// staleError returns the error of a schema of gvk resolved from a stale
+// document.
+func staleError(gvk schema.GroupVersionKind) error {
+	return fmt.Errorf("the document of %q that %v was resolved from is stale: %w", gvk.GroupVersion(), gvk, ErrStaleDiscovery)
+}
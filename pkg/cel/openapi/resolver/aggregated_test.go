@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/openapi/openapitest"
+)
+
+// fakeAggregatedDiscovery serves aggregated discovery that reports the given
+// failed group-versions.
+type fakeAggregatedDiscovery struct {
+	*fakeDiscovery
+	failed map[schema.GroupVersion]error
+	err    error
+}
+
+func (d *fakeAggregatedDiscovery) GroupsAndMaybeResources() (*metav1.APIGroupList, map[schema.GroupVersion]*metav1.APIResourceList, map[schema.GroupVersion]error, error) {
+	return &metav1.APIGroupList{}, nil, d.failed, d.err
+}
+
+func TestStaleGroupVersions(t *testing.T) {
+	metrics := schema.GroupVersion{Group: "metrics.k8s.io", Version: "v1beta1"}
+	apps := schema.GroupVersion{Group: "apps", Version: "v1"}
+	failing := schema.GroupVersion{Group: "failing.example.com", Version: "v1"}
+	aggregated := &fakeAggregatedDiscovery{
+		fakeDiscovery: newFakeDiscovery(openapitest.NewEmbeddedFileClient()),
+		failed: map[schema.GroupVersion]error{
+			metrics: discovery.StaleGroupVersionError{},
+			failing: errors.New("unavailable"),
+		},
+	}
+
+	for _, tc := range []struct {
+		name      string
+		discovery discovery.DiscoveryInterface
+		wantStale []schema.GroupVersion
+		wantErr   bool
+	}{
+		{name: "aggregated", discovery: aggregated, wantStale: []schema.GroupVersion{metrics}},
+		{name: "classic", discovery: newFakeDiscovery(openapitest.NewEmbeddedFileClient())},
+		{name: "error", discovery: &fakeAggregatedDiscovery{fakeDiscovery: aggregated.fakeDiscovery, err: errors.New("unavailable")}, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &ClientDiscoveryResolver{Discovery: tc.discovery}
+			stale, err := r.StaleGroupVersions()
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if stale.Len() != len(tc.wantStale) || !stale.HasAll(tc.wantStale...) {
+				t.Errorf("expected stale group versions %v, got %v", tc.wantStale, stale)
+			}
+			for _, gv := range []schema.GroupVersion{metrics, apps, failing} {
+				got, err := r.IsStale(gv)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got != stale.Has(gv) {
+					t.Errorf("expected IsStale(%v) to be %v, got %v", gv, stale.Has(gv), got)
+				}
+			}
+			// resolution does not depend on the freshness.
+			if _, err := r.ResolveSchema(apps.WithKind("Deployment")); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRequireFresh(t *testing.T) {
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	job := schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}
+	aggregated := &fakeAggregatedDiscovery{
+		fakeDiscovery: newFakeDiscovery(openapitest.NewEmbeddedFileClient()),
+		failed:        map[schema.GroupVersion]error{deployment.GroupVersion(): discovery.StaleGroupVersionError{}},
+	}
+	r := &ClientDiscoveryResolver{Discovery: aggregated}
+	if _, err := r.ResolveSchema(deployment); err != nil {
+		t.Fatalf("expected a stale schema to resolve without RequireFresh, got %v", err)
+	}
+
+	r.RequireFresh = true
+	if _, err := r.ResolveSchema(deployment); !errors.Is(err, ErrStaleDiscovery) {
+		t.Errorf("expected ErrStaleDiscovery, got %v", err)
+	}
+	if _, _, err := r.ResolveSchemaWithDiagnostics(deployment); !errors.Is(err, ErrStaleDiscovery) {
+		t.Errorf("expected ErrStaleDiscovery with diagnostics, got %v", err)
+	}
+	if _, err := r.ResolveSchema(job); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	aggregated.err = errors.New("unavailable")
+	if _, err := r.ResolveSchema(job); !errors.Is(err, ErrDiscoveryUnavailable) {
+		t.Errorf("expected ErrDiscoveryUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ErrAllOfConflict is wrapped and returned if the members of an allOf that
+// is merged with PopulateRefsOptions.MergeAllOf declare conflicting values of
+// the same keyword.
+var ErrAllOfConflict = errors.New("conflicting allOf members")
+
+// composesAllOf checks if the allOf of a schema composes partial schemas,
+// rather than merely wrapping a single Ref as kube-openapi does.
+func composesAllOf(s *spec.Schema) bool {
+	if s.Ref.GetURL() != nil {
+		return false
+	}
+	return len(s.AllOf) > 1 || (len(s.AllOf) == 1 && s.AllOf[0].Ref.GetURL() == nil)
+}
+
+// mergeMembers populates the Refs of the members of the allOf of schema,
+// which is nested depth levels below the root, and merges them into a copy of
+// schema without allOf.
+func (p *populator) mergeMembers(schema *spec.Schema, depth int) (*spec.Schema, error) {
+	result := *schema
+	result.AllOf = nil
+	result.Extensions = copyExtensions(schema.Extensions)
+	for i := range schema.AllOf {
+		member, err := p.populateRefs(&schema.AllOf[i], depth)
+		if err != nil {
+			return nil, err
+		}
+		if err := mergeSchema(&result, member); err != nil {
+			return nil, fmt.Errorf("cannot merge allOf member %d: %w", i, err)
+		}
+	}
+	return &result, nil
+}
+
+// mergeSchema merges the properties, required fields, type, items,
+// additionalProperties, extensions and validations of src into dst, which
+// must not share its Properties or Extensions with other schemas. A keyword
+// that both declare with different values is a conflict, except for the
+// description and the title, which dst keeps, and nullable, which either
+// sets. The properties that both declare are merged in turn.
+func mergeSchema(dst, src *spec.Schema) error {
+	if len(dst.Type) > 0 && len(src.Type) > 0 && !reflect.DeepEqual(dst.Type, src.Type) {
+		return allOfConflict("type", dst.Type, src.Type)
+	}
+	if len(dst.Type) == 0 {
+		dst.Type = src.Type
+	}
+	if len(src.Properties) > 0 {
+		props := make(map[string]spec.Schema, len(dst.Properties)+len(src.Properties))
+		for name, prop := range dst.Properties {
+			props[name] = prop
+		}
+		for name, prop := range src.Properties {
+			existing, ok := props[name]
+			if !ok {
+				props[name] = prop
+				continue
+			}
+			existing.Properties = copyProperties(existing.Properties)
+			existing.Extensions = copyExtensions(existing.Extensions)
+			if err := mergeSchema(&existing, &prop); err != nil {
+				return fmt.Errorf("property %q: %w", name, err)
+			}
+			props[name] = existing
+		}
+		dst.Properties = props
+	}
+	for _, name := range src.Required {
+		if !containsString(dst.Required, name) {
+			dst.Required = append(dst.Required[:len(dst.Required):len(dst.Required)], name)
+		}
+	}
+	if src.Items != nil {
+		if dst.Items != nil && !reflect.DeepEqual(dst.Items, src.Items) {
+			return allOfConflict("items", dst.Items, src.Items)
+		}
+		dst.Items = src.Items
+	}
+	if src.AdditionalProperties != nil {
+		if dst.AdditionalProperties != nil && !reflect.DeepEqual(dst.AdditionalProperties, src.AdditionalProperties) {
+			return allOfConflict("additionalProperties", dst.AdditionalProperties, src.AdditionalProperties)
+		}
+		dst.AdditionalProperties = src.AdditionalProperties
+	}
+	dst.Nullable = dst.Nullable || src.Nullable
+	if len(dst.Description) == 0 {
+		dst.Description = src.Description
+	}
+	if len(dst.Title) == 0 {
+		dst.Title = src.Title
+	}
+	for _, keyword := range []struct {
+		name     string
+		dst, src interface{}
+	}{
+		{"format", &dst.Format, &src.Format},
+		{"pattern", &dst.Pattern, &src.Pattern},
+		{"default", &dst.Default, &src.Default},
+		{"enum", &dst.Enum, &src.Enum},
+		{"maximum", &dst.Maximum, &src.Maximum},
+		{"minimum", &dst.Minimum, &src.Minimum},
+		{"maxLength", &dst.MaxLength, &src.MaxLength},
+		{"minLength", &dst.MinLength, &src.MinLength},
+		{"maxItems", &dst.MaxItems, &src.MaxItems},
+		{"minItems", &dst.MinItems, &src.MinItems},
+		{"maxProperties", &dst.MaxProperties, &src.MaxProperties},
+		{"minProperties", &dst.MinProperties, &src.MinProperties},
+	} {
+		if err := mergeKeyword(keyword.name, keyword.dst, keyword.src); err != nil {
+			return err
+		}
+	}
+	for key, value := range src.Extensions {
+		if existing, ok := dst.Extensions[key]; ok && !reflect.DeepEqual(existing, value) {
+			return allOfConflict(key, existing, value)
+		}
+		dst.AddExtension(key, value)
+	}
+	return nil
+}
+
+// mergeKeyword sets the keyword that dst points to to the one that src points
+// to if it is unset, and returns a conflict if both are set to different
+// values. Only a nil pointer, slice or default and an empty string are unset,
+// so that e.g. a default of false conflicts with a default of true.
+func mergeKeyword(name string, dst, src interface{}) error {
+	d := reflect.ValueOf(dst).Elem()
+	s := reflect.ValueOf(src).Elem()
+	if isUnsetKeyword(s) {
+		return nil
+	}
+	if !isUnsetKeyword(d) && !reflect.DeepEqual(d.Interface(), s.Interface()) {
+		return allOfConflict(name, d.Interface(), s.Interface())
+	}
+	d.Set(s)
+	return nil
+}
+
+// isUnsetKeyword checks if the value of a keyword is unset. The string
+// keywords, e.g. format, are omitted if empty, so an empty string cannot be
+// told apart from an unset one.
+func isUnsetKeyword(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return v.IsNil()
+	case reflect.String:
+		return v.Len() == 0
+	}
+	return false
+}
+
+func allOfConflict(keyword string, a, b interface{}) error {
+	return fmt.Errorf("%s: %v and %v: %w", keyword, printable(a), printable(b), ErrAllOfConflict)
+}
+
+// printable dereferences the pointers of the optional keywords for errors.
+func printable(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() != reflect.Struct {
+		return rv.Elem().Interface()
+	}
+	return v
+}
+
+func copyExtensions(extensions spec.Extensions) spec.Extensions {
+	if extensions == nil {
+		return nil
+	}
+	result := make(spec.Extensions, len(extensions))
+	for key, value := range extensions {
+		result[key] = value
+	}
+	return result
+}
+
+func containsString(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/utils/ptr"
+)
+
+func TestPopulateRefsMergeAllOf(t *testing.T) {
+	refTo := func(name string) spec.Schema {
+		return spec.Schema{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef(name)}}
+	}
+	composed := func(members ...spec.Schema) spec.Schema {
+		return spec.Schema{SchemaProps: spec.SchemaProps{AllOf: members}}
+	}
+	base := objectSchema(map[string]spec.Schema{
+		"name": scalarSchema("string", ""),
+	}, "name")
+	scaled := *objectSchema(map[string]spec.Schema{
+		"replicas": scalarSchema("integer", "int32"),
+	}, "replicas")
+	withDefault := func(s spec.Schema, value interface{}) spec.Schema {
+		s.Default = value
+		return s
+	}
+	for _, tc := range []struct {
+		name    string
+		root    *spec.Schema
+		schemas map[string]*spec.Schema
+		// expected is the expected schema of the spec property, if any.
+		expected *spec.Schema
+		wantErr  string
+	}{
+		{
+			name:     "Ref with partial schema",
+			root:     objectSchema(map[string]spec.Schema{"spec": composed(refTo("Base"), scaled)}),
+			expected: objectSchema(map[string]spec.Schema{"name": scalarSchema("string", ""), "replicas": scalarSchema("integer", "int32")}, "name", "replicas"),
+		},
+		{
+			name: "referred schema composing allOf",
+			root: objectSchema(map[string]spec.Schema{"spec": refTo("Composed")}),
+			schemas: map[string]*spec.Schema{
+				"Composed": func() *spec.Schema {
+					s := composed(refTo("Base"), scaled)
+					return &s
+				}(),
+			},
+			expected: objectSchema(map[string]spec.Schema{"name": scalarSchema("string", ""), "replicas": scalarSchema("integer", "int32")}, "name", "replicas"),
+		},
+		{
+			name: "overlapping properties",
+			root: objectSchema(map[string]spec.Schema{"spec": composed(
+				*objectSchema(map[string]spec.Schema{"name": {SchemaProps: spec.SchemaProps{Type: []string{"string"}, MaxLength: ptr.To[int64](63)}}}),
+				*objectSchema(map[string]spec.Schema{"name": {SchemaProps: spec.SchemaProps{Pattern: "^[a-z]+$"}}}),
+			)}),
+			expected: objectSchema(map[string]spec.Schema{"name": {SchemaProps: spec.SchemaProps{
+				Type:      []string{"string"},
+				MaxLength: ptr.To[int64](63),
+				Pattern:   "^[a-z]+$",
+			}}}),
+		},
+		{
+			name: "conflicting types",
+			root: objectSchema(map[string]spec.Schema{"spec": composed(
+				*objectSchema(map[string]spec.Schema{"replicas": scalarSchema("integer", "")}),
+				*objectSchema(map[string]spec.Schema{"replicas": scalarSchema("string", "")}),
+			)}),
+			wantErr: `allOf member 1: property "replicas": type`,
+		},
+		{
+			name: "conflicting formats",
+			root: objectSchema(map[string]spec.Schema{"spec": composed(
+				scalarSchema("integer", "int32"),
+				scalarSchema("integer", "int64"),
+			)}),
+			wantErr: "format: int32 and int64",
+		},
+		{
+			name: "conflicting defaults",
+			root: objectSchema(map[string]spec.Schema{"spec": composed(
+				withDefault(scalarSchema("boolean", ""), true),
+				withDefault(scalarSchema("boolean", ""), false),
+			)}),
+			wantErr: "default: true and false",
+		},
+		{
+			name: "conflicting defaults in the other order",
+			root: objectSchema(map[string]spec.Schema{"spec": composed(
+				withDefault(scalarSchema("boolean", ""), false),
+				withDefault(scalarSchema("boolean", ""), true),
+			)}),
+			wantErr: "default: false and true",
+		},
+		{
+			name: "conflicting empty default",
+			root: objectSchema(map[string]spec.Schema{"spec": composed(
+				withDefault(scalarSchema("string", ""), "a"),
+				withDefault(scalarSchema("string", ""), ""),
+			)}),
+			wantErr: "default: a and ",
+		},
+		{
+			name: "false default",
+			root: objectSchema(map[string]spec.Schema{"spec": composed(
+				scalarSchema("boolean", ""),
+				withDefault(scalarSchema("boolean", ""), false),
+			)}),
+			expected: func() *spec.Schema {
+				s := withDefault(scalarSchema("boolean", ""), false)
+				return &s
+			}(),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			schemas := map[string]*spec.Schema{"Root": tc.root, "Base": base}
+			for name, s := range tc.schemas {
+				schemas[name] = s
+			}
+			schemaOf := func(ref string) (*spec.Schema, bool) {
+				s, ok := schemas[ref]
+				return s, ok
+			}
+			before, err := deepCopy(tc.root)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			s, err := PopulateRefsWithOptions(schemaOf, "Root", PopulateRefsOptions{MergeAllOf: true})
+			if len(tc.wantErr) > 0 {
+				if !errors.Is(err, ErrAllOfConflict) || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("expected ErrAllOfConflict with %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := s.Properties["spec"]; !reflect.DeepEqual(&got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, &got)
+			}
+			if !reflect.DeepEqual(tc.root, before) {
+				t.Errorf("expected the original schema not to be mutated")
+			}
+
+			// by default, the members are not merged.
+			s, err = PopulateRefs(schemaOf, "Root")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := s.Properties["spec"]; reflect.DeepEqual(&got, tc.expected) {
+				t.Errorf("expected the members not to be merged by default")
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// APIServiceResolver resolves the schemas of types served by aggregated
+// apiservers by fetching the OpenAPI v3 document of the group-version
+// directly from the service of its APIService, through the service proxy of
+// the apiserver, rather than from the aggregated document.
+type APIServiceResolver struct {
+	// Client is a REST client of the apiserver that proxies to the services,
+	// e.g. the REST client of the core/v1 client.
+	Client rest.Interface
+	// APIServices lists the apiregistration.k8s.io APIServices, e.g. from a
+	// dynamic client or informer.
+	APIServices func() ([]*unstructured.Unstructured, error)
+}
+
+var _ SchemaResolver = (*APIServiceResolver)(nil)
+
+// ResolveSchema resolves the schema of the GVK from the document published by
+// the service of the APIService of its group-version. It returns an error
+// wrapping ErrSchemaNotFound if there is no such APIService, if the
+// APIService is served locally, or if its service does not publish the
+// document.
+func (r *APIServiceResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema, with the
+// request to the service bound to ctx.
+func (r *APIServiceResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	s, err := r.resolve(ctx, gvk)
+	if err != nil {
+		return nil, wrapResolutionError(gvk, err)
+	}
+	return s, nil
+}
+
+// resolve resolves the schema of gvk like ResolveSchemaWithContext, without
+// prefixing its errors with the GVK.
+func (r *APIServiceResolver) resolve(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	apiServices, err := r.APIServices()
+	if err != nil {
+		return nil, err
+	}
+	var service []string
+	for _, apiService := range apiServices {
+		group, _, _ := unstructured.NestedString(apiService.Object, "spec", "group")
+		version, _, _ := unstructured.NestedString(apiService.Object, "spec", "version")
+		if group == gvk.Group && version == gvk.Version {
+			service, err = proxyPathOfAPIService(apiService)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	if service == nil {
+		return nil, fmt.Errorf("no APIService with a service for %q: %w", gvk.GroupVersion(), ErrSchemaNotFound)
+	}
+
+	b, err := r.Client.Get().AbsPath(service...).Suffix("openapi", "v3", resourcePathFromGV(gvk.GroupVersion())).DoRaw(ctx)
+	if err := checkContext(ctx, gvk); err != nil {
+		return nil, err
+	}
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("the service does not publish OpenAPI for %q: %w", gvk.GroupVersion(), ErrSchemaNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp := new(schemaResponse)
+	if err := json.Unmarshal(b, resp); err != nil {
+		return nil, err
+	}
+	ref, err := resolveRef(resp, gvk)
+	if err != nil {
+		return nil, err
+	}
+	return PopulateRefs(func(ref string) (*spec.Schema, bool) {
+		s, ok := resp.Components.Schemas[schemaName(ref)]
+		return s, ok
+	}, ref)
+}
+
+// proxyPathOfAPIService returns the segments of the path of the service proxy
+// to the service of an APIService, or nil if the APIService is served locally.
+func proxyPathOfAPIService(apiService *unstructured.Unstructured) ([]string, error) {
+	service, ok, err := unstructured.NestedMap(apiService.Object, "spec", "service")
+	if err != nil {
+		return nil, fmt.Errorf("invalid service of APIService %q: %w", apiService.GetName(), err)
+	}
+	if !ok || service == nil {
+		return nil, nil
+	}
+	namespace, _, _ := unstructured.NestedString(service, "namespace")
+	name, _, _ := unstructured.NestedString(service, "name")
+	if len(namespace) == 0 || len(name) == 0 {
+		return nil, fmt.Errorf("invalid service of APIService %q: namespace and name are required", apiService.GetName())
+	}
+	port := int64(443)
+	if p, ok, _ := unstructured.NestedInt64(service, "port"); ok {
+		port = p
+	}
+	return []string{"api", "v1", "namespaces", namespace, "services", "https:" + name + ":" + strconv.FormatInt(port, 10), "proxy"}, nil
+}
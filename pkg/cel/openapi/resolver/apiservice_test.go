@@ -0,0 +1,107 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest/fake"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func newAPIService(group, version string, service map[string]interface{}) *unstructured.Unstructured {
+	apiServiceSpec := map[string]interface{}{"group": group, "version": version}
+	if service != nil {
+		apiServiceSpec["service"] = service
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiregistration.k8s.io/v1",
+		"kind":       "APIService",
+		"metadata":   map[string]interface{}{"name": version + "." + group},
+		"spec":       apiServiceSpec,
+	}}
+}
+
+func TestAPIServiceResolver(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "proxies.clusternet.io", Version: "v1alpha1", Kind: "Socket"}
+	socket := withGVK(*objectSchema(map[string]spec.Schema{"kind": scalarSchema("string", "")}), gvk)
+	doc := newDocument(map[string]*spec.Schema{"io.clusternet.apis.proxies.v1alpha1.Socket": &socket})
+	const endpoint = "/api/v1/namespaces/clusternet-system/services/https:clusternet-hub:8443/proxy/openapi/v3/apis/proxies.clusternet.io/v1alpha1"
+	var requested []string
+	client := &fake.RESTClient{
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			requested = append(requested, req.URL.Path)
+			if req.URL.Path != endpoint {
+				return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(bytes.NewReader(nil))}, nil
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(bytes.NewReader(doc)),
+			}, nil
+		}),
+	}
+	r := &APIServiceResolver{
+		Client: client,
+		APIServices: func() ([]*unstructured.Unstructured, error) {
+			return []*unstructured.Unstructured{
+				newAPIService("proxies.clusternet.io", "v1alpha1", map[string]interface{}{
+					"namespace": "clusternet-system", "name": "clusternet-hub", "port": int64(8443),
+				}),
+				newAPIService("shadow.clusternet.io", "v1alpha1", map[string]interface{}{
+					"namespace": "clusternet-system", "name": "clusternet-hub",
+				}),
+				newAPIService("apps", "v1", nil),
+			}, nil
+		},
+	}
+
+	s, err := r.ResolveSchema(gvk)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := s.Properties["kind"]; !ok {
+		t.Errorf("expected the schema of the service, got %v", s)
+	}
+	if len(requested) != 1 || requested[0] != endpoint {
+		t.Errorf("expected a request to %q, got %v", endpoint, requested)
+	}
+
+	for _, missing := range []schema.GroupVersionKind{
+		// the service does not publish OpenAPI.
+		{Group: "shadow.clusternet.io", Version: "v1alpha1", Kind: "Manifest"},
+		// the APIService is served locally.
+		{Group: "apps", Version: "v1", Kind: "Deployment"},
+		// there is no APIService.
+		{Group: "example.com", Version: "v1", Kind: "Widget"},
+	} {
+		if _, err := r.ResolveSchema(missing); !errors.Is(err, ErrSchemaNotFound) {
+			t.Errorf("%v: expected ErrSchemaNotFound, got %v", missing, err)
+		}
+	}
+	if last := requested[len(requested)-1]; last != "/api/v1/namespaces/clusternet-system/services/https:clusternet-hub:443/proxy/openapi/v3/apis/shadow.clusternet.io/v1alpha1" {
+		t.Errorf("expected the default port, got a request to %q", last)
+	}
+}
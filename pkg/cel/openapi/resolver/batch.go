@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// BatchOptions configures BatchResolve.
+type BatchOptions struct {
+	// UsePlaceholders makes BatchResolve return an entry for every requested
+	// GVK. GVKs that fail to resolve are mapped to a placeholder schema, which
+	// callers can detect with IsUnresolvedPlaceholder.
+	// By default, GVKs that fail to resolve are omitted from the result.
+	UsePlaceholders bool
+
+	// NodeBudget, if positive, limits the total number of schema nodes
+	// resolved across the batch. Once resolving a GVK would exceed the
+	// budget, that GVK and all the remaining ones fail with ErrBudgetExceeded.
+	NodeBudget int
+}
+
+// ErrBudgetExceeded is wrapped and returned for the GVKs of a batch that are
+// not resolved because the node budget of the batch is exhausted.
+var ErrBudgetExceeded = errors.New("schema node budget exceeded")
+
+// unresolvedPlaceholder is the designated placeholder schema for GVKs that
+// BatchResolve failed to resolve. It is compared by identity.
+var unresolvedPlaceholder = &spec.Schema{SchemaProps: spec.SchemaProps{
+	Type:        []string{"object"},
+	Description: "placeholder for a schema that could not be resolved",
+}}
+
+// IsUnresolvedPlaceholder checks if the schema is the placeholder that
+// BatchResolve substitutes for GVKs that failed to resolve.
+func IsUnresolvedPlaceholder(s *spec.Schema) bool {
+	return s == unresolvedPlaceholder
+}
+
+// BatchResolve resolves the schemas of the given GVKs with the resolver on a
+// best-effort basis. Duplicate GVKs are resolved only once, and GVKs are
+// resolved in sorted order so that, under a node budget, the same GVKs are
+// dropped on every run.
+// The returned map holds the schemas that resolved successfully, and the
+// returned error joins the errors of all GVKs that failed, each of which
+// names the GVK. Whether unresolved GVKs have an entry in the map is
+// controlled by opts.
+// The placeholder schema is shared and must not be mutated.
+func BatchResolve(r SchemaResolver, gvks []schema.GroupVersionKind, opts BatchOptions) (map[schema.GroupVersionKind]*spec.Schema, error) {
+	sorted := sets.New(gvks...).UnsortedList()
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].String() < sorted[j].String()
+	})
+
+	result := make(map[schema.GroupVersionKind]*spec.Schema, len(sorted))
+	var errs []error
+	fail := func(gvk schema.GroupVersionKind, err error) {
+		errs = append(errs, wrapResolutionError(gvk, err))
+		if opts.UsePlaceholders {
+			result[gvk] = unresolvedPlaceholder
+		}
+	}
+	nodes := 0
+	for i, gvk := range sorted {
+		s, err := r.ResolveSchema(gvk)
+		if err != nil {
+			fail(gvk, err)
+			continue
+		}
+		if opts.NodeBudget > 0 {
+			nodes += countNodes(s)
+			if nodes > opts.NodeBudget {
+				for _, gvk := range sorted[i:] {
+					fail(gvk, fmt.Errorf("%w: budget of %d nodes exhausted", ErrBudgetExceeded, opts.NodeBudget))
+				}
+				break
+			}
+		}
+		result[gvk] = s
+	}
+	return result, errors.Join(errs...)
+}
+
+// countNodes returns the number of nodes in the schema tree, as visited by WalkSchema.
+func countNodes(s *spec.Schema) int {
+	n := 0
+	_ = WalkSchema(s, func(string, *spec.Schema) error {
+		n++
+		return nil
+	})
+	return n
+}
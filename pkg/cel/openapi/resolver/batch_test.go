@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestBatchResolve(t *testing.T) {
+	found := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	missing := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Missing"}
+	r := staticResolver{
+		found: objectSchema(map[string]spec.Schema{"spec": *objectSchema(nil)}),
+	}
+	gvks := []schema.GroupVersionKind{found, missing, found}
+
+	for _, tc := range []struct {
+		name     string
+		opts     BatchOptions
+		wantGVKs []schema.GroupVersionKind
+	}{
+		{name: "omit unresolved", wantGVKs: []schema.GroupVersionKind{found}},
+		{name: "placeholders", opts: BatchOptions{UsePlaceholders: true}, wantGVKs: []schema.GroupVersionKind{found, missing}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := BatchResolve(r, gvks, tc.opts)
+			if !errors.Is(err, ErrSchemaNotFound) {
+				t.Errorf("expected error to wrap ErrSchemaNotFound, got %v", err)
+			}
+			if expected := "cannot resolve apps/v1, Kind=Missing: schema not found"; err == nil || err.Error() != expected {
+				t.Errorf("expected %q, got %v", expected, err)
+			}
+			if len(result) != len(tc.wantGVKs) {
+				t.Fatalf("expected %d entries, got %d: %v", len(tc.wantGVKs), len(result), result)
+			}
+			for _, gvk := range tc.wantGVKs {
+				if _, ok := result[gvk]; !ok {
+					t.Errorf("expected an entry for %v", gvk)
+				}
+			}
+			if IsUnresolvedPlaceholder(result[found]) {
+				t.Errorf("expected a resolved schema for %v, got the placeholder", found)
+			}
+			if s, ok := result[missing]; ok && !IsUnresolvedPlaceholder(s) {
+				t.Errorf("expected the placeholder for %v, got %v", missing, s)
+			}
+		})
+	}
+}
+
+func TestBatchResolveNodeBudget(t *testing.T) {
+	// each schema has 3 nodes: the root, spec and spec.replicas.
+	r := staticResolver{}
+	var gvks []schema.GroupVersionKind
+	for _, kind := range []string{"D", "C", "B", "A"} {
+		gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: kind}
+		r[gvk] = objectSchema(map[string]spec.Schema{
+			"spec": *objectSchema(map[string]spec.Schema{"replicas": scalarSchema("integer", "int32")}),
+		})
+		gvks = append(gvks, gvk)
+	}
+
+	for i := 0; i < 3; i++ {
+		result, err := BatchResolve(r, gvks, BatchOptions{NodeBudget: 7, UsePlaceholders: true})
+		if !errors.Is(err, ErrBudgetExceeded) {
+			t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+		}
+		if expected := "cannot resolve apps/v1, Kind=C: schema node budget exceeded: budget of 7 nodes exhausted\ncannot resolve apps/v1, Kind=D: schema node budget exceeded: budget of 7 nodes exhausted"; err.Error() != expected {
+			t.Fatalf("expected %q, got %q", expected, err.Error())
+		}
+		for _, kind := range []string{"A", "B"} {
+			if s := result[schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: kind}]; s == nil || IsUnresolvedPlaceholder(s) {
+				t.Errorf("expected %s to be resolved within the budget, got %v", kind, s)
+			}
+		}
+		for _, kind := range []string{"C", "D"} {
+			if s := result[schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: kind}]; !IsUnresolvedPlaceholder(s) {
+				t.Errorf("expected %s to be dropped by the budget, got %v", kind, s)
+			}
+		}
+	}
+}
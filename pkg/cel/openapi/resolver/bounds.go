@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// DefaultBounds are the bounds that ApplyDefaultBounds declares on the lists,
+// maps and strings of a schema that declare none. Without a declared bound,
+// the cost estimation of CEL bounds their sizes by the maximum size of a
+// request, which makes rules that traverse them likely to exceed the cost
+// limits. A zero value leaves the corresponding bound undeclared.
+type DefaultBounds struct {
+	// MaxLength is declared on strings without maxLength, except for those
+	// whose format or enum bounds their length already.
+	MaxLength int64
+	// MaxItems is declared on arrays without maxItems.
+	MaxItems int64
+	// MaxProperties is declared on maps, i.e. objects with
+	// additionalProperties, without maxProperties.
+	MaxProperties int64
+}
+
+// ApplyDefaultBounds returns the schema with the given default bounds
+// declared on its unbounded lists, maps and strings. Already bounded fields
+// are left as they are. The input is not mutated.
+func ApplyDefaultBounds(s *spec.Schema, bounds DefaultBounds) (*spec.Schema, error) {
+	return transformSchema(s, func(s *spec.Schema) (*spec.Schema, error) {
+		var maxLength, maxItems, maxProperties *int64
+		switch {
+		case s.Type.Contains("array"):
+			if s.MaxItems == nil && bounds.MaxItems > 0 {
+				maxItems = &bounds.MaxItems
+			}
+		case s.Type.Contains("object"):
+			if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil && s.MaxProperties == nil && bounds.MaxProperties > 0 {
+				maxProperties = &bounds.MaxProperties
+			}
+		case s.Type.Contains("string"):
+			if isUnbounded(s) && bounds.MaxLength > 0 {
+				maxLength = &bounds.MaxLength
+			}
+		}
+		if maxLength == nil && maxItems == nil && maxProperties == nil {
+			return s, nil
+		}
+		result := *s
+		if maxLength != nil {
+			result.MaxLength = maxLength
+		}
+		if maxItems != nil {
+			result.MaxItems = maxItems
+		}
+		if maxProperties != nil {
+			result.MaxProperties = maxProperties
+		}
+		return &result, nil
+	})
+}
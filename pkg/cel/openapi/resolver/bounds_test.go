@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestApplyDefaultBounds(t *testing.T) {
+	bounded := int64(63)
+	name := scalarSchema("string", "")
+	name.MaxLength = &bounded
+	s := objectSchema(map[string]spec.Schema{
+		"name":      name,
+		"image":     scalarSchema("string", ""),
+		"startTime": scalarSchema("string", "date-time"),
+		"protocol":  enumSchema("string", "TCP", "UDP"),
+		"args": {SchemaProps: spec.SchemaProps{
+			Type: []string{"array"},
+			Items: &spec.SchemaOrArray{Schema: &spec.Schema{
+				SchemaProps: spec.SchemaProps{Type: []string{"string"}},
+			}},
+		}},
+		"labels": {SchemaProps: spec.SchemaProps{
+			Type:                 []string{"object"},
+			AdditionalProperties: &spec.SchemaOrBool{Allows: true, Schema: &spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"string"}}}},
+		}},
+	})
+	bounds := DefaultBounds{MaxLength: 1024, MaxItems: 100, MaxProperties: 64}
+
+	result, err := ApplyDefaultBounds(s, bounds)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, tc := range []struct {
+		path     string
+		expected *int64
+		bound    func(s *spec.Schema) *int64
+	}{
+		{path: "image", expected: &bounds.MaxLength, bound: func(s *spec.Schema) *int64 { return s.MaxLength }},
+		{path: "name", expected: &bounded, bound: func(s *spec.Schema) *int64 { return s.MaxLength }},
+		{path: "startTime", bound: func(s *spec.Schema) *int64 { return s.MaxLength }},
+		{path: "protocol", bound: func(s *spec.Schema) *int64 { return s.MaxLength }},
+		{path: "args", expected: &bounds.MaxItems, bound: func(s *spec.Schema) *int64 { return s.MaxItems }},
+		{path: "args[*]", expected: &bounds.MaxLength, bound: func(s *spec.Schema) *int64 { return s.MaxLength }},
+		{path: "labels", expected: &bounds.MaxProperties, bound: func(s *spec.Schema) *int64 { return s.MaxProperties }},
+		{path: "labels[*]", expected: &bounds.MaxLength, bound: func(s *spec.Schema) *int64 { return s.MaxLength }},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			node, ok := lookupPath(result, tc.path)
+			if !ok {
+				t.Fatalf("expected %q to exist", tc.path)
+			}
+			actual := tc.bound(node)
+			if (actual == nil) != (tc.expected == nil) || actual != nil && *actual != *tc.expected {
+				t.Errorf("expected bound %v, got %v", ptrString(tc.expected), ptrString(actual))
+			}
+		})
+	}
+	if image := s.Properties["image"]; image.MaxLength != nil {
+		t.Errorf("expected the input not to be mutated, got maxLength %d", *image.MaxLength)
+	}
+}
+
+func TestDiscoveryResolverDefaultBounds(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	r := newEmbeddedDiscoveryResolver()
+	s, err := r.ResolveSchema(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if image, _ := lookupPath(s, "spec.containers[*].image"); image.MaxLength != nil {
+		t.Errorf("expected no bounds by default, got maxLength %d", *image.MaxLength)
+	}
+
+	r.DefaultBounds = &DefaultBounds{MaxLength: 1024}
+	if s, err = r.ResolveSchema(pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if image, _ := lookupPath(s, "spec.containers[*].image"); image.MaxLength == nil || *image.MaxLength != 1024 {
+		t.Errorf("expected maxLength 1024, got %v", ptrString(image.MaxLength))
+	}
+	if containers, _ := lookupPath(s, "spec.containers"); containers.MaxItems != nil {
+		t.Errorf("expected no maxItems without a default, got %d", *containers.MaxItems)
+	}
+}
+
+func ptrString(p *int64) string {
+	if p == nil {
+		return "<nil>"
+	}
+	return fmt.Sprint(*p)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// BytesResolver resolves schemas from OpenAPI v3 documents held in memory,
+// e.g. embedded in a custom resource or pushed through a configuration API,
+// without reading files or querying discovery. Each document is parsed once,
+// on first use.
+type BytesResolver struct {
+	// docs are the raw documents by group-version path.
+	docs map[string][]byte
+
+	lock sync.Mutex
+	// documents are the parsed documents by group-version path.
+	documents map[string]*schemaResponse
+	// errs are the errors of the documents that cannot be parsed, by
+	// group-version path, since parsing them again would fail again.
+	errs map[string]error
+}
+
+var _ SchemaResolver = (*BytesResolver)(nil)
+
+// NewBytesResolver creates a BytesResolver over the given OpenAPI v3
+// documents by group-version path, e.g. "apis/apps/v1". The map is copied,
+// but the documents are not and must not be modified afterwards.
+func NewBytesResolver(docs map[string][]byte) *BytesResolver {
+	copied := make(map[string][]byte, len(docs))
+	for path, b := range docs {
+		copied[path] = b
+	}
+	return &BytesResolver{
+		docs:      copied,
+		documents: make(map[string]*schemaResponse),
+		errs:      make(map[string]error),
+	}
+}
+
+func (r *BytesResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema unless ctx
+// is already done.
+func (r *BytesResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	if err := checkContext(ctx, gvk); err != nil {
+		return nil, err
+	}
+	resp, err := r.document(resourcePathFromGV(gvk.GroupVersion()))
+	if err != nil {
+		return nil, wrapResolutionError(gvk, err)
+	}
+	return resolveSelfContained(resp, gvk)
+}
+
+// document returns the parsed document of the group-version at
+// resourcePath, parsing it if it was not parsed yet.
+func (r *BytesResolver) document(resourcePath string) (*schemaResponse, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if resp, ok := r.documents[resourcePath]; ok {
+		return resp, nil
+	}
+	if err, ok := r.errs[resourcePath]; ok {
+		return nil, err
+	}
+	b, ok := r.docs[resourcePath]
+	if !ok {
+		return nil, fmt.Errorf("no document for group version path %q: %w", resourcePath, ErrSchemaNotFound)
+	}
+	resp, err := parseDocument(resourcePath, b)
+	if err != nil {
+		r.errs[resourcePath] = err
+		return nil, err
+	}
+	r.documents[resourcePath] = resp
+	return resp, nil
+}
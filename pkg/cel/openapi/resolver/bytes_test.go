@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/openapi/openapitest"
+)
+
+func TestBytesResolver(t *testing.T) {
+	paths, err := openapitest.NewEmbeddedFileClient().Paths()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := paths["apis/apps/v1"].Schema(runtime.ContentTypeJSON)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := NewBytesResolver(map[string][]byte{
+		"apis/apps/v1":  b,
+		"apis/batch/v1": []byte("not json"),
+	})
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+
+	expected, err := newEmbeddedDiscoveryResolver().ResolveSchema(deployment)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, err := r.ResolveSchema(deployment)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("expected the same schema as resolved by discovery, got %v", s)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := r.ResolveSchema(schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"})
+		if err == nil || errors.Is(err, ErrSchemaNotFound) || !strings.Contains(err.Error(), `"apis/batch/v1"`) {
+			t.Errorf("expected a parse error naming the group-version path, got %v", err)
+		}
+	}
+	if _, err := r.ResolveSchema(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound for a group-version without document, got %v", err)
+	}
+	if _, err := r.ResolveSchema(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Missing"}); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound for a missing kind, got %v", err)
+	}
+}
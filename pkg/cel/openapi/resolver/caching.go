@@ -0,0 +1,315 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ErrCachedSchemaMutated is wrapped and returned by CachingResolver with
+// ReadOnlyResults in debug mode if a cached schema was mutated.
+var ErrCachedSchemaMutated = errors.New("cached schema was mutated")
+
+// CachingResolver wraps a SchemaResolver and caches the resolved schemas by
+// GVK. Unless ReadOnlyResults is set, every call returns a deep copy of the
+// cached schema, so callers are free to mutate the result.
+// Errors are not cached. The cache is unbounded unless MaxEntries is set.
+type CachingResolver struct {
+	// ReadOnlyResults makes the resolver return the cached schemas themselves
+	// instead of deep copies, which saves copying the whole schema on every
+	// call. The results are then shared by all callers and must never be
+	// mutated; callers that need to modify a schema must deep copy it first.
+	ReadOnlyResults bool
+	// Debug makes the resolver with ReadOnlyResults verify, whenever it
+	// returns a cached schema, that the schema was not mutated since it was
+	// cached. Resolution fails with ErrCachedSchemaMutated otherwise. Only
+	// the schemas cached while Debug is set are verified. This is expensive
+	// and meant for tests.
+	Debug bool
+	// Metrics, if not nil, observes the lookups in the cache.
+	Metrics Metrics
+	// MaxEntries, if positive, bounds the number of cached schemas. Once the
+	// bound is reached, caching another schema evicts the least recently
+	// used one. Lookups then take an exclusive lock to record their use.
+	MaxEntries int
+
+	delegate SchemaResolver
+
+	lock  sync.RWMutex
+	cache map[schema.GroupVersionKind]*spec.Schema
+	// hashes are the SchemaHash of the cached schemas, recorded in debug mode.
+	hashes map[schema.GroupVersionKind]string
+	// recency orders the GVKs of the cached schemas from the most to the
+	// least recently cached, or used with MaxEntries, and elements are their
+	// elements in recency.
+	recency  *list.List
+	elements map[schema.GroupVersionKind]*list.Element
+	// generation counts the invalidations, so that a schema resolved while
+	// it was invalidated is not cached.
+	generation uint64
+}
+
+var _ SchemaResolver = (*CachingResolver)(nil)
+var _ DiagnosticResolver = (*CachingResolver)(nil)
+
+// NewCachingResolver creates a CachingResolver over the delegate.
+func NewCachingResolver(delegate SchemaResolver) *CachingResolver {
+	return &CachingResolver{
+		delegate: delegate,
+		cache:    make(map[schema.GroupVersionKind]*spec.Schema),
+		hashes:   make(map[schema.GroupVersionKind]string),
+		recency:  list.New(),
+		elements: make(map[schema.GroupVersionKind]*list.Element),
+	}
+}
+
+// ResolveSchema returns a copy of the cached schema of the GVK, or the cached
+// schema itself with ReadOnlyResults, resolving it with the delegate on a
+// cache miss.
+func (r *CachingResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema, passing ctx
+// to the delegate on a cache miss.
+func (r *CachingResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	s, err := r.resolve(ctx, gvk, nil)
+	if err != nil {
+		return nil, err
+	}
+	return r.result(s)
+}
+
+// result returns the schema to return for the cached schema s.
+func (r *CachingResolver) result(s *spec.Schema) (*spec.Schema, error) {
+	if r.ReadOnlyResults {
+		return s, nil
+	}
+	return deepCopy(s)
+}
+
+// ResolveSchemaWithDiagnostics resolves the schema like ResolveSchema and also
+// returns diagnostics of the resolution. On a cache miss, the diagnostics
+// reported by the delegate, if any, are returned.
+func (r *CachingResolver) ResolveSchemaWithDiagnostics(gvk schema.GroupVersionKind) (*spec.Schema, ResolveDiagnostics, error) {
+	start := time.Now()
+	var diag ResolveDiagnostics
+	s, err := r.resolve(context.Background(), gvk, &diag)
+	if err != nil {
+		return nil, ResolveDiagnostics{}, err
+	}
+	if s, err = r.result(s); err != nil {
+		return nil, ResolveDiagnostics{}, err
+	}
+	diag.Nodes = countNodes(s)
+	diag.Duration = time.Since(start)
+	return s, diag, nil
+}
+
+// resolve returns the cached schema of gvk, resolving it with the delegate on
+// a cache miss, and fills diag, if not nil.
+func (r *CachingResolver) resolve(ctx context.Context, gvk schema.GroupVersionKind, diag *ResolveDiagnostics) (*spec.Schema, error) {
+	r.lock.RLock()
+	s, ok := r.cache[gvk]
+	hash, hashed := r.hashes[gvk]
+	generation := r.generation
+	r.lock.RUnlock()
+	if r.Metrics != nil {
+		r.Metrics.ObserveCacheLookup(ok)
+	}
+	if ok {
+		if r.MaxEntries > 0 {
+			r.touch(gvk)
+		}
+		if r.ReadOnlyResults && r.Debug && hashed {
+			if current, err := SchemaHash(s); err != nil || current != hash {
+				return nil, fmt.Errorf("schema of %v: %w", gvk, ErrCachedSchemaMutated)
+			}
+		}
+		if diag != nil {
+			diag.Source = DiagnosticSourceCache
+		}
+		return s, nil
+	}
+	s, err := resolveWithDiagnostics(ctx, r.delegate, gvk, diag)
+	if err != nil {
+		return nil, err
+	}
+	// keep a private copy so that the delegate may not mutate cached schemas.
+	s, err = deepCopy(s)
+	if err != nil {
+		return nil, err
+	}
+	if r.ReadOnlyResults && r.Debug {
+		if hash, err = SchemaHash(s); err != nil {
+			return nil, err
+		}
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	// the delegate may have resolved the schema from before an invalidation,
+	// which is returned but not cached.
+	if r.generation != generation {
+		return s, nil
+	}
+	r.cache[gvk] = s
+	if len(hash) > 0 {
+		r.hashes[gvk] = hash
+	}
+	r.evict(gvk)
+	return s, nil
+}
+
+// touch records the use of the cached schema of gvk, unless it was dropped
+// in the meantime.
+func (r *CachingResolver) touch(gvk schema.GroupVersionKind) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if e, ok := r.elements[gvk]; ok {
+		r.recency.MoveToFront(e)
+	}
+}
+
+// evict records the use of the just cached schema of gvk and drops the least
+// recently used schemas beyond MaxEntries, if set. The use is recorded even
+// without MaxEntries, so that every cached schema can be evicted once it is
+// set. The lock must be held.
+func (r *CachingResolver) evict(gvk schema.GroupVersionKind) {
+	if e, ok := r.elements[gvk]; ok {
+		r.recency.MoveToFront(e)
+	} else {
+		r.elements[gvk] = r.recency.PushFront(gvk)
+	}
+	for r.MaxEntries > 0 && len(r.cache) > r.MaxEntries {
+		back := r.recency.Back()
+		if back == nil {
+			return
+		}
+		r.remove(back.Value.(schema.GroupVersionKind))
+	}
+}
+
+// remove drops the cached schema of gvk. The lock must be held.
+func (r *CachingResolver) remove(gvk schema.GroupVersionKind) {
+	delete(r.cache, gvk)
+	delete(r.hashes, gvk)
+	if e, ok := r.elements[gvk]; ok {
+		r.recency.Remove(e)
+		delete(r.elements, gvk)
+	}
+}
+
+// Invalidate drops the cached schema of the GVK, e.g. once its CRD changed.
+func (r *CachingResolver) Invalidate(gvk schema.GroupVersionKind) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.generation++
+	r.remove(gvk)
+}
+
+// InvalidateGroupVersion drops the cached schemas of all GVKs of the
+// group-version.
+func (r *CachingResolver) InvalidateGroupVersion(gv schema.GroupVersion) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.generation++
+	for gvk := range r.cache {
+		if gvk.GroupVersion() == gv {
+			r.remove(gvk)
+		}
+	}
+}
+
+// InvalidateAll drops all cached schemas.
+func (r *CachingResolver) InvalidateAll() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.generation++
+	r.cache = make(map[schema.GroupVersionKind]*spec.Schema)
+	r.hashes = make(map[schema.GroupVersionKind]string)
+	r.recency.Init()
+	r.elements = make(map[schema.GroupVersionKind]*list.Element)
+}
+
+// Warm resolves the given GVKs and stores them in the cache, so that later
+// calls to ResolveSchema do not pay the cost of a cold resolution.
+// The returned error joins the errors of all GVKs that failed to resolve.
+func (r *CachingResolver) Warm(gvks []schema.GroupVersionKind) error {
+	var errs []error
+	for _, gvk := range gvks {
+		if _, err := r.resolve(context.Background(), gvk, nil); err != nil {
+			errs = append(errs, wrapResolutionError(gvk, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewBootstrappedResolver creates a CachingResolver over the delegate and
+// warms it with the GVKs of the given scheme, optionally restricted to the
+// given API groups. This is meant for the types that an aggregated apiserver
+// serves itself, in which case the delegate is usually
+// NewDefinitionsSchemaResolver(getDefinitions, scheme).
+// The resolver is returned even if some GVKs fail to resolve, along with the
+// error.
+func NewBootstrappedResolver(delegate SchemaResolver, scheme *runtime.Scheme, groups ...string) (*CachingResolver, error) {
+	r := NewCachingResolver(delegate)
+	return r, r.Warm(GVKsFromScheme(scheme, groups...))
+}
+
+// GVKsFromScheme returns the external GVKs known to the scheme, sorted.
+// If groups are given, only the GVKs of these groups are returned.
+func GVKsFromScheme(scheme *runtime.Scheme, groups ...string) []schema.GroupVersionKind {
+	groupSet := sets.New(groups...)
+	var result []schema.GroupVersionKind
+	for gvk := range scheme.AllKnownTypes() {
+		if gvk.Version == runtime.APIVersionInternal {
+			continue
+		}
+		if groupSet.Len() > 0 && !groupSet.Has(gvk.Group) {
+			continue
+		}
+		result = append(result, gvk)
+	}
+	sortGVKs(result)
+	return result
+}
+
+// deepCopy returns a deep copy of the schema by round-tripping it through
+// JSON. Resolved schemas are trees, since PopulateRefs breaks circular Refs,
+// so this always terminates.
+func deepCopy(s *spec.Schema) (*spec.Schema, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("cannot deep copy schema: %w", err)
+	}
+	result := new(spec.Schema)
+	if err := json.Unmarshal(b, result); err != nil {
+		return nil, fmt.Errorf("cannot deep copy schema: %w", err)
+	}
+	return result, nil
+}
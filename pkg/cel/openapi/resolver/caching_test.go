@@ -0,0 +1,301 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestNewBootstrappedResolver(t *testing.T) {
+	subscription := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	localization := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Localization"}
+	other := schema.GroupVersionKind{Group: "other.example.com", Version: "v1", Kind: "Other"}
+	scheme := runtime.NewScheme()
+	// reuse existing Go types for the definition names.
+	scheme.AddKnownTypeWithName(subscription, &corev1.ConfigMap{})
+	scheme.AddKnownTypeWithName(localization, &corev1.Secret{})
+	scheme.AddKnownTypeWithName(other, &corev1.Service{})
+	scheme.AddKnownTypeWithName(subscription.GroupKind().WithVersion(runtime.APIVersionInternal), &corev1.Pod{})
+
+	getDefinitions := func(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		return map[string]common.OpenAPIDefinition{
+			"k8s.io/api/core/v1.ConfigMap": {Schema: *objectSchema(map[string]spec.Schema{"data": scalarSchema("string", "")})},
+			"k8s.io/api/core/v1.Secret":    {Schema: *objectSchema(map[string]spec.Schema{"data": scalarSchema("string", "byte")})},
+			"k8s.io/api/core/v1.Service":   {Schema: *objectSchema(nil)},
+		}
+	}
+	delegate := newCountingResolver(NewDefinitionsSchemaResolver(getDefinitions, scheme))
+	r, err := NewBootstrappedResolver(delegate, scheme, "apps.clusternet.io")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, gvk := range []schema.GroupVersionKind{subscription, localization} {
+		if delegate.calls[gvk] != 1 {
+			t.Errorf("expected %v to be resolved once at boot, got %d", gvk, delegate.calls[gvk])
+		}
+		s, err := r.ResolveSchema(gvk)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := s.Properties["data"]; !ok {
+			t.Errorf("unexpected schema for %v: %v", gvk, s)
+		}
+		if delegate.calls[gvk] != 1 {
+			t.Errorf("expected a cache hit for %v after bootstrap, got %d delegate calls", gvk, delegate.calls[gvk])
+		}
+	}
+	if delegate.calls[other] != 0 {
+		t.Errorf("expected GVKs of other groups not to be resolved, got %d calls", delegate.calls[other])
+	}
+
+	// results are copies of the cached schemas.
+	s, _ := r.ResolveSchema(subscription)
+	delete(s.Properties, "data")
+	s, _ = r.ResolveSchema(subscription)
+	if _, ok := s.Properties["data"]; !ok {
+		t.Errorf("mutating a result must not affect the cache")
+	}
+}
+
+func TestCachingResolverWarmErrors(t *testing.T) {
+	a := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "A"}
+	b := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "B"}
+	c := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "C"}
+	r := NewCachingResolver(staticResolver{a: objectSchema(nil)})
+	err := r.Warm([]schema.GroupVersionKind{a, b, c})
+	if !errors.Is(err, ErrSchemaNotFound) {
+		t.Fatalf("expected ErrSchemaNotFound, got %v", err)
+	}
+	if expected := "cannot resolve example.com/v1, Kind=B: schema not found\ncannot resolve example.com/v1, Kind=C: schema not found"; err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestCachingResolverReadOnlyResults(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	r := NewCachingResolver(newEmbeddedDiscoveryResolver())
+	r.ReadOnlyResults = true
+	r.Debug = true
+
+	first, err := r.ResolveSchema(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := r.ResolveSchema(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first != second {
+		t.Errorf("expected the cached schema to be shared, got distinct schemas")
+	}
+
+	// an illegal write to the shared schema.
+	containers := second.Properties["spec"].Properties["containers"]
+	containers.Items.Schema.Description = "mutated"
+	if _, err := r.ResolveSchema(pod); !errors.Is(err, ErrCachedSchemaMutated) {
+		t.Errorf("expected ErrCachedSchemaMutated, got %v", err)
+	}
+	if _, _, err := r.ResolveSchemaWithDiagnostics(pod); !errors.Is(err, ErrCachedSchemaMutated) {
+		t.Errorf("expected ErrCachedSchemaMutated, got %v", err)
+	}
+
+	// the schema is resolved again once invalidated.
+	r.InvalidateGroupVersion(pod.GroupVersion())
+	if _, err := r.ResolveSchema(pod); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCachingResolverCopiesResults(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	r := NewCachingResolver(newEmbeddedDiscoveryResolver())
+	first, err := r.ResolveSchema(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first.Properties["spec"].Properties["containers"].Items.Schema.Description = "mutated"
+	second, err := r.ResolveSchema(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first == second {
+		t.Errorf("expected a fresh copy per call, got the same schema")
+	}
+	if d := second.Properties["spec"].Properties["containers"].Items.Schema.Description; d == "mutated" {
+		t.Errorf("expected the cached schema not to be affected by mutations of a copy, got description %q", d)
+	}
+}
+
+func TestCachingResolverMaxEntries(t *testing.T) {
+	gvks := make([]schema.GroupVersionKind, 3)
+	static := staticResolver{}
+	for i, kind := range []string{"A", "B", "C"} {
+		gvks[i] = schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: kind}
+		static[gvks[i]] = objectSchema(nil)
+	}
+	a, b, c := gvks[0], gvks[1], gvks[2]
+	delegate := newCountingResolver(static)
+	r := NewCachingResolver(delegate)
+	r.MaxEntries = 2
+
+	for _, gvk := range []schema.GroupVersionKind{a, b, a, c, a, b} {
+		if _, err := r.ResolveSchema(gvk); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// b is evicted by c since a was used more recently, and c by b.
+	expected := map[schema.GroupVersionKind]int{a: 1, b: 2, c: 1}
+	for gvk, calls := range expected {
+		if delegate.calls[gvk] != calls {
+			t.Errorf("expected %d delegate calls for %v, got %d", calls, gvk, delegate.calls[gvk])
+		}
+	}
+	if len(r.cache) != 2 || r.recency.Len() != 2 || len(r.elements) != 2 {
+		t.Errorf("expected 2 cached schemas, got %d with %d tracked", len(r.cache), r.recency.Len())
+	}
+}
+
+func TestCachingResolverInvalidate(t *testing.T) {
+	a := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "A"}
+	b := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "B"}
+	delegate := newCountingResolver(staticResolver{a: objectSchema(nil), b: objectSchema(nil)})
+	r := NewCachingResolver(delegate)
+	r.MaxEntries = 2
+	resolve := func(gvks ...schema.GroupVersionKind) {
+		t.Helper()
+		for _, gvk := range gvks {
+			if _, err := r.ResolveSchema(gvk); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+
+	resolve(a, b)
+	r.Invalidate(a)
+	resolve(a, b)
+	if delegate.calls[a] != 2 || delegate.calls[b] != 1 {
+		t.Errorf("expected only the invalidated GVK to be resolved again, got %v", delegate.calls)
+	}
+	r.InvalidateAll()
+	if len(r.cache) != 0 || r.recency.Len() != 0 || len(r.elements) != 0 {
+		t.Errorf("expected no cached schemas, got %d", len(r.cache))
+	}
+	resolve(a, b)
+	if delegate.calls[a] != 3 || delegate.calls[b] != 2 {
+		t.Errorf("expected all GVKs to be resolved again, got %v", delegate.calls)
+	}
+}
+
+func TestCachingResolverInvalidateDuringResolution(t *testing.T) {
+	a := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "A"}
+	var r *CachingResolver
+	for _, tc := range []struct {
+		name       string
+		invalidate func()
+	}{
+		{name: "gvk", invalidate: func() { r.Invalidate(a) }},
+		{name: "group-version", invalidate: func() { r.InvalidateGroupVersion(a.GroupVersion()) }},
+		{name: "all", invalidate: func() { r.InvalidateAll() }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			r = NewCachingResolver(FuncResolver(func(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+				calls++
+				// e.g. the CRD changes while the stale schema is resolved.
+				if calls == 1 {
+					tc.invalidate()
+				}
+				return objectSchema(nil), nil
+			}))
+			for i := 0; i < 3; i++ {
+				if _, err := r.ResolveSchema(a); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if calls != 2 {
+				t.Errorf("expected the schema resolved during the invalidation not to be cached, got %d delegate calls", calls)
+			}
+		})
+	}
+}
+
+func TestCachingResolverMaxEntriesSetLater(t *testing.T) {
+	static := staticResolver{}
+	r := NewCachingResolver(static)
+	for _, kind := range []string{"A", "B", "C"} {
+		gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: kind}
+		static[gvk] = objectSchema(nil)
+		if _, err := r.ResolveSchema(gvk); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// the schemas cached while unbounded are evicted once MaxEntries is set.
+	r.MaxEntries = 1
+	d := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "D"}
+	static[d] = objectSchema(nil)
+	if _, err := r.ResolveSchema(d); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := r.cache[d]; len(r.cache) != 1 || !ok {
+		t.Errorf("expected only %v to be cached, got %d schemas", d, len(r.cache))
+	}
+}
+
+func BenchmarkCachingResolver(b *testing.B) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	b.Run("uncached", func(b *testing.B) {
+		r := newEmbeddedDiscoveryResolver()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := r.ResolveSchema(pod); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+	for _, bc := range []struct {
+		name       string
+		readOnly   bool
+		maxEntries int
+	}{
+		{name: "copy"},
+		{name: "read-only", readOnly: true},
+		{name: "bounded read-only", readOnly: true, maxEntries: 16},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			r := NewCachingResolver(newEmbeddedDiscoveryResolver())
+			r.ReadOnlyResults = bc.readOnly
+			r.MaxEntries = bc.maxEntries
+			if _, err := r.ResolveSchema(pod); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := r.ResolveSchema(pod); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ChainResolver tries a list of SchemaResolvers in order and returns the
+// first schema resolved.
+type ChainResolver struct {
+	Resolvers []SchemaResolver
+}
+
+var _ SchemaResolver = (*ChainResolver)(nil)
+
+// ResolveSchema resolves the schema with each resolver in order, moving to
+// the next one only if the error wraps ErrSchemaNotFound. Any other error
+// aborts the chain and is returned as is, so that real problems are not
+// masked. If no resolver finds the schema, the returned error wraps
+// ErrSchemaNotFound.
+func (r *ChainResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema, passing ctx
+// to the resolvers.
+func (r *ChainResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	for _, resolver := range r.Resolvers {
+		s, err := resolver.ResolveSchemaWithContext(ctx, gvk)
+		if err == nil {
+			return s, nil
+		}
+		if !errors.Is(err, ErrSchemaNotFound) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("cannot resolve %v with any of %d resolvers: %w", gvk, len(r.Resolvers), ErrSchemaNotFound)
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestChainResolver(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	errUnavailable := errors.New("discovery unavailable")
+	local := objectSchema(nil)
+	remote := objectSchema(nil)
+	failing := FuncResolver(func(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+		return nil, fmt.Errorf("cannot resolve %v: %w", gvk, errUnavailable)
+	})
+
+	for _, tc := range []struct {
+		name      string
+		resolvers []SchemaResolver
+		want      *spec.Schema
+		wantErr   error
+		// wantCalls is the number of calls expected to the last resolver.
+		wantCalls int
+	}{
+		{
+			name:      "first resolver wins",
+			resolvers: []SchemaResolver{staticResolver{pod: local}, staticResolver{pod: remote}},
+			want:      local,
+		},
+		{
+			name:      "falls back on not found",
+			resolvers: []SchemaResolver{staticResolver{}, staticResolver{pod: remote}},
+			want:      remote,
+			wantCalls: 1,
+		},
+		{
+			name:      "other errors abort the chain",
+			resolvers: []SchemaResolver{failing, staticResolver{pod: remote}},
+			wantErr:   errUnavailable,
+		},
+		{
+			name:      "not found by any resolver",
+			resolvers: []SchemaResolver{staticResolver{}, staticResolver{}},
+			wantErr:   ErrSchemaNotFound,
+			wantCalls: 1,
+		},
+		{
+			name:    "empty chain",
+			wantErr: ErrSchemaNotFound,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var last *countingResolver
+			resolvers := tc.resolvers
+			if len(resolvers) > 0 {
+				last = newCountingResolver(resolvers[len(resolvers)-1])
+				resolvers = append(resolvers[:len(resolvers)-1:len(resolvers)-1], last)
+			}
+			r := &ChainResolver{Resolvers: resolvers}
+			s, err := r.ResolveSchema(pod)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if s != tc.want {
+				t.Errorf("expected schema %v, got %v", tc.want, s)
+			}
+			if last != nil && last.calls[pod] != tc.wantCalls {
+				t.Errorf("expected %d calls to the last resolver, got %d", tc.wantCalls, last.calls[pod])
+			}
+		})
+	}
+}
@@ -17,7 +17,10 @@ limitations under the License.
 package resolver
 
 import (
+	"context"
+
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/kube-openapi/pkg/validation/spec"
 )
 
@@ -33,13 +36,41 @@ type combinedSchemaResolver struct {
 	secondary   SchemaResolver
 }
 
+var _ GVKLister = (*combinedSchemaResolver)(nil)
+
 // ResolveSchema takes a GroupVersionKind (GVK) and returns the OpenAPI schema
 // identified by the GVK.
 // If the DefinitionsSchemaResolver knows the gvk, the DefinitionsSchemaResolver handles the resolution,
 // otherwise, the secondary does.
 func (r *combinedSchemaResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema, passing ctx
+// to the resolvers.
+func (r *combinedSchemaResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
 	if _, ok := r.definitions.gvkToRef[gvk]; ok {
-		return r.definitions.ResolveSchema(gvk)
+		return r.definitions.ResolveSchemaWithContext(ctx, gvk)
+	}
+	return r.secondary.ResolveSchemaWithContext(ctx, gvk)
+}
+
+// GVKs returns the GVKs of the DefinitionsSchemaResolver merged with those of
+// the secondary, if it is a GVKLister, sorted.
+func (r *combinedSchemaResolver) GVKs() ([]schema.GroupVersionKind, error) {
+	gvks, err := r.definitions.GVKs()
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := r.secondary.(GVKLister)
+	if !ok {
+		return gvks, nil
+	}
+	secondary, err := lister.GVKs()
+	if err != nil {
+		return nil, err
 	}
-	return r.secondary.ResolveSchema(gvk)
+	merged := sets.New(gvks...).Insert(secondary...).UnsortedList()
+	sortGVKs(merged)
+	return merged, nil
 }
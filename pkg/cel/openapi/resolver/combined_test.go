@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestCombineDefinitionsWithCRDs(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	subscription := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	definitions := NewDefinitionsSchemaResolver(func(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		return map[string]common.OpenAPIDefinition{
+			"k8s.io/api/core/v1.Pod": {Schema: *objectSchema(map[string]spec.Schema{
+				"spec": scalarSchema("string", ""),
+			})},
+		}
+	}, scheme.Scheme)
+	crd := newCRD("apiextensions.k8s.io/v1", subscription.Group, subscription.Kind, map[string]interface{}{
+		"versions": []interface{}{
+			map[string]interface{}{
+				"name":   subscription.Version,
+				"schema": map[string]interface{}{"openAPIV3Schema": openAPIV3Schema("subscribers")},
+			},
+			// a version without schema is not resolvable.
+			map[string]interface{}{"name": "v1alpha2"},
+		},
+	})
+	lists := 0
+	crds := &CRDSchemaResolver{CRDs: func() ([]*unstructured.Unstructured, error) {
+		lists++
+		return []*unstructured.Unstructured{crd}, nil
+	}}
+	r := definitions.Combine(crds)
+
+	s, err := r.ResolveSchema(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := s.Properties["spec"]; !ok || lists != 0 {
+		t.Errorf("expected the built-in schema without consulting the CRDs, got %v after %d lists", s, lists)
+	}
+	s, err = r.ResolveSchema(subscription)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := s.Properties["subscribers"]; !ok {
+		t.Errorf("expected the schema of the CRD, got %v", s)
+	}
+
+	lister, ok := r.(GVKLister)
+	if !ok {
+		t.Fatalf("expected the combined resolver to list its GVKs")
+	}
+	gvks, err := lister.GVKs()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []schema.GroupVersionKind{pod, subscription}; !reflect.DeepEqual(gvks, expected) {
+		t.Errorf("expected %v, got %v", expected, gvks)
+	}
+
+	// a secondary that cannot list its GVKs contributes none.
+	gvks, err = definitions.Combine(staticResolver{subscription: objectSchema(nil)}).(GVKLister).GVKs()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []schema.GroupVersionKind{pod}; !reflect.DeepEqual(gvks, expected) {
+		t.Errorf("expected %v, got %v", expected, gvks)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// SchemaChangeType is the type of a SchemaChange.
+type SchemaChangeType string
+
+const (
+	// FieldAdded is a field that only the new schema defines.
+	FieldAdded SchemaChangeType = "FieldAdded"
+	// FieldRemoved is a field that only the old schema defines.
+	FieldRemoved SchemaChangeType = "FieldRemoved"
+	// TypeChanged is a field whose type or format differs.
+	TypeChanged SchemaChangeType = "TypeChanged"
+	// RequiredAdded is a field that only the new schema requires.
+	RequiredAdded SchemaChangeType = "RequiredAdded"
+	// RequiredRemoved is a field that only the old schema requires.
+	RequiredRemoved SchemaChangeType = "RequiredRemoved"
+)
+
+// SchemaChange is a difference between two resolved schemas.
+type SchemaChange struct {
+	// Path is the path of the changed field, in the notation of WalkSchema.
+	Path string
+	Type SchemaChangeType
+	// Old and New describe the type of the field before and after a
+	// TypeChanged.
+	Old, New string
+}
+
+// DiffSchemas returns the differences between the fields of two resolved
+// schemas, ordered by path. The subtrees of added and removed fields are not
+// compared. Refs left unresolved, e.g. the circular Refs of recursive
+// definitions, are compared by the definitions they refer to rather than by
+// their expanded shapes: a field whose Ref changes, or that becomes or stops
+// being a Ref, is a TypeChanged from or to the Ref.
+func DiffSchemas(oldSchema, newSchema *spec.Schema) []SchemaChange {
+	var changes []SchemaChange
+	diffSchemas("", oldSchema, newSchema, &changes)
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func diffSchemas(path string, oldSchema, newSchema *spec.Schema, changes *[]SchemaChange) {
+	if oldType, newType := typeOf(oldSchema), typeOf(newSchema); oldType != newType {
+		*changes = append(*changes, SchemaChange{Path: path, Type: TypeChanged, Old: oldType, New: newType})
+	}
+	_, oldIsRef := refOf(oldSchema)
+	_, newIsRef := refOf(newSchema)
+	if oldIsRef || newIsRef {
+		return
+	}
+	oldRequired, newRequired := sets.New(oldSchema.Required...), sets.New(newSchema.Required...)
+	for _, name := range sets.List(newRequired.Difference(oldRequired)) {
+		*changes = append(*changes, SchemaChange{Path: childPath(path, name), Type: RequiredAdded})
+	}
+	for _, name := range sets.List(oldRequired.Difference(newRequired)) {
+		*changes = append(*changes, SchemaChange{Path: childPath(path, name), Type: RequiredRemoved})
+	}
+	names := sets.New[string]()
+	for name := range oldSchema.Properties {
+		names.Insert(name)
+	}
+	for name := range newSchema.Properties {
+		names.Insert(name)
+	}
+	for _, name := range sets.List(names) {
+		oldProp, inOld := oldSchema.Properties[name]
+		newProp, inNew := newSchema.Properties[name]
+		switch {
+		case !inNew:
+			*changes = append(*changes, SchemaChange{Path: childPath(path, name), Type: FieldRemoved})
+		case !inOld:
+			*changes = append(*changes, SchemaChange{Path: childPath(path, name), Type: FieldAdded})
+		default:
+			diffSchemas(childPath(path, name), &oldProp, &newProp, changes)
+		}
+	}
+	if oldSchema.Items != nil && oldSchema.Items.Schema != nil && newSchema.Items != nil && newSchema.Items.Schema != nil {
+		diffSchemas(path+"[*]", oldSchema.Items.Schema, newSchema.Items.Schema, changes)
+	}
+	if oldSchema.AdditionalProperties != nil && oldSchema.AdditionalProperties.Schema != nil && newSchema.AdditionalProperties != nil && newSchema.AdditionalProperties.Schema != nil {
+		diffSchemas(path+"[*]", oldSchema.AdditionalProperties.Schema, newSchema.AdditionalProperties.Schema, changes)
+	}
+}
+
+// typeOf describes the type and format of a schema, e.g. "string/date-time",
+// or the Ref of an unresolved schema.
+func typeOf(s *spec.Schema) string {
+	if ref, isRef := refOf(s); isRef {
+		return ref
+	}
+	t := strings.Join(s.Type, ",")
+	if len(s.Format) > 0 {
+		return t + "/" + s.Format
+	}
+	return t
+}
+
+// CompatibilityIssue is a change of the schema of a GVK between two versions.
+type CompatibilityIssue struct {
+	GVK schema.GroupVersionKind
+	SchemaChange
+	// Breaking is true if objects or CEL rules that are valid against the old
+	// schema may not be valid against the new one.
+	Breaking bool
+}
+
+// CheckCompatibility resolves each GVK with both resolvers and reports the
+// changes from the old to the new schema, classified as breaking or not:
+// removing a field, changing its type other than widening an integer to a
+// number, and newly requiring a field are breaking, while adding an optional
+// field and no longer requiring a field are not. A GVK that only the new
+// resolver finds is skipped; one that only the old resolver finds is reported
+// as a breaking removal at the root path. Other errors abort the check.
+func CheckCompatibility(oldResolver, newResolver SchemaResolver, gvks []schema.GroupVersionKind) ([]CompatibilityIssue, error) {
+	var issues []CompatibilityIssue
+	for _, gvk := range gvks {
+		oldSchema, err := oldResolver.ResolveSchema(gvk)
+		if errors.Is(err, ErrSchemaNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		newSchema, err := newResolver.ResolveSchema(gvk)
+		if errors.Is(err, ErrSchemaNotFound) {
+			issues = append(issues, CompatibilityIssue{GVK: gvk, SchemaChange: SchemaChange{Type: FieldRemoved}, Breaking: true})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range DiffSchemas(oldSchema, newSchema) {
+			issues = append(issues, CompatibilityIssue{GVK: gvk, SchemaChange: change, Breaking: isBreaking(change)})
+		}
+	}
+	return issues, nil
+}
+
+func isBreaking(change SchemaChange) bool {
+	switch change.Type {
+	case FieldAdded, RequiredRemoved:
+		return false
+	case TypeChanged:
+		// every integer is a number.
+		return !(strings.HasPrefix(change.Old, "integer") && change.New == "number")
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	removed := schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Deployment"}
+	added := schema.GroupVersionKind{Group: "apps", Version: "v2", Kind: "Deployment"}
+	oldResolver := staticResolver{
+		deployment: objectSchema(map[string]spec.Schema{
+			"spec": *objectSchema(map[string]spec.Schema{
+				"replicas": scalarSchema("integer", "int32"),
+				"selector": scalarSchema("string", ""),
+				"paused":   scalarSchema("boolean", ""),
+			}),
+		}),
+		removed: objectSchema(nil),
+	}
+	newResolver := staticResolver{
+		deployment: objectSchema(map[string]spec.Schema{
+			"spec": *objectSchema(map[string]spec.Schema{
+				"replicas":        scalarSchema("number", ""),
+				"selector":        scalarSchema("string", ""),
+				"minReadySeconds": scalarSchema("integer", "int32"),
+				"paused":          scalarSchema("string", ""),
+			}, "selector"),
+		}),
+		added: objectSchema(nil),
+	}
+
+	issues, err := CheckCompatibility(oldResolver, newResolver, []schema.GroupVersionKind{deployment, removed, added})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []CompatibilityIssue{
+		{GVK: deployment, SchemaChange: SchemaChange{Path: "spec.minReadySeconds", Type: FieldAdded}, Breaking: false},
+		{GVK: deployment, SchemaChange: SchemaChange{Path: "spec.paused", Type: TypeChanged, Old: "boolean", New: "string"}, Breaking: true},
+		{GVK: deployment, SchemaChange: SchemaChange{Path: "spec.replicas", Type: TypeChanged, Old: "integer/int32", New: "number"}, Breaking: false},
+		{GVK: deployment, SchemaChange: SchemaChange{Path: "spec.selector", Type: RequiredAdded}, Breaking: true},
+		{GVK: removed, SchemaChange: SchemaChange{Type: FieldRemoved}, Breaking: true},
+	}
+	if !reflect.DeepEqual(issues, expected) {
+		t.Errorf("expected %+v, got %+v", expected, issues)
+	}
+}
+
+func TestDiffSchemas(t *testing.T) {
+	oldSchema := objectSchema(map[string]spec.Schema{
+		"ports": {SchemaProps: spec.SchemaProps{Type: []string{"array"}, Items: &spec.SchemaOrArray{Schema: objectSchema(map[string]spec.Schema{
+			"port":     scalarSchema("integer", "int32"),
+			"protocol": scalarSchema("string", ""),
+		}, "port")}}},
+	})
+	newSchema := objectSchema(map[string]spec.Schema{
+		"ports": {SchemaProps: spec.SchemaProps{Type: []string{"array"}, Items: &spec.SchemaOrArray{Schema: objectSchema(map[string]spec.Schema{
+			"port": scalarSchema("integer", "int32"),
+		})}}},
+	})
+	expected := []SchemaChange{
+		{Path: "ports[*].port", Type: RequiredRemoved},
+		{Path: "ports[*].protocol", Type: FieldRemoved},
+	}
+	if got := DiffSchemas(oldSchema, newSchema); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	if got := DiffSchemas(oldSchema, oldSchema); len(got) != 0 {
+		t.Errorf("expected no changes, got %+v", got)
+	}
+}
+
+func TestDiffSchemasUnresolvedRefs(t *testing.T) {
+	ref := func(name string) spec.Schema {
+		return spec.Schema{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef(refPrefix + name)}}
+	}
+	oldSchema := objectSchema(map[string]spec.Schema{
+		"parent":   ref("Node"),
+		"children": ref("Node"),
+		"value":    scalarSchema("string", ""),
+	})
+	newSchema := objectSchema(map[string]spec.Schema{
+		"parent":   ref("Node"),
+		"children": ref("NodeList"),
+		"value":    ref("Value"),
+	})
+	expected := []SchemaChange{
+		{Path: "children", Type: TypeChanged, Old: refPrefix + "Node", New: refPrefix + "NodeList"},
+		{Path: "value", Type: TypeChanged, Old: "string", New: refPrefix + "Value"},
+	}
+	if got := DiffSchemas(oldSchema, newSchema); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
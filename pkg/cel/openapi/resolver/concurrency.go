@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ErrConcurrencyLimitExceeded is wrapped and returned by a fail-fast
+// ConcurrencyLimitedResolver if all of its slots are in use.
+var ErrConcurrencyLimitExceeded = errors.New("concurrent resolution limit exceeded")
+
+// ConcurrencyLimitedResolver wraps a SchemaResolver and caps the number of
+// resolutions in flight at the same time, so that a burst of resolutions
+// cannot monopolize the CPU. Calls beyond the limit either wait for a slot or
+// fail fast with ErrConcurrencyLimitExceeded.
+type ConcurrencyLimitedResolver struct {
+	delegate SchemaResolver
+	failFast bool
+	slots    chan struct{}
+}
+
+var _ SchemaResolver = (*ConcurrencyLimitedResolver)(nil)
+
+// NewConcurrencyLimitedResolver creates a ConcurrencyLimitedResolver that runs
+// at most limit resolutions of the delegate at a time. If failFast is set,
+// calls beyond the limit fail instead of waiting. limit must be positive.
+func NewConcurrencyLimitedResolver(delegate SchemaResolver, limit int, failFast bool) *ConcurrencyLimitedResolver {
+	if limit <= 0 {
+		panic(fmt.Sprintf("concurrency limit must be positive, got %d", limit))
+	}
+	return &ConcurrencyLimitedResolver{
+		delegate: delegate,
+		failFast: failFast,
+		slots:    make(chan struct{}, limit),
+	}
+}
+
+func (r *ConcurrencyLimitedResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema, passing ctx
+// to the delegate. While waiting for a slot, the call is aborted if ctx is
+// done, and the returned error wraps the error of ctx.
+func (r *ConcurrencyLimitedResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	if r.failFast {
+		select {
+		case r.slots <- struct{}{}:
+		default:
+			return nil, fmt.Errorf("cannot resolve %v: %w", gvk, ErrConcurrencyLimitExceeded)
+		}
+	} else {
+		select {
+		case r.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("cannot resolve %v while waiting for a slot: %w", gvk, ctx.Err())
+		}
+	}
+	defer func() { <-r.slots }()
+	return r.delegate.ResolveSchemaWithContext(ctx, gvk)
+}
+
+// InFlight returns the number of resolutions currently in flight.
+func (r *ConcurrencyLimitedResolver) InFlight() int {
+	return len(r.slots)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// blockingResolver blocks every resolution until release is closed.
+type blockingResolver struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	r.started <- struct{}{}
+	<-r.release
+	return objectSchema(nil), nil
+}
+
+func (r *blockingResolver) ResolveSchemaWithContext(_ context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchema(gvk)
+}
+
+func TestConcurrencyLimitedResolver(t *testing.T) {
+	const limit = 2
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	delegate := &blockingResolver{started: make(chan struct{}, limit+1), release: make(chan struct{})}
+	r := NewConcurrencyLimitedResolver(delegate, limit, false)
+
+	results := make(chan error, limit+1)
+	for i := 0; i < limit; i++ {
+		go func() {
+			_, err := r.ResolveSchema(gvk)
+			results <- err
+		}()
+		<-delegate.started
+	}
+	if got := r.InFlight(); got != limit {
+		t.Errorf("expected %d resolutions in flight, got %d", limit, got)
+	}
+
+	// the N+1th call blocks until a slot frees.
+	go func() {
+		_, err := r.ResolveSchema(gvk)
+		results <- err
+	}()
+	select {
+	case <-delegate.started:
+		t.Fatalf("expected the call beyond the limit to block")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// a call beyond the limit is aborted with its context.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := r.ResolveSchemaWithContext(ctx, gvk); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	close(delegate.release)
+	select {
+	case <-delegate.started:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("expected the blocked call to proceed once a slot freed")
+	}
+	for i := 0; i < limit+1; i++ {
+		if err := <-results; err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if got := r.InFlight(); got != 0 {
+		t.Errorf("expected no resolutions in flight, got %d", got)
+	}
+}
+
+func TestConcurrencyLimitedResolverFailFast(t *testing.T) {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	delegate := &blockingResolver{started: make(chan struct{}, 1), release: make(chan struct{})}
+	r := NewConcurrencyLimitedResolver(delegate, 1, true)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = r.ResolveSchema(gvk)
+	}()
+	<-delegate.started
+	if _, err := r.ResolveSchema(gvk); !errors.Is(err, ErrConcurrencyLimitExceeded) {
+		t.Errorf("expected ErrConcurrencyLimitExceeded, got %v", err)
+	}
+	close(delegate.release)
+	<-done
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// defaultDiscoveryQPS and defaultDiscoveryBurst are the client-side rate
+	// limits of the discovery client of NewClientDiscoveryResolverForConfig
+	// if config does not set any, as for the discovery of kubectl.
+	defaultDiscoveryQPS   = 50
+	defaultDiscoveryBurst = 300
+)
+
+// NewClientDiscoveryResolverForConfig returns a ClientDiscoveryResolver over a
+// memory-cached discovery client of the apiserver of config, so that the
+// OpenAPI documents are fetched once rather than for every resolution.
+// RefreshOnMiss is enabled so that types installed later still resolve, and
+// the rate limits of config default to those of kubectl if unset. config is
+// not mutated. The returned resolver can be further configured before use.
+func NewClientDiscoveryResolverForConfig(config *rest.Config) (*ClientDiscoveryResolver, error) {
+	if config == nil {
+		return nil, errors.New("cannot create a discovery client without a rest config")
+	}
+	config = rest.CopyConfig(config)
+	if config.QPS == 0 {
+		config.QPS = defaultDiscoveryQPS
+	}
+	if config.Burst == 0 {
+		config.Burst = defaultDiscoveryBurst
+	}
+	client, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create a discovery client for %q: %w", config.Host, err)
+	}
+	return &ClientDiscoveryResolver{
+		Discovery:     memory.NewMemCacheClient(client),
+		RefreshOnMiss: true,
+	}, nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestNewClientDiscoveryResolverForConfig(t *testing.T) {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	pod := withGVK(*objectSchema(map[string]spec.Schema{"kind": scalarSchema("string", "")}), gvk)
+	transport := &recordingTransport{responses: map[string][]byte{
+		"/openapi/v3":        []byte(`{"paths":{"api/v1":{"serverRelativeURL":"/openapi/v3/api/v1?hash=0123"}}}`),
+		"/openapi/v3/api/v1": newDocument(map[string]*spec.Schema{"io.k8s.api.core.v1.Pod": &pod}),
+	}}
+	config := &rest.Config{Host: "https://member.example.com", Transport: transport}
+	r, err := NewClientDiscoveryResolverForConfig(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.QPS != 0 || config.Burst != 0 {
+		t.Errorf("expected config not to be mutated")
+	}
+	if !r.UsesDiscoveryCache() || !r.RefreshOnMiss {
+		t.Errorf("expected a cached discovery client refreshed on misses")
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := r.ResolveSchema(gvk); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(transport.headers) != 2 {
+		t.Errorf("expected the paths and the document to be fetched once, got %d requests", len(transport.headers))
+	}
+}
+
+func TestNewClientDiscoveryResolverForConfigErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config *rest.Config
+	}{
+		{name: "nil config"},
+		{
+			name: "invalid TLS config",
+			config: &rest.Config{
+				Host:            "https://member.example.com",
+				TLSClientConfig: rest.TLSClientConfig{CAFile: "/nonexistent/ca.crt"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := NewClientDiscoveryResolverForConfig(tc.config)
+			if err == nil {
+				t.Errorf("expected an error, got resolver %v", r)
+			}
+		})
+	}
+}
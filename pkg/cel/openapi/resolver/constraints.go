@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"strings"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ResolveForPolicyConstraints resolves the schemas of all GVKs that the
+// matchConstraints of a ValidatingAdmissionPolicy may match, so that the
+// expressions of the policy can be compiled against every one of them.
+// The resource rules, including wildcards in groups, versions and resources,
+// are expanded against the resources served by discovery, taking subresources
+// and scopes into account; GVKs matched by the exclude rules are dropped.
+// Equivalent versions implied by matchPolicy are not added.
+// Like BatchResolve, the returned map holds the schemas that resolved
+// successfully, and the returned error joins the errors of the GVKs that
+// failed as well as any failure to discover some of the groups.
+func (r *ClientDiscoveryResolver) ResolveForPolicyConstraints(constraints *admissionregistrationv1.MatchResources) (map[schema.GroupVersionKind]*spec.Schema, error) {
+	if constraints == nil {
+		return map[schema.GroupVersionKind]*spec.Schema{}, nil
+	}
+	_, lists, discoveryErr := r.Discovery.ServerGroupsAndResources()
+	if discoveryErr != nil && !discovery.IsGroupDiscoveryFailedError(discoveryErr) {
+		return nil, discoveryErr
+	}
+	var gvks []schema.GroupVersionKind
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range list.APIResources {
+			if matchesAnyRule(constraints.ResourceRules, gv, resource) && !matchesAnyRule(constraints.ExcludeResourceRules, gv, resource) {
+				gvks = append(gvks, kindOfResource(gv, resource))
+			}
+		}
+	}
+	result, err := BatchResolve(r, gvks, BatchOptions{})
+	if discoveryErr != nil {
+		err = errors.Join(discoveryErr, err)
+	}
+	return result, err
+}
+
+// kindOfResource returns the GVK of a resource served in gv, which a
+// subresource may override.
+func kindOfResource(gv schema.GroupVersion, resource metav1.APIResource) schema.GroupVersionKind {
+	gvk := gv.WithKind(resource.Kind)
+	if len(resource.Group) > 0 {
+		gvk.Group = resource.Group
+	}
+	if len(resource.Version) > 0 {
+		gvk.Version = resource.Version
+	}
+	return gvk
+}
+
+func matchesAnyRule(rules []admissionregistrationv1.NamedRuleWithOperations, gv schema.GroupVersion, resource metav1.APIResource) bool {
+	for _, rule := range rules {
+		if matchesRule(rule.Rule, gv, resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesRule checks if a rule matches a resource served in gv, with the same
+// semantics as the admission matching of the rule.
+func matchesRule(rule admissionregistrationv1.Rule, gv schema.GroupVersion, resource metav1.APIResource) bool {
+	if !matchesValue(rule.APIGroups, gv.Group) || !matchesValue(rule.APIVersions, gv.Version) {
+		return false
+	}
+	if rule.Scope != nil {
+		switch *rule.Scope {
+		case admissionregistrationv1.ClusterScope:
+			if resource.Namespaced {
+				return false
+			}
+		case admissionregistrationv1.NamespacedScope:
+			if !resource.Namespaced {
+				return false
+			}
+		}
+	}
+	name, subresource, _ := strings.Cut(resource.Name, "/")
+	for _, pattern := range rule.Resources {
+		patternName, patternSubresource, _ := strings.Cut(pattern, "/")
+		if (patternName == "*" || patternName == name) &&
+			(patternSubresource == "*" || patternSubresource == subresource) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == "*" || v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"sort"
+	"testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/openapi/openapitest"
+)
+
+func TestResolveForPolicyConstraints(t *testing.T) {
+	d := newFakeDiscovery(openapitest.NewEmbeddedFileClient())
+	d.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "pods", Kind: "Pod", Namespaced: true},
+			{Name: "pods/status", Kind: "Pod", Namespaced: true},
+			{Name: "namespaces", Kind: "Namespace"},
+		}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{
+			{Name: "deployments", Kind: "Deployment", Namespaced: true},
+			{Name: "deployments/scale", Kind: "Scale", Group: "autoscaling", Version: "v1", Namespaced: true},
+			{Name: "statefulsets", Kind: "StatefulSet", Namespaced: true},
+		}},
+	}
+	r := &ClientDiscoveryResolver{Discovery: d}
+	rule := func(groups, versions, resources []string) admissionregistrationv1.NamedRuleWithOperations {
+		return admissionregistrationv1.NamedRuleWithOperations{RuleWithOperations: admissionregistrationv1.RuleWithOperations{
+			Rule: admissionregistrationv1.Rule{APIGroups: groups, APIVersions: versions, Resources: resources},
+		}}
+	}
+	namespaced := admissionregistrationv1.NamespacedScope
+
+	for _, tc := range []struct {
+		name        string
+		constraints *admissionregistrationv1.MatchResources
+		expected    []string
+	}{
+		{
+			name: "two resource types",
+			constraints: &admissionregistrationv1.MatchResources{ResourceRules: []admissionregistrationv1.NamedRuleWithOperations{
+				rule([]string{""}, []string{"v1"}, []string{"pods"}),
+				rule([]string{"apps"}, []string{"v1"}, []string{"deployments"}),
+			}},
+			expected: []string{"/v1, Kind=Pod", "apps/v1, Kind=Deployment"},
+		},
+		{
+			name: "wildcards",
+			constraints: &admissionregistrationv1.MatchResources{ResourceRules: []admissionregistrationv1.NamedRuleWithOperations{
+				rule([]string{"*"}, []string{"*"}, []string{"*"}),
+			}},
+			expected: []string{"/v1, Kind=Namespace", "/v1, Kind=Pod", "apps/v1, Kind=Deployment", "apps/v1, Kind=StatefulSet"},
+		},
+		{
+			name: "scope and exclusion",
+			constraints: &admissionregistrationv1.MatchResources{
+				ResourceRules: []admissionregistrationv1.NamedRuleWithOperations{func() admissionregistrationv1.NamedRuleWithOperations {
+					r := rule([]string{"*"}, []string{"*"}, []string{"*"})
+					r.Scope = &namespaced
+					return r
+				}()},
+				ExcludeResourceRules: []admissionregistrationv1.NamedRuleWithOperations{
+					rule([]string{"apps"}, []string{"*"}, []string{"statefulsets"}),
+				},
+			},
+			expected: []string{"/v1, Kind=Pod", "apps/v1, Kind=Deployment"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			schemas, err := r.ResolveForPolicyConstraints(tc.constraints)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for gvk, s := range schemas {
+				if s == nil {
+					t.Errorf("expected a schema for %v", gvk)
+				}
+				got = append(got, gvk.String())
+			}
+			sort.Strings(got)
+			if len(got) != len(tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+			for i := range got {
+				if got[i] != tc.expected[i] {
+					t.Errorf("expected %v, got %v", tc.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveForPolicyConstraintsSubresources(t *testing.T) {
+	gv := schema.GroupVersion{Group: "apps", Version: "v1"}
+	scale := metav1.APIResource{Name: "deployments/scale", Kind: "Scale", Group: "autoscaling", Version: "v1"}
+	for _, tc := range []struct {
+		resources []string
+		expected  bool
+	}{
+		{resources: []string{"*"}, expected: false},
+		{resources: []string{"deployments"}, expected: false},
+		{resources: []string{"*/*"}, expected: true},
+		{resources: []string{"deployments/*"}, expected: true},
+		{resources: []string{"*/scale"}, expected: true},
+	} {
+		rule := admissionregistrationv1.Rule{APIGroups: []string{"apps"}, APIVersions: []string{"v1"}, Resources: tc.resources}
+		if got := matchesRule(rule, gv, scale); got != tc.expected {
+			t.Errorf("resources %v: expected match %v, got %v", tc.resources, tc.expected, got)
+		}
+	}
+	if got, expected := kindOfResource(gv, scale), (schema.GroupVersionKind{Group: "autoscaling", Version: "v1", Kind: "Scale"}); got != expected {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// checkContext returns an error wrapping the error of ctx if ctx is done, for
+// resolvers that resolve locally and only check ctx before they start.
+func checkContext(ctx context.Context, gvk schema.GroupVersionKind) error {
+	if err := ctx.Err(); err != nil {
+		return wrapResolutionError(gvk, err)
+	}
+	return nil
+}
+
+// callWithContext calls fn, e.g. a network round trip through a client that
+// does not accept a context, and returns its results, or the error of ctx if
+// ctx is done first. In that case, fn is abandoned and
+// its results are discarded once it returns. A panic in fn is propagated to
+// the caller.
+func callWithContext[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	var zero T
+	if ctx.Done() == nil {
+		// ctx is never done.
+		return fn()
+	}
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	type result struct {
+		value    T
+		err      error
+		panicked interface{}
+	}
+	done := make(chan result, 1)
+	go func() {
+		var r result
+		defer func() {
+			if p := recover(); p != nil {
+				r.panicked = p
+			}
+			done <- r
+		}()
+		r.value, r.err = fn()
+	}()
+	select {
+	case r := <-done:
+		if r.panicked != nil {
+			panic(r.panicked)
+		}
+		return r.value, r.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/openapi"
+	"k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// hangingOpenAPIClient is an OpenAPI v3 client whose round trips hang until
+// release is closed, like a remote apiserver that does not respond.
+type hangingOpenAPIClient struct {
+	release chan struct{}
+}
+
+func (c hangingOpenAPIClient) Paths() (map[string]openapi.GroupVersion, error) {
+	<-c.release
+	return nil, nil
+}
+
+func TestResolveSchemaWithContextCancelled(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	release := make(chan struct{})
+	defer close(release)
+	discovery := &ClientDiscoveryResolver{Discovery: newFakeDiscovery(hangingOpenAPIClient{release: release})}
+
+	for _, tc := range []struct {
+		name     string
+		resolver SchemaResolver
+		// local resolvers do not block, and are called with ctx already
+		// cancelled.
+		local bool
+	}{
+		{name: "discovery", resolver: discovery},
+		{name: "caching", resolver: NewCachingResolver(discovery)},
+		{name: "chain", resolver: &ChainResolver{Resolvers: []SchemaResolver{discovery}}},
+		{name: "strict", resolver: &StrictResolver{Delegate: discovery}},
+		{name: "nested", resolver: ResolveNestedGVKFields(discovery)},
+		{name: "recovering", resolver: RecoverPanics(discovery)},
+		{name: "concurrency limited", resolver: NewConcurrencyLimitedResolver(discovery, 1, false)},
+		{name: "definitions", resolver: NewDefinitionsSchemaResolver(func(common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+			return nil
+		}), local: true},
+		{name: "func", resolver: FuncResolver(func(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+			return objectSchema(nil), nil
+		}), local: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			if tc.local {
+				cancel()
+			}
+			done := make(chan error, 1)
+			go func() {
+				_, err := tc.resolver.ResolveSchemaWithContext(ctx, pod)
+				done <- err
+			}()
+			cancel()
+			err := <-done
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("expected the error to wrap context.Canceled, got %v", err)
+			}
+			if errors.Is(err, ErrSchemaNotFound) {
+				t.Errorf("expected the error not to wrap ErrSchemaNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestResolveSchemaWithContext(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	r := newEmbeddedDiscoveryResolver()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, err := r.ResolveSchemaWithContext(ctx, pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := s.Properties["spec"]; !ok {
+		t.Errorf("expected the schema of Pod, got %v", s)
+	}
+	if _, err := r.ResolveSchemaWithContext(ctx, schema.GroupVersionKind{Version: "v1", Kind: "Missing"}); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// StatusConventionInfo describes where a schema that follows the Kubernetes
+// spec/status and conditions conventions declares the relevant fields.
+// Each path is dot-separated, relative to the root of the schema, and empty
+// if the schema does not declare the field.
+type StatusConventionInfo struct {
+	// SpecPath is the path of the spec, usually "spec".
+	SpecPath string
+	// StatusPath is the path of the status, usually "status".
+	StatusPath string
+	// ConditionsPath is the path of the list of conditions, usually "status.conditions".
+	ConditionsPath string
+	// ObservedGenerationPath is the path of the observed generation of the
+	// status, usually "status.observedGeneration".
+	ObservedGenerationPath string
+}
+
+// DetectStatusConvention inspects a resolved schema and reports whether it
+// follows the standard status and conditions pattern. A schema follows the
+// pattern if it has an object "status" with a list of "conditions" whose
+// items declare at least the "type" and "status" fields. The spec, the
+// observed generation and the other fields of a condition, such as "reason"
+// or "lastTransitionTime", are reported if present but are not required.
+func DetectStatusConvention(s *spec.Schema) (StatusConventionInfo, bool) {
+	var info StatusConventionInfo
+	if s == nil {
+		return info, false
+	}
+	if _, ok := s.Properties["spec"]; ok {
+		info.SpecPath = "spec"
+	}
+	status, ok := s.Properties["status"]
+	if !ok || !hasType(&status, "object") {
+		return info, false
+	}
+	info.StatusPath = "status"
+	if _, ok := status.Properties["observedGeneration"]; ok {
+		info.ObservedGenerationPath = "status.observedGeneration"
+	}
+	conditions, ok := status.Properties["conditions"]
+	if !ok || !hasType(&conditions, "array") || conditions.Items == nil || conditions.Items.Schema == nil {
+		return info, false
+	}
+	for _, field := range []string{"type", "status"} {
+		if _, ok := conditions.Items.Schema.Properties[field]; !ok {
+			return info, false
+		}
+	}
+	info.ConditionsPath = "status.conditions"
+	return info, true
+}
+
+// hasType checks if the schema declares the given type. Schemas that declare
+// no type but have properties are treated as objects.
+func hasType(s *spec.Schema, typ string) bool {
+	if len(s.Type) == 0 {
+		return typ == "object" && len(s.Properties) > 0
+	}
+	return s.Type.Contains(typ)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"testing"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func conditionsSchema(fields ...string) spec.Schema {
+	props := make(map[string]spec.Schema, len(fields))
+	for _, field := range fields {
+		props[field] = scalarSchema("string", "")
+	}
+	return spec.Schema{SchemaProps: spec.SchemaProps{
+		Type:  []string{"array"},
+		Items: &spec.SchemaOrArray{Schema: objectSchema(props)},
+	}}
+}
+
+func TestDetectStatusConvention(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		schema   *spec.Schema
+		wantOK   bool
+		wantInfo StatusConventionInfo
+	}{
+		{
+			name: "conforming",
+			schema: objectSchema(map[string]spec.Schema{
+				"spec": *objectSchema(nil),
+				"status": *objectSchema(map[string]spec.Schema{
+					"observedGeneration": scalarSchema("integer", "int64"),
+					"conditions":         conditionsSchema("type", "status", "reason", "message", "lastTransitionTime"),
+				}),
+			}),
+			wantOK: true,
+			wantInfo: StatusConventionInfo{
+				SpecPath:               "spec",
+				StatusPath:             "status",
+				ConditionsPath:         "status.conditions",
+				ObservedGenerationPath: "status.observedGeneration",
+			},
+		},
+		{
+			name: "conforming without spec and optional condition fields",
+			schema: objectSchema(map[string]spec.Schema{
+				"status": *objectSchema(map[string]spec.Schema{
+					"conditions": conditionsSchema("type", "status"),
+				}),
+			}),
+			wantOK: true,
+			wantInfo: StatusConventionInfo{
+				StatusPath:     "status",
+				ConditionsPath: "status.conditions",
+			},
+		},
+		{
+			name: "conditions without status field",
+			schema: objectSchema(map[string]spec.Schema{
+				"spec": *objectSchema(nil),
+				"status": *objectSchema(map[string]spec.Schema{
+					"conditions": conditionsSchema("type", "message"),
+				}),
+			}),
+			wantInfo: StatusConventionInfo{
+				SpecPath:   "spec",
+				StatusPath: "status",
+			},
+		},
+		{
+			name: "scalar status",
+			schema: objectSchema(map[string]spec.Schema{
+				"status": scalarSchema("string", ""),
+			}),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			info, ok := DetectStatusConvention(tc.schema)
+			if ok != tc.wantOK {
+				t.Errorf("expected %v, got %v", tc.wantOK, ok)
+			}
+			if info != tc.wantInfo {
+				t.Errorf("expected %+v, got %+v", tc.wantInfo, info)
+			}
+		})
+	}
+}
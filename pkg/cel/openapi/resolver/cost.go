@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"sort"
+
+	apiservercel "k8s.io/apiserver/pkg/cel"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ErrNotExposedToCEL is returned by EstimateCELCost and SchemaToDeclType if
+// a schema has no CEL declaration, e.g. an array without items.
+var ErrNotExposedToCEL = errors.New("schema is not exposed to CEL")
+
+// CostEstimate is the worst-case contribution of the structure of a schema to
+// the cost of CEL rules that are evaluated against it.
+type CostEstimate struct {
+	// Cost is the worst-case cost of a rule that visits every value of an
+	// object once, e.g. with nested all() macros, and traverses every string
+	// and byte value, e.g. with matches(). It approximates how close rules
+	// that traverse the object come to the cost limits, rather than the cost
+	// of any specific rule. It saturates at math.MaxUint64.
+	Cost uint64
+	// MaxCardinality is the worst-case number of values of each field in one
+	// object, i.e. the product of the maximum sizes of the lists and maps
+	// enclosing it, keyed by the path in the notation of WalkSchema. It
+	// saturates at math.MaxUint64.
+	MaxCardinality map[string]uint64
+	// Unbounded are the sorted paths of the lists, maps and strings whose size
+	// is estimated from the maximum size of a request because they declare no
+	// maxItems, maxProperties or maxLength.
+	Unbounded []string
+}
+
+// EstimateCELCost estimates the worst-case cost contribution of a resolved
+// schema to CEL rules, based on its CEL declaration, which bounds lists,
+// maps and strings by maxItems, maxProperties and maxLength, or by the
+// maximum size of a request if those are not declared, the same way the cost
+// estimation of rules does.
+func EstimateCELCost(s *spec.Schema) (CostEstimate, error) {
+	declType, err := SchemaToDeclType(s)
+	if err != nil {
+		return CostEstimate{}, err
+	}
+	estimate := CostEstimate{MaxCardinality: make(map[string]uint64)}
+	estimateCost("", s, declType, 1, &estimate)
+	_ = WalkSchema(s, func(path string, node *spec.Schema) error {
+		if _, ok := estimate.MaxCardinality[path]; !ok {
+			// not exposed to CEL.
+			return errSkipNode
+		}
+		if isUnbounded(node) {
+			estimate.Unbounded = append(estimate.Unbounded, path)
+		}
+		return nil
+	})
+	sort.Strings(estimate.Unbounded)
+	return estimate, nil
+}
+
+// estimateCost adds the cost of the node s, of the CEL type t, that has the
+// given cardinality, and of its descendants to estimate.
+func estimateCost(path string, s *spec.Schema, t *apiservercel.DeclType, cardinality uint64, estimate *CostEstimate) {
+	estimate.MaxCardinality[path] = cardinality
+	estimate.Cost = saturatingAdd(estimate.Cost, cardinality)
+	switch {
+	case t.IsList():
+		if s.Items != nil && s.Items.Schema != nil {
+			estimateCost(path+"[*]", s.Items.Schema, t.ElemType, saturatingMul(cardinality, uint64(t.MaxElements)), estimate)
+		}
+	case t.IsMap():
+		if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+			estimateCost(path+"[*]", s.AdditionalProperties.Schema, t.ElemType, saturatingMul(cardinality, uint64(t.MaxElements)), estimate)
+		}
+	case t.IsObject():
+		for name, prop := range s.Properties {
+			escaped, ok := apiservercel.Escape(name)
+			if !ok {
+				continue
+			}
+			field, ok := t.Fields[escaped]
+			if !ok {
+				continue
+			}
+			estimateCost(childPath(path, name), &prop, field.Type, cardinality, estimate)
+		}
+	default:
+		// strings and bytes are traversed by their length.
+		if t.MaxElements > 0 {
+			estimate.Cost = saturatingAdd(estimate.Cost, saturatingMul(cardinality, uint64(t.MaxElements)))
+		}
+	}
+}
+
+// isUnbounded checks if the size of a list, map or string is not declared.
+func isUnbounded(s *spec.Schema) bool {
+	switch {
+	case s.Type.Contains("array"):
+		return s.MaxItems == nil
+	case s.Type.Contains("object"):
+		return s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil && s.MaxProperties == nil
+	case s.Type.Contains("string"):
+		switch s.Format {
+		case "date", "date-time", "duration":
+			return false
+		}
+		return s.MaxLength == nil && len(s.Enum) == 0
+	}
+	return false
+}
+
+func saturatingAdd(a, b uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return math.MaxUint64
+	}
+	return sum
+}
+
+func saturatingMul(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	if hi != 0 {
+		return math.MaxUint64
+	}
+	return lo
+}
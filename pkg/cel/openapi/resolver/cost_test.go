@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// listOfStrings returns an object whose items property lists strings of the
+// given maximum length, at most maxItems of them, or unbounded if nil.
+func listOfStrings(maxItems, maxLength *int64) *spec.Schema {
+	item := scalarSchema("string", "")
+	item.MaxLength = maxLength
+	return objectSchema(map[string]spec.Schema{
+		"items": {SchemaProps: spec.SchemaProps{
+			Type:     []string{"array"},
+			MaxItems: maxItems,
+			Items:    &spec.SchemaOrArray{Schema: &item},
+		}},
+	})
+}
+
+func TestEstimateCELCost(t *testing.T) {
+	small, large, maxLength := int64(3), int64(10000), int64(64)
+
+	smallEstimate, err := EstimateCELCost(listOfStrings(&small, &maxLength))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	largeEstimate, err := EstimateCELCost(listOfStrings(&large, &maxLength))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	unboundedEstimate, err := EstimateCELCost(listOfStrings(nil, nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if smallEstimate.Cost >= largeEstimate.Cost {
+		t.Errorf("expected the cost with maxItems %d to be less than with maxItems %d, got %d and %d", small, large, smallEstimate.Cost, largeEstimate.Cost)
+	}
+	if largeEstimate.Cost >= unboundedEstimate.Cost {
+		t.Errorf("expected the cost with maxItems %d to be less than without maxItems, got %d and %d", large, largeEstimate.Cost, unboundedEstimate.Cost)
+	}
+	if cardinality := smallEstimate.MaxCardinality["items[*]"]; cardinality != uint64(small) {
+		t.Errorf("expected the items to have a cardinality of %d, got %d", small, cardinality)
+	}
+	// the visits of the root, the list and its items, and the traversal of
+	// the strings, whose size the CEL declaration bounds by 4 bytes per
+	// character.
+	if expected := uint64(1 + 1 + small + small*maxLength*4); smallEstimate.Cost != expected {
+		t.Errorf("expected cost %d, got %d", expected, smallEstimate.Cost)
+	}
+	if len(smallEstimate.Unbounded) != 0 {
+		t.Errorf("expected no unbounded fields, got %v", smallEstimate.Unbounded)
+	}
+	if expected := []string{"items", "items[*]"}; !reflect.DeepEqual(unboundedEstimate.Unbounded, expected) {
+		t.Errorf("expected unbounded fields %v, got %v", expected, unboundedEstimate.Unbounded)
+	}
+}
+
+func TestEstimateCELCostOfPod(t *testing.T) {
+	r := newEmbeddedDiscoveryResolver()
+	pod, err := r.ResolveSchema(workloadKinds[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	estimate, err := EstimateCELCost(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if estimate.MaxCardinality["spec.containers[*].ports[*]"] <= estimate.MaxCardinality["spec.containers[*]"] {
+		t.Errorf("expected nested lists to multiply the cardinality, got %d and %d", estimate.MaxCardinality["spec.containers[*].ports[*]"], estimate.MaxCardinality["spec.containers[*]"])
+	}
+}
+
+func TestEstimateCELCostNotExposed(t *testing.T) {
+	s := &spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"array"}}}
+	if _, err := EstimateCELCost(s); !errors.Is(err, ErrNotExposedToCEL) {
+		t.Errorf("expected ErrNotExposedToCEL, got %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// CRDSchemaResolver resolves the schemas of custom resources from the
+// openAPIV3Schema of their CustomResourceDefinitions, without a round trip to
+// discovery. The CRDs are unstructured, since this module cannot depend on
+// the apiextensions API; both apiextensions.k8s.io/v1 CRDs, which declare a
+// schema per version, and v1beta1 CRDs, which may declare a single schema for
+// all versions instead, are supported.
+type CRDSchemaResolver struct {
+	// CRDs lists the CustomResourceDefinitions, e.g. from a dynamic client or
+	// informer.
+	CRDs func() ([]*unstructured.Unstructured, error)
+}
+
+var _ SchemaResolver = (*CRDSchemaResolver)(nil)
+var _ GVKLister = (*CRDSchemaResolver)(nil)
+
+// NewCRDSchemaResolver creates a CRDSchemaResolver over a fixed set of CRDs.
+func NewCRDSchemaResolver(crds ...*unstructured.Unstructured) *CRDSchemaResolver {
+	return &CRDSchemaResolver{CRDs: func() ([]*unstructured.Unstructured, error) {
+		return crds, nil
+	}}
+}
+
+// ResolveSchema resolves the schema of the GVK from the CRD of its group and
+// kind. It returns an error wrapping ErrSchemaNotFound if there is no such
+// CRD, if the CRD does not serve the version, or if it declares no schema for
+// the version.
+func (r *CRDSchemaResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema unless ctx
+// is already done.
+func (r *CRDSchemaResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	if err := checkContext(ctx, gvk); err != nil {
+		return nil, err
+	}
+	crds, err := r.CRDs()
+	if err != nil {
+		return nil, wrapResolutionError(gvk, err)
+	}
+	for _, crd := range crds {
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+		if group == gvk.Group && kind == gvk.Kind {
+			s, err := schemaOfCRDVersion(crd, gvk)
+			if err != nil {
+				return nil, wrapResolutionError(gvk, err)
+			}
+			return s, nil
+		}
+	}
+	return nil, wrapResolutionError(gvk, fmt.Errorf("no CustomResourceDefinition for %q: %w", gvk.GroupKind(), ErrSchemaNotFound))
+}
+
+// GVKs returns the GVKs of the versions of the CRDs that declare a schema,
+// sorted.
+func (r *CRDSchemaResolver) GVKs() ([]schema.GroupVersionKind, error) {
+	crds, err := r.CRDs()
+	if err != nil {
+		return nil, err
+	}
+	result := sets.New[schema.GroupVersionKind]()
+	for _, crd := range crds {
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+		var names []string
+		versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+		for _, v := range versions {
+			version, _ := v.(map[string]interface{})
+			name, _, _ := unstructured.NestedString(version, "name")
+			names = append(names, name)
+		}
+		if version, _, _ := unstructured.NestedString(crd.Object, "spec", "version"); len(version) > 0 {
+			names = append(names, version)
+		}
+		for _, name := range names {
+			gvk := schema.GroupVersionKind{Group: group, Version: name, Kind: kind}
+			if _, err := schemaOfCRDVersion(crd, gvk); err == nil {
+				result.Insert(gvk)
+			}
+		}
+	}
+	gvks := result.UnsortedList()
+	sortGVKs(gvks)
+	return gvks, nil
+}
+
+// schemaOfCRDVersion returns the schema of the version of gvk declared by
+// crd, or the schema that crd declares for all versions, if any.
+func schemaOfCRDVersion(crd *unstructured.Unstructured, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	versions, _, err := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	if err != nil {
+		return nil, fmt.Errorf("invalid versions of CustomResourceDefinition %q: %w", crd.GetName(), err)
+	}
+	served := false
+	var openAPIV3Schema map[string]interface{}
+	for _, v := range versions {
+		version, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid versions of CustomResourceDefinition %q: expected an object, got %T", crd.GetName(), v)
+		}
+		if name, _, _ := unstructured.NestedString(version, "name"); name != gvk.Version {
+			continue
+		}
+		// a version is served unless it says otherwise, as with v1beta1 CRDs,
+		// which default served to true.
+		if isServed, ok, _ := unstructured.NestedBool(version, "served"); ok && !isServed {
+			return nil, fmt.Errorf("CustomResourceDefinition %q does not serve version %q: %w", crd.GetName(), gvk.Version, ErrSchemaNotFound)
+		}
+		served = true
+		if openAPIV3Schema, _, err = unstructured.NestedMap(version, "schema", "openAPIV3Schema"); err != nil {
+			return nil, fmt.Errorf("invalid schema of CustomResourceDefinition %q: %w", crd.GetName(), err)
+		}
+		break
+	}
+	// a v1beta1 CRD may declare a single version only, and a schema for all
+	// versions.
+	if version, _, _ := unstructured.NestedString(crd.Object, "spec", "version"); version == gvk.Version {
+		served = true
+	}
+	if !served {
+		return nil, fmt.Errorf("CustomResourceDefinition %q does not serve version %q: %w", crd.GetName(), gvk.Version, ErrSchemaNotFound)
+	}
+	if openAPIV3Schema == nil {
+		if openAPIV3Schema, _, err = unstructured.NestedMap(crd.Object, "spec", "validation", "openAPIV3Schema"); err != nil {
+			return nil, fmt.Errorf("invalid schema of CustomResourceDefinition %q: %w", crd.GetName(), err)
+		}
+	}
+	if openAPIV3Schema == nil {
+		return nil, fmt.Errorf("CustomResourceDefinition %q declares no schema for version %q: %w", crd.GetName(), gvk.Version, ErrSchemaNotFound)
+	}
+	b, err := json.Marshal(openAPIV3Schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema of CustomResourceDefinition %q: %w", crd.GetName(), err)
+	}
+	s := new(spec.Schema)
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("invalid schema of CustomResourceDefinition %q: %w", crd.GetName(), err)
+	}
+	return s, nil
+}
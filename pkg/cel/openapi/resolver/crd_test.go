@@ -0,0 +1,143 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newCRD(apiVersion, group, kind string, spec map[string]interface{}) *unstructured.Unstructured {
+	spec["group"] = group
+	spec["names"] = map[string]interface{}{"kind": kind}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": kind},
+		"spec":       spec,
+	}}
+}
+
+// openAPIV3Schema returns an object schema with a single string property.
+func openAPIV3Schema(property string) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			property: map[string]interface{}{"type": "string"},
+		},
+	}
+}
+
+func TestCRDSchemaResolver(t *testing.T) {
+	const group = "apps.clusternet.io"
+	r := NewCRDSchemaResolver(
+		newCRD("apiextensions.k8s.io/v1", group, "Subscription", map[string]interface{}{
+			"versions": []interface{}{
+				map[string]interface{}{
+					"name":   "v1alpha1",
+					"schema": map[string]interface{}{"openAPIV3Schema": openAPIV3Schema("subscribers")},
+				},
+				map[string]interface{}{"name": "v1alpha2"},
+				map[string]interface{}{
+					"name":   "v1alpha3",
+					"served": false,
+					"schema": map[string]interface{}{"openAPIV3Schema": openAPIV3Schema("subscribers")},
+				},
+				map[string]interface{}{
+					"name":   "v1beta1",
+					"served": true,
+					"schema": map[string]interface{}{"openAPIV3Schema": openAPIV3Schema("subscribers")},
+				},
+			},
+		}),
+		newCRD("apiextensions.k8s.io/v1beta1", group, "Manifest", map[string]interface{}{
+			"version":    "v1alpha1",
+			"versions":   []interface{}{map[string]interface{}{"name": "v1alpha1"}, map[string]interface{}{"name": "v1beta1"}},
+			"validation": map[string]interface{}{"openAPIV3Schema": openAPIV3Schema("template")},
+		}),
+	)
+
+	for _, tc := range []struct {
+		name     string
+		gvk      schema.GroupVersionKind
+		wantProp string
+		wantErr  error
+	}{
+		{name: "per-version schema", gvk: schema.GroupVersionKind{Group: group, Version: "v1alpha1", Kind: "Subscription"}, wantProp: "subscribers"},
+		{name: "version without schema", gvk: schema.GroupVersionKind{Group: group, Version: "v1alpha2", Kind: "Subscription"}, wantErr: ErrSchemaNotFound},
+		{name: "version not listed", gvk: schema.GroupVersionKind{Group: group, Version: "v1", Kind: "Subscription"}, wantErr: ErrSchemaNotFound},
+		{name: "version not served", gvk: schema.GroupVersionKind{Group: group, Version: "v1alpha3", Kind: "Subscription"}, wantErr: ErrSchemaNotFound},
+		{name: "served version", gvk: schema.GroupVersionKind{Group: group, Version: "v1beta1", Kind: "Subscription"}, wantProp: "subscribers"},
+		{name: "top-level schema", gvk: schema.GroupVersionKind{Group: group, Version: "v1alpha1", Kind: "Manifest"}, wantProp: "template"},
+		{name: "top-level schema of another version", gvk: schema.GroupVersionKind{Group: group, Version: "v1beta1", Kind: "Manifest"}, wantProp: "template"},
+		{name: "no CRD", gvk: schema.GroupVersionKind{Group: group, Version: "v1alpha1", Kind: "Base"}, wantErr: ErrSchemaNotFound},
+		{name: "other group", gvk: schema.GroupVersionKind{Group: "apps", Version: "v1alpha1", Kind: "Subscription"}, wantErr: ErrSchemaNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := r.ResolveSchema(tc.gvk)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if prop, ok := s.Properties[tc.wantProp]; !ok || !prop.Type.Contains("string") {
+				t.Errorf("expected property %q of type string, got %v", tc.wantProp, s)
+			}
+		})
+	}
+
+	// only the served versions that declare a schema are listed.
+	gvks, err := r.GVKs()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []schema.GroupVersionKind{
+		{Group: group, Version: "v1alpha1", Kind: "Manifest"},
+		{Group: group, Version: "v1alpha1", Kind: "Subscription"},
+		{Group: group, Version: "v1beta1", Kind: "Manifest"},
+		{Group: group, Version: "v1beta1", Kind: "Subscription"},
+	}
+	if !reflect.DeepEqual(gvks, expected) {
+		t.Errorf("expected %v, got %v", expected, gvks)
+	}
+}
+
+func TestCRDSchemaResolverErrors(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	errList := errors.New("list failed")
+	r := &CRDSchemaResolver{CRDs: func() ([]*unstructured.Unstructured, error) {
+		return nil, errList
+	}}
+	if _, err := r.ResolveSchema(gvk); !errors.Is(err, errList) {
+		t.Errorf("expected the error of the lister, got %v", err)
+	}
+
+	r = NewCRDSchemaResolver(newCRD("apiextensions.k8s.io/v1", gvk.Group, gvk.Kind, map[string]interface{}{
+		"versions": []interface{}{map[string]interface{}{
+			"name":   gvk.Version,
+			"schema": map[string]interface{}{"openAPIV3Schema": map[string]interface{}{"type": int64(1)}},
+		}},
+	}))
+	if _, err := r.ResolveSchema(gvk); err == nil || errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected an error for the malformed schema, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"fmt"
+
+	apiservercel "k8s.io/apiserver/pkg/cel"
+	"k8s.io/apiserver/pkg/cel/common"
+	"k8s.io/apiserver/pkg/cel/openapi"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// SchemaToDeclType converts a resolved schema of a resource into its CEL
+// declaration, e.g. to declare the variables of a CEL environment that
+// type-checks rules against the GVK the schema was resolved for, after
+// naming it with MaybeAssignTypeName.
+// The declaration is the same that the apiserver compiles rules against:
+// apiVersion, kind, metadata.name and metadata.generateName are declared at
+// the root, formats such as date-time and duration map to the CEL timestamp
+// and duration types, and x-kubernetes-int-or-string maps to dyn. Fields that
+// are only permitted by x-kubernetes-preserve-unknown-fields are not
+// declared, and neither are fields whose schema has no CEL declaration, e.g.
+// an untyped one. It fails with ErrNotExposedToCEL if the root itself has
+// no CEL declaration.
+func SchemaToDeclType(s *spec.Schema) (*apiservercel.DeclType, error) {
+	if s == nil {
+		return nil, fmt.Errorf("nil schema: %w", ErrNotExposedToCEL)
+	}
+	declType := common.SchemaDeclType(&openapi.Schema{Schema: s}, true)
+	if declType == nil {
+		return nil, ErrNotExposedToCEL
+	}
+	return declType, nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/version"
+	apiservercel "k8s.io/apiserver/pkg/cel"
+	"k8s.io/apiserver/pkg/cel/environment"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestSchemaToDeclType(t *testing.T) {
+	intOrString := spec.Schema{}
+	intOrString.AddExtension(extIntOrString, true)
+	preserved := *objectSchema(nil)
+	preserved.AddExtension(extPreserveUnknownFields, true)
+	s := objectSchema(map[string]spec.Schema{
+		"spec": *objectSchema(map[string]spec.Schema{
+			"replicas":    scalarSchema("integer", "int32"),
+			"maxSurge":    intOrString,
+			"timeout":     scalarSchema("string", "duration"),
+			"paused":      scalarSchema("boolean", ""),
+			"certificate": scalarSchema("string", "byte"),
+			"config":      preserved,
+			"untyped":     {},
+		}),
+		"status": *objectSchema(map[string]spec.Schema{
+			"startTime": scalarSchema("string", "date-time"),
+		}),
+	})
+
+	declType, err := SchemaToDeclType(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"apiVersion", "kind", "metadata"} {
+		if _, ok := declType.Fields[name]; !ok {
+			t.Errorf("expected %s to be declared at the root", name)
+		}
+	}
+	spec := declType.Fields["spec"].Type
+	status := declType.Fields["status"].Type
+	for _, tc := range []struct {
+		name     string
+		declType *apiservercel.DeclType
+		expected *cel.Type
+	}{
+		{name: "integer", declType: spec.Fields["replicas"].Type, expected: cel.IntType},
+		{name: "int-or-string", declType: spec.Fields["maxSurge"].Type, expected: cel.DynType},
+		{name: "duration", declType: spec.Fields["timeout"].Type, expected: cel.DurationType},
+		{name: "boolean", declType: spec.Fields["paused"].Type, expected: cel.BoolType},
+		{name: "byte", declType: spec.Fields["certificate"].Type, expected: cel.BytesType},
+		{name: "date-time", declType: status.Fields["startTime"].Type, expected: cel.TimestampType},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.declType.CelType(); !got.IsExactType(tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+	if config := spec.Fields["config"].Type; !config.IsObject() || len(config.Fields) != 0 {
+		t.Errorf("expected the fields preserved as unknown not to be declared, got %v", config)
+	}
+	if _, ok := spec.Fields["untyped"]; ok {
+		t.Errorf("expected the untyped field not to be declared")
+	}
+}
+
+func TestSchemaToDeclTypeResolved(t *testing.T) {
+	s, err := newEmbeddedDiscoveryResolver().ResolveSchema(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	declType, err := SchemaToDeclType(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	declType = declType.MaybeAssignTypeName("Deployment")
+	envSet, err := environment.MustBaseEnvSet(environment.DefaultCompatibilityVersion(), true).Extend(
+		environment.VersionedOptions{
+			IntroducedVersion: version.MajorMinor(1, 0),
+			EnvOptions:        []cel.EnvOption{cel.Variable("object", declType.CelType())},
+			DeclTypes:         []*apiservercel.DeclType{declType},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	env, err := envSet.Env(environment.NewExpressions)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, tc := range []struct {
+		expression string
+		wantErr    bool
+	}{
+		{expression: "object.spec.replicas > 1"},
+		{expression: "object.metadata.name.startsWith('web-')"},
+		{expression: "object.spec.template.spec.containers.all(c, c.image != '')"},
+		{expression: "object.spec.replicas == 'one'", wantErr: true},
+		{expression: "object.spec.missing == 1", wantErr: true},
+	} {
+		t.Run(tc.expression, func(t *testing.T) {
+			_, issues := env.Compile(tc.expression)
+			if tc.wantErr != (issues.Err() != nil) {
+				t.Errorf("expected error %v, got %v", tc.wantErr, issues.Err())
+			}
+		})
+	}
+}
+
+func TestSchemaToDeclTypeNotExposed(t *testing.T) {
+	for _, s := range []*spec.Schema{nil, {SchemaProps: spec.SchemaProps{Type: []string{"array"}}}} {
+		if _, err := SchemaToDeclType(s); !errors.Is(err, ErrNotExposedToCEL) {
+			t.Errorf("expected ErrNotExposedToCEL, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// DefaultedFields returns the paths of all fields of a resolved schema that
+// declare a default, mapped to their default values. Paths follow the
+// notation of WalkSchema.
+// CEL treats a defaulted field as always present, so callers may use the
+// result to decide which fields are optional.
+func DefaultedFields(s *spec.Schema) map[string]interface{} {
+	result := make(map[string]interface{})
+	_ = WalkSchema(s, func(path string, node *spec.Schema) error {
+		if node.Default != nil {
+			result[path] = node.Default
+		}
+		return nil
+	})
+	return result
+}
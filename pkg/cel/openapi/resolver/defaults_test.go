@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestDefaultedFields(t *testing.T) {
+	getDefinitions := func(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		restartPolicy := scalarSchema("string", "")
+		restartPolicy.Default = "Always"
+		dnsConfig := spec.Schema{SchemaProps: spec.SchemaProps{
+			AllOf:   []spec.Schema{{SchemaProps: spec.SchemaProps{Ref: ref("k8s.io/api/core/v1.PodDNSConfig")}}},
+			Default: map[string]interface{}{"options": []interface{}{}},
+		}}
+		return map[string]common.OpenAPIDefinition{
+			"k8s.io/api/core/v1.Pod": {Schema: *objectSchema(map[string]spec.Schema{
+				"spec": {SchemaProps: spec.SchemaProps{Ref: ref("k8s.io/api/core/v1.PodSpec")}},
+			})},
+			"k8s.io/api/core/v1.PodSpec": {Schema: *objectSchema(map[string]spec.Schema{
+				"restartPolicy": restartPolicy,
+				"dnsConfig":     dnsConfig,
+			})},
+			"k8s.io/api/core/v1.PodDNSConfig": {Schema: *objectSchema(map[string]spec.Schema{
+				"nameservers": {SchemaProps: spec.SchemaProps{
+					Type:  []string{"array"},
+					Items: &spec.SchemaOrArray{Schema: &spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"string"}}}},
+				}},
+			})},
+		}
+	}
+	r := NewDefinitionsSchemaResolver(getDefinitions, scheme.Scheme)
+	s, err := r.ResolveSchema(schema.GroupVersionKind{Version: "v1", Kind: "Pod"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the default must survive inlining of the referred schema.
+	if _, ok := s.Properties["spec"].Properties["dnsConfig"].Properties["nameservers"]; !ok {
+		t.Fatalf("expected the Ref of dnsConfig to be populated, got %v", s.Properties["spec"])
+	}
+	expected := map[string]interface{}{
+		"spec.restartPolicy": "Always",
+		"spec.dnsConfig":     map[string]interface{}{"options": []interface{}{}},
+	}
+	if got := DefaultedFields(s); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
@@ -17,11 +17,16 @@ limitations under the License.
 package resolver
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apiserver/pkg/endpoints/openapi"
+	"k8s.io/klog/v2"
 	"k8s.io/kube-openapi/pkg/common"
 	"k8s.io/kube-openapi/pkg/validation/spec"
 )
@@ -29,39 +34,217 @@ import (
 // DefinitionsSchemaResolver resolves the schema of a built-in type
 // by looking up the OpenAPI definitions.
 type DefinitionsSchemaResolver struct {
+	// RefOptions configures how Refs of the definitions are populated, e.g.
+	// to keep the circular Refs of recursive definitions unresolved.
+	RefOptions PopulateRefsOptions
+
 	defs     map[string]common.OpenAPIDefinition
 	gvkToRef map[schema.GroupVersionKind]string
+	// extensionErrs joins the errors of the definitions whose GVK extension
+	// is malformed.
+	extensionErrs error
+	// refs shares the populated Ref sites of the definitions across the
+	// resolutions of ResolveSchemaShared.
+	refs *refCache
 }
 
+// ErrMalformedGVKExtension is wrapped and returned if the
+// x-kubernetes-group-version-kind extension of a definition does not have
+// the shape of a list of objects with string group, version and kind.
+var ErrMalformedGVKExtension = errors.New("malformed x-kubernetes-group-version-kind extension")
+
+var _ DiagnosticResolver = (*DefinitionsSchemaResolver)(nil)
+
 // NewDefinitionsSchemaResolver creates a new DefinitionsSchemaResolver.
+// The definitions are mapped to the GVKs of their Go types in all schemes,
+// e.g. a primary scheme of the built-in types and a secondary one of custom
+// aggregated types. Definitions whose types no scheme knows are skipped.
 // An example working setup:
 // getDefinitions = "k8s.io/kubernetes/pkg/generated/openapi".GetOpenAPIDefinitions
 // scheme         = "k8s.io/client-go/kubernetes/scheme".Scheme
 func NewDefinitionsSchemaResolver(getDefinitions common.GetOpenAPIDefinitions, schemes ...*runtime.Scheme) *DefinitionsSchemaResolver {
+	return NewDefinitionsSchemaResolverWithOverrides(getDefinitions, nil, schemes...)
+}
+
+// NewDefinitionsSchemaResolverWithOverrides creates a new
+// DefinitionsSchemaResolver like NewDefinitionsSchemaResolver, with explicit
+// mappings of definition names to GVKs, e.g. for types reused across groups
+// whose extensions are incomplete. The overrides take precedence over the
+// GVKs produced by the definition namer and the extensions: an overridden
+// definition is mapped to its override only, and an override claims its GVK
+// even if another definition maps to it.
+func NewDefinitionsSchemaResolverWithOverrides(getDefinitions common.GetOpenAPIDefinitions, overrides map[string]schema.GroupVersionKind, schemes ...*runtime.Scheme) *DefinitionsSchemaResolver {
 	gvkToRef := make(map[schema.GroupVersionKind]string)
 	namer := openapi.NewDefinitionNamer(schemes...)
 	defs := getDefinitions(func(path string) spec.Ref {
 		return spec.MustCreateRef(path)
 	})
+	var errs []error
 	for name := range defs {
+		if _, ok := overrides[name]; ok {
+			continue
+		}
 		_, e := namer.GetDefinitionName(name)
-		gvks := extensionsToGVKs(e)
+		gvks, err := extensionsToGVKs(e)
+		if err != nil {
+			klog.ErrorS(err, "Skipping definition with malformed GVK extension", "definition", name)
+			errs = append(errs, fmt.Errorf("definition %q: %w", name, err))
+			continue
+		}
 		for _, gvk := range gvks {
 			gvkToRef[gvk] = name
 		}
 	}
+	for name, gvk := range overrides {
+		gvkToRef[gvk] = name
+	}
 	return &DefinitionsSchemaResolver{
-		gvkToRef: gvkToRef,
-		defs:     defs,
+		gvkToRef:      gvkToRef,
+		defs:          defs,
+		extensionErrs: errors.Join(errs...),
+		refs:          &refCache{},
 	}
 }
 
+// ExtensionErrors returns the errors of the definitions that were skipped
+// because their x-kubernetes-group-version-kind extension is malformed, each
+// naming the definition and the offending field and wrapping
+// ErrMalformedGVKExtension, or nil if there are none. It tells such a
+// definition apart from a type that is absent when ResolveSchema returns
+// ErrSchemaNotFound.
+func (d *DefinitionsSchemaResolver) ExtensionErrors() error {
+	return d.extensionErrs
+}
+
 func (d *DefinitionsSchemaResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return d.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema unless ctx
+// is already done. The definitions are compiled in, so the resolution itself
+// does not block.
+func (d *DefinitionsSchemaResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	if err := checkContext(ctx, gvk); err != nil {
+		return nil, err
+	}
+	ref, ok := d.gvkToRef[gvk]
+	if !ok {
+		return nil, wrapResolutionError(gvk, ErrSchemaNotFound)
+	}
+	s, err := d.resolveDefinition(ref, nil, false)
+	if err != nil {
+		return nil, wrapResolutionError(gvk, err)
+	}
+	return s, nil
+}
+
+// ResolveSchemaShared resolves the schema like ResolveSchema, sharing the
+// populated Refs with the schemas of the other GVKs, and of other calls, that
+// refer to the same definitions from the same site, e.g. their ObjectMeta.
+// Each definition is then populated once, which saves most of the
+// allocations of resolving many kinds, but the result must not be mutated:
+// mutating it affects the other schemas and later resolutions. Use
+// ResolveSchemaImmutable for a schema that may be mutated.
+func (d *DefinitionsSchemaResolver) ResolveSchemaShared(gvk schema.GroupVersionKind) (*spec.Schema, error) {
 	ref, ok := d.gvkToRef[gvk]
 	if !ok {
-		return nil, fmt.Errorf("cannot resolve %v: %w", gvk, ErrSchemaNotFound)
+		return nil, wrapResolutionError(gvk, ErrSchemaNotFound)
+	}
+	s, err := d.resolveDefinition(ref, nil, true)
+	if err != nil {
+		return nil, wrapResolutionError(gvk, err)
+	}
+	return s, nil
+}
+
+// ResolveSchemaImmutable resolves the schema like ResolveSchema into a freshly
+// allocated tree. The schemas returned by ResolveSchema share the subtrees
+// that hold no Refs with the definitions, so mutating them affects later
+// resolutions. The result of ResolveSchemaImmutable shares no
+// memory with the definitions and may be mutated freely, at the cost of a
+// deep copy.
+func (d *DefinitionsSchemaResolver) ResolveSchemaImmutable(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	// the tree is copied anyway, so it may as well be shared until then.
+	s, err := d.ResolveSchemaShared(gvk)
+	if err != nil {
+		return nil, err
 	}
-	s, err := PopulateRefs(func(ref string) (*spec.Schema, bool) {
+	s, err = deepCopy(s)
+	if err != nil {
+		return nil, wrapResolutionError(gvk, err)
+	}
+	return s, nil
+}
+
+// ResolveSchemaWithDiagnostics resolves the schema like ResolveSchema and also
+// returns diagnostics of the resolution.
+func (d *DefinitionsSchemaResolver) ResolveSchemaWithDiagnostics(gvk schema.GroupVersionKind) (*spec.Schema, ResolveDiagnostics, error) {
+	start := time.Now()
+	diag := ResolveDiagnostics{Source: DiagnosticSourceDefinitions}
+	ref, ok := d.gvkToRef[gvk]
+	if !ok {
+		return nil, ResolveDiagnostics{}, wrapResolutionError(gvk, ErrSchemaNotFound)
+	}
+	s, err := d.resolveDefinition(ref, &diag.RefExpansions, false)
+	if err != nil {
+		return nil, ResolveDiagnostics{}, wrapResolutionError(gvk, err)
+	}
+	diag.Nodes = countNodes(s)
+	diag.Duration = time.Since(start)
+	return s, diag, nil
+}
+
+// ResolveSchemaForType resolves the schema of the definition of the given Go
+// type, without the need of knowing its GVK. Pointer, slice, array and map
+// types are unwrapped to their element type, so that e.g. the types of
+// &corev1.Pod{} and []corev1.Pod{} both resolve to the schema of corev1.Pod.
+// The errors of a definition that is mapped to a GVK are prefixed with the
+// GVK like those of ResolveSchema; the other errors name the type.
+func (d *DefinitionsSchemaResolver) ResolveSchemaForType(t reflect.Type) (*spec.Schema, error) {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	// definitions are named the same way as the definition namer does.
+	name := t.PkgPath() + "." + t.Name()
+	if _, ok := d.defs[name]; !ok {
+		return nil, fmt.Errorf("cannot resolve type %v: %w", t, ErrSchemaNotFound)
+	}
+	s, err := d.resolveDefinition(name, nil, false)
+	if err == nil {
+		return s, nil
+	}
+	if gvk, ok := d.gvkOfDefinition(name); ok {
+		return nil, wrapResolutionError(gvk, err)
+	}
+	return nil, fmt.Errorf("cannot resolve type %v: %w", t, err)
+}
+
+// gvkOfDefinition returns the first of the sorted GVKs that are mapped to the
+// definition name, if any.
+func (d *DefinitionsSchemaResolver) gvkOfDefinition(name string) (schema.GroupVersionKind, bool) {
+	var gvks []schema.GroupVersionKind
+	for gvk, ref := range d.gvkToRef {
+		if ref == name {
+			gvks = append(gvks, gvk)
+		}
+	}
+	if len(gvks) == 0 {
+		return schema.GroupVersionKind{}, false
+	}
+	sortGVKs(gvks)
+	return gvks[0], true
+}
+
+// resolveDefinition resolves the definition of ref and counts the expanded
+// Refs in expansions, if not nil. If share is set, the populated Ref sites
+// are shared through the cache, unless the expanded Refs are counted, which
+// a shared site would not be.
+func (d *DefinitionsSchemaResolver) resolveDefinition(ref string, expansions *int, share bool) (*spec.Schema, error) {
+	var cache *refCache
+	if share && expansions == nil && d.refs != nil && d.RefOptions.cacheable() {
+		cache = d.refs.forOptions(d.RefOptions)
+	}
+	s, err := populateRefsWithCache(countingSchemaOf(func(ref string) (*spec.Schema, bool) {
 		// find the schema by the ref string, and return a deep copy
 		def, ok := d.defs[ref]
 		if !ok {
@@ -69,46 +252,45 @@ func (d *DefinitionsSchemaResolver) ResolveSchema(gvk schema.GroupVersionKind) (
 		}
 		s := def.Schema
 		return &s, true
-	}, ref)
+	}, expansions), ref, d.RefOptions, cache)
 	if err != nil {
 		return nil, err
 	}
 	return s, nil
 }
 
-func extensionsToGVKs(extensions spec.Extensions) []schema.GroupVersionKind {
+// extensionsToGVKs returns the GVKs of the x-kubernetes-group-version-kind
+// extension, if any. It returns an error wrapping ErrMalformedGVKExtension
+// that names the offending field if the extension has an unexpected shape.
+func extensionsToGVKs(extensions spec.Extensions) ([]schema.GroupVersionKind, error) {
 	gvksAny, ok := extensions[extGVK]
 	if !ok {
-		return nil
+		return nil, nil
 	}
 	gvks, ok := gvksAny.([]any)
 	if !ok {
-		return nil
+		return nil, fmt.Errorf("%s: expected a list, got %T: %w", extGVK, gvksAny, ErrMalformedGVKExtension)
 	}
 	result := make([]schema.GroupVersionKind, 0, len(gvks))
-	for _, gvkAny := range gvks {
+	for i, gvkAny := range gvks {
 		// type check the map and all fields
 		gvkMap, ok := gvkAny.(map[string]any)
 		if !ok {
-			return nil
-		}
-		g, ok := gvkMap["group"].(string)
-		if !ok {
-			return nil
+			return nil, fmt.Errorf("%s[%d]: expected an object, got %T: %w", extGVK, i, gvkAny, ErrMalformedGVKExtension)
 		}
-		v, ok := gvkMap["version"].(string)
-		if !ok {
-			return nil
-		}
-		k, ok := gvkMap["kind"].(string)
-		if !ok {
-			return nil
+		var fields [3]string
+		for j, field := range []string{"group", "version", "kind"} {
+			value, ok := gvkMap[field].(string)
+			if !ok {
+				return nil, fmt.Errorf("%s[%d].%s: expected a string, got %T: %w", extGVK, i, field, gvkMap[field], ErrMalformedGVKExtension)
+			}
+			fields[j] = value
 		}
 		result = append(result, schema.GroupVersionKind{
-			Group:   g,
-			Version: v,
-			Kind:    k,
+			Group:   fields[0],
+			Version: fields[1],
+			Kind:    fields[2],
 		})
 	}
-	return result
+	return result, nil
 }
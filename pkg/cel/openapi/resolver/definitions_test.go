@@ -0,0 +1,569 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/openapi/openapitest"
+	"k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestResolveSchemaForType(t *testing.T) {
+	getDefinitions := func(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		return map[string]common.OpenAPIDefinition{
+			"k8s.io/api/core/v1.Pod": {Schema: *objectSchema(map[string]spec.Schema{
+				"spec": {SchemaProps: spec.SchemaProps{Ref: ref("k8s.io/api/core/v1.PodSpec")}},
+			})},
+			"k8s.io/api/core/v1.PodSpec": {Schema: *objectSchema(map[string]spec.Schema{
+				"nodeName": scalarSchema("string", ""),
+			})},
+			"k8s.io/api/core/v1.ConfigMap": {Schema: *objectSchema(map[string]spec.Schema{
+				"data": {SchemaProps: spec.SchemaProps{Ref: ref("k8s.io/api/core/v1.Missing")}},
+			})},
+		}
+	}
+	r := NewDefinitionsSchemaResolver(getDefinitions, scheme.Scheme)
+
+	for _, tc := range []struct {
+		name       string
+		t          reflect.Type
+		wantErr    error
+		wantPrefix string
+	}{
+		{name: "pointer", t: reflect.TypeOf(&corev1.Pod{})},
+		{name: "value", t: reflect.TypeOf(corev1.Pod{})},
+		{name: "slice", t: reflect.TypeOf([]*corev1.Pod{})},
+		{name: "unknown", t: reflect.TypeOf(&corev1.Service{}), wantErr: ErrSchemaNotFound, wantPrefix: "cannot resolve type v1.Service: "},
+		{name: "dangling ref", t: reflect.TypeOf(corev1.ConfigMap{}), wantErr: ErrSchemaNotFound, wantPrefix: "cannot resolve /v1, Kind=ConfigMap: "},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := r.ResolveSchemaForType(tc.t)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				if !strings.HasPrefix(err.Error(), tc.wantPrefix) {
+					t.Errorf("expected the error to be prefixed with %q, got %q", tc.wantPrefix, err.Error())
+				}
+				return
+			}
+			if _, ok := s.Properties["spec"].Properties["nodeName"]; !ok {
+				t.Errorf("expected the schema of Pod with its refs populated, got %v", s)
+			}
+		})
+	}
+}
+
+func TestDefinitionsSchemaResolverOverrides(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	subscription := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	getDefinitions := func(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		return map[string]common.OpenAPIDefinition{
+			"k8s.io/api/core/v1.Pod": {Schema: *objectSchema(map[string]spec.Schema{
+				"spec": scalarSchema("string", ""),
+			})},
+			"github.com/clusternet/clusternet/pkg/apis/apps/v1alpha1.subscription": {Schema: *objectSchema(map[string]spec.Schema{
+				"subscribers": scalarSchema("string", ""),
+			})},
+		}
+	}
+
+	for _, tc := range []struct {
+		name      string
+		overrides map[string]schema.GroupVersionKind
+		gvk       schema.GroupVersionKind
+		wantErr   error
+		wantProp  string
+	}{
+		{name: "namer", gvk: pod, wantProp: "spec"},
+		{name: "not produced by the namer", gvk: subscription, wantErr: ErrSchemaNotFound},
+		{
+			name:      "override",
+			overrides: map[string]schema.GroupVersionKind{"github.com/clusternet/clusternet/pkg/apis/apps/v1alpha1.subscription": subscription},
+			gvk:       subscription,
+			wantProp:  "subscribers",
+		},
+		{
+			name:      "override takes precedence over the namer",
+			overrides: map[string]schema.GroupVersionKind{"github.com/clusternet/clusternet/pkg/apis/apps/v1alpha1.subscription": pod},
+			gvk:       pod,
+			wantProp:  "subscribers",
+		},
+		{
+			name:      "overridden definition loses the GVK of the namer",
+			overrides: map[string]schema.GroupVersionKind{"k8s.io/api/core/v1.Pod": subscription},
+			gvk:       pod,
+			wantErr:   ErrSchemaNotFound,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewDefinitionsSchemaResolverWithOverrides(getDefinitions, tc.overrides, scheme.Scheme)
+			s, err := r.ResolveSchema(tc.gvk)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if _, ok := s.Properties[tc.wantProp]; !ok {
+				t.Errorf("expected property %q, got %v", tc.wantProp, s)
+			}
+		})
+	}
+}
+
+func TestDefinitionsSchemaResolverMultipleSchemes(t *testing.T) {
+	configMap := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	subscription := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	// the secondary scheme registers a custom aggregated type that reuses an
+	// existing Go type for its definition name.
+	secondary := runtime.NewScheme()
+	secondary.AddKnownTypeWithName(subscription, &corev1.ConfigMap{})
+	getDefinitions := func(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		return map[string]common.OpenAPIDefinition{
+			"k8s.io/api/core/v1.ConfigMap": {Schema: *objectSchema(map[string]spec.Schema{
+				"data": scalarSchema("string", ""),
+			})},
+			// not known to any scheme.
+			"github.com/clusternet/clusternet/pkg/apis/apps/v1alpha1.Unknown": {Schema: *objectSchema(nil)},
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		schemes  []*runtime.Scheme
+		wantGVKs []schema.GroupVersionKind
+	}{
+		{name: "primary scheme", schemes: []*runtime.Scheme{scheme.Scheme}, wantGVKs: []schema.GroupVersionKind{configMap}},
+		{name: "secondary scheme", schemes: []*runtime.Scheme{secondary}, wantGVKs: []schema.GroupVersionKind{subscription}},
+		{name: "both schemes", schemes: []*runtime.Scheme{scheme.Scheme, secondary}, wantGVKs: []schema.GroupVersionKind{configMap, subscription}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewDefinitionsSchemaResolver(getDefinitions, tc.schemes...)
+			gvks, err := r.GVKs()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(gvks, tc.wantGVKs) {
+				t.Errorf("expected GVKs %v, got %v", tc.wantGVKs, gvks)
+			}
+			for _, gvk := range tc.wantGVKs {
+				s, err := r.ResolveSchema(gvk)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if _, ok := s.Properties["data"]; !ok {
+					t.Errorf("expected the schema of ConfigMap for %v, got %v", gvk, s)
+				}
+			}
+		})
+	}
+}
+
+func TestDefinitionsSchemaResolverCircularRefs(t *testing.T) {
+	const (
+		crdName   = "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1.CustomResourceDefinition"
+		propsName = "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1.JSONSchemaProps"
+	)
+	crd := schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}
+	// JSONSchemaProps refers to itself through its properties, items, and
+	// additionalProperties, both directly and through its wrapper types.
+	getDefinitions := func(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		propsRef := spec.Schema{SchemaProps: spec.SchemaProps{Ref: ref(propsName)}}
+		return map[string]common.OpenAPIDefinition{
+			crdName: {Schema: *objectSchema(map[string]spec.Schema{
+				"spec": *objectSchema(map[string]spec.Schema{
+					"versions": {SchemaProps: spec.SchemaProps{
+						Type: []string{"array"},
+						Items: &spec.SchemaOrArray{Schema: objectSchema(map[string]spec.Schema{
+							"schema": *objectSchema(map[string]spec.Schema{
+								"openAPIV3Schema": {SchemaProps: spec.SchemaProps{AllOf: []spec.Schema{propsRef}}},
+							}),
+						})},
+					}},
+				}),
+			})},
+			propsName: {Schema: *objectSchema(map[string]spec.Schema{
+				"type": scalarSchema("string", ""),
+				"properties": {SchemaProps: spec.SchemaProps{
+					Type:                 []string{"object"},
+					AdditionalProperties: &spec.SchemaOrBool{Allows: true, Schema: &propsRef},
+				}},
+				"items": {SchemaProps: spec.SchemaProps{Ref: ref(propsName + "OrArray")}},
+				"allOf": {SchemaProps: spec.SchemaProps{
+					Type:  []string{"array"},
+					Items: &spec.SchemaOrArray{Schema: &propsRef},
+				}},
+				"not": propsRef,
+			})},
+			propsName + "OrArray": {Schema: *objectSchema(map[string]spec.Schema{
+				"schema": propsRef,
+			})},
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts PopulateRefsOptions
+		// wantRef is whether the circular Refs are expected to be kept.
+		wantRef bool
+	}{
+		{name: "placeholder"},
+		{name: "keep circular refs", opts: PopulateRefsOptions{KeepCircularRefs: true}, wantRef: true},
+		{name: "keep circular refs with shared items", opts: PopulateRefsOptions{KeepCircularRefs: true, ShareArrayItems: true}, wantRef: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewDefinitionsSchemaResolverWithOverrides(getDefinitions, map[string]schema.GroupVersionKind{crdName: crd})
+			r.RefOptions = tc.opts
+			done := make(chan struct{})
+			var s *spec.Schema
+			var err error
+			go func() {
+				defer close(done)
+				s, err = r.ResolveSchema(crd)
+			}()
+			select {
+			case <-done:
+			case <-time.After(wait.ForeverTestTimeout):
+				t.Fatalf("expected the resolution of %v to terminate", crd)
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			props := s.Properties["spec"].Properties["versions"].Items.Schema.Properties["schema"].Properties["openAPIV3Schema"]
+			if _, ok := props.Properties["type"]; !ok {
+				t.Fatalf("expected the first JSONSchemaProps to be resolved, got %v", props)
+			}
+			for name, circular := range map[string]spec.Schema{
+				"properties": *props.Properties["properties"].AdditionalProperties.Schema,
+				"items":      props.Properties["items"].Properties["schema"],
+				"allOf":      *props.Properties["allOf"].Items.Schema,
+				"not":        props.Properties["not"],
+			} {
+				ref, isRef := refOf(&circular)
+				if tc.wantRef && (!isRef || ref != propsName) {
+					t.Errorf("expected %s to keep the circular Ref %q, got %v", name, propsName, circular)
+				}
+				if !tc.wantRef && (isRef || len(circular.Properties) != 0) {
+					t.Errorf("expected %s to be a placeholder, got %v", name, circular)
+				}
+			}
+			if _, err := deepCopy(s); err != nil {
+				t.Errorf("unexpected error copying the schema: %v", err)
+			}
+		})
+	}
+}
+
+func TestResolveSchemaImmutable(t *testing.T) {
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	daemonSet := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "DaemonSet"}
+	// both GVKs refer to the template, which holds no Refs itself.
+	getDefinitions := func(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		templateRef := spec.Schema{SchemaProps: spec.SchemaProps{Ref: ref("k8s.io/api/core/v1.PodTemplateSpec")}}
+		return map[string]common.OpenAPIDefinition{
+			"k8s.io/api/apps/v1.Deployment": {Schema: *objectSchema(map[string]spec.Schema{
+				"template": templateRef,
+			})},
+			"k8s.io/api/apps/v1.DaemonSet": {Schema: *objectSchema(map[string]spec.Schema{
+				"template": templateRef,
+			})},
+			"k8s.io/api/core/v1.PodTemplateSpec": {Schema: *objectSchema(map[string]spec.Schema{
+				"nodeName": scalarSchema("string", ""),
+			}, "nodeName")},
+		}
+	}
+	r := NewDefinitionsSchemaResolver(getDefinitions, scheme.Scheme)
+	expected, err := r.ResolveSchema(daemonSet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected, err = deepCopy(expected)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s, err := r.ResolveSchemaImmutable(deployment)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	template := s.Properties["template"]
+	template.Properties["nodeName"] = scalarSchema("integer", "")
+	template.Properties["hostname"] = scalarSchema("string", "")
+	template.Required[0] = "hostname"
+	s.Properties["template"] = template
+
+	for _, gvk := range []schema.GroupVersionKind{daemonSet, deployment} {
+		got, err := r.ResolveSchema(gvk)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got.Properties["template"], expected.Properties["template"]) {
+			t.Errorf("expected the schema of %v not to be affected by mutating an immutable result, got %v", gvk, got.Properties["template"])
+		}
+	}
+}
+
+func TestExtensionsToGVKs(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	for _, tc := range []struct {
+		name       string
+		extensions spec.Extensions
+		expected   []schema.GroupVersionKind
+		// wantField is the offending field named by the error, if any.
+		wantField string
+	}{
+		{name: "absent"},
+		{
+			name:       "well-formed",
+			extensions: withGVK(spec.Schema{}, pod).Extensions,
+			expected:   []schema.GroupVersionKind{pod},
+		},
+		{
+			name:       "not a list",
+			extensions: spec.Extensions{extGVK: map[string]any{"version": "v1", "kind": "Pod"}},
+			wantField:  extGVK + ":",
+		},
+		{
+			name:       "not an object",
+			extensions: spec.Extensions{extGVK: []any{"v1/Pod"}},
+			wantField:  extGVK + "[0]:",
+		},
+		{
+			name: "kind is not a string",
+			extensions: spec.Extensions{extGVK: []any{
+				map[string]any{"group": "", "version": "v1", "kind": "Pod"},
+				map[string]any{"group": "", "version": "v1", "kind": 1},
+			}},
+			wantField: extGVK + "[1].kind:",
+		},
+		{
+			name:       "missing version",
+			extensions: spec.Extensions{extGVK: []any{map[string]any{"group": "", "kind": "Pod"}}},
+			wantField:  extGVK + "[0].version:",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gvks, err := extensionsToGVKs(tc.extensions)
+			if len(tc.wantField) > 0 {
+				if !errors.Is(err, ErrMalformedGVKExtension) || !strings.HasPrefix(err.Error(), tc.wantField) {
+					t.Errorf("expected ErrMalformedGVKExtension for %q, got %v", tc.wantField, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(gvks, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, gvks)
+			}
+		})
+	}
+}
+
+func TestDefinitionsSchemaResolverExtensionErrors(t *testing.T) {
+	getDefinitions := func(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		return map[string]common.OpenAPIDefinition{
+			"k8s.io/api/core/v1.Pod": {Schema: *objectSchema(nil)},
+		}
+	}
+	r := NewDefinitionsSchemaResolver(getDefinitions, scheme.Scheme)
+	if err := r.ExtensionErrors(); err != nil {
+		t.Errorf("expected no errors for the extensions of the definition namer, got %v", err)
+	}
+}
+
+// embeddedDefinitions returns the OpenAPI definitions of the embedded OpenAPI
+// v3 document of the group-version at path, named and referred to by their
+// Go types as the generated definitions are.
+func embeddedDefinitions(tb testing.TB, path string) common.GetOpenAPIDefinitions {
+	paths, err := openapitest.NewEmbeddedFileClient().Paths()
+	if err != nil {
+		tb.Fatalf("unexpected error: %v", err)
+	}
+	b, err := paths[path].Schema(runtime.ContentTypeJSON)
+	if err != nil {
+		tb.Fatalf("unexpected error: %v", err)
+	}
+	// e.g. io.k8s.api.core.v1.PodSpec is k8s.io/api/core/v1.PodSpec.
+	goName := func(name string) string {
+		name = strings.TrimPrefix(name, "io.k8s.")
+		i := strings.LastIndex(name, ".")
+		return "k8s.io/" + strings.ReplaceAll(name[:i], ".", "/") + name[i:]
+	}
+	refs := regexp.MustCompile(`"` + regexp.QuoteMeta(refPrefix) + `([^"]+)"`)
+	b = refs.ReplaceAllFunc(b, func(ref []byte) []byte {
+		return []byte(`"` + goName(string(refs.FindSubmatch(ref)[1])) + `"`)
+	})
+	resp := new(schemaResponse)
+	if err := json.Unmarshal(b, resp); err != nil {
+		tb.Fatalf("unexpected error: %v", err)
+	}
+	return func(common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		defs := make(map[string]common.OpenAPIDefinition, len(resp.Components.Schemas))
+		for name, s := range resp.Components.Schemas {
+			defs[goName(name)] = common.OpenAPIDefinition{Schema: *s}
+		}
+		return defs
+	}
+}
+
+// coreGVKs returns the GVKs of the core group-version that r resolves.
+func coreGVKs(tb testing.TB, r *DefinitionsSchemaResolver) []schema.GroupVersionKind {
+	var gvks []schema.GroupVersionKind
+	for gvk := range r.gvkToRef {
+		if gvk.Group == "" && gvk.Version == "v1" {
+			gvks = append(gvks, gvk)
+		}
+	}
+	if len(gvks) == 0 {
+		tb.Fatalf("expected definitions of the core group-version")
+	}
+	return gvks
+}
+
+func TestDefinitionsSchemaResolverSharesRefs(t *testing.T) {
+	getDefinitions := embeddedDefinitions(t, "api/v1")
+	shared := NewDefinitionsSchemaResolver(getDefinitions, scheme.Scheme)
+	unshared := NewDefinitionsSchemaResolver(getDefinitions, scheme.Scheme)
+	for i := 0; i < 2; i++ {
+		for _, gvk := range coreGVKs(t, shared) {
+			s, err := shared.ResolveSchemaShared(gvk)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected, err := unshared.ResolveSchema(gvk)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(s, expected) {
+				t.Errorf("expected the schema of %v to be the same with shared Refs", gvk)
+			}
+		}
+	}
+
+	pod, err := shared.ResolveSchemaShared(schema.GroupVersionKind{Version: "v1", Kind: "Pod"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	service, err := shared.ResolveSchemaShared(schema.GroupVersionKind{Version: "v1", Kind: "Service"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	podMeta, serviceMeta := pod.Properties["metadata"], service.Properties["metadata"]
+	if reflect.ValueOf(podMeta.Properties).UnsafePointer() != reflect.ValueOf(serviceMeta.Properties).UnsafePointer() {
+		t.Errorf("expected the ObjectMeta to be shared between the Pod and the Service")
+	}
+
+	// the Ref sites are not shared if the expanded Refs are counted.
+	_, diag, err := shared.ResolveSchemaWithDiagnostics(schema.GroupVersionKind{Version: "v1", Kind: "Pod"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, expected, err := unshared.ResolveSchemaWithDiagnostics(schema.GroupVersionKind{Version: "v1", Kind: "Pod"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diag.RefExpansions != expected.RefExpansions {
+		t.Errorf("expected %d Ref expansions, got %d", expected.RefExpansions, diag.RefExpansions)
+	}
+}
+
+func TestDefinitionsSchemaResolverMutation(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	service := schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	r := NewDefinitionsSchemaResolver(embeddedDefinitions(t, "api/v1"), scheme.Scheme)
+	// the Ref sites are shared with the other resolutions of
+	// ResolveSchemaShared, which must not be mutated.
+	for _, gvk := range []schema.GroupVersionKind{pod, service} {
+		if _, err := r.ResolveSchemaShared(gvk); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	expected, err := r.ResolveSchemaImmutable(service)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s, err := r.ResolveSchema(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	meta := s.Properties["metadata"]
+	meta.Description = "mutated"
+	meta.Properties["name"] = scalarSchema("integer", "")
+	s.Properties["metadata"] = meta
+
+	for _, resolve := range []func(schema.GroupVersionKind) (*spec.Schema, error){r.ResolveSchema, r.ResolveSchemaShared} {
+		got, err := resolve(service)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected the mutation of the Pod not to affect the Service, got metadata %v", got.Properties["metadata"])
+		}
+	}
+	again, err := r.ResolveSchema(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name := again.Properties["metadata"].Properties["name"]; !reflect.DeepEqual(name.Type, spec.StringOrArray{"string"}) {
+		t.Errorf("expected the mutation of the Pod not to affect a second resolution, got %v", name)
+	}
+}
+
+func BenchmarkDefinitionsSchemaResolver(b *testing.B) {
+	getDefinitions := embeddedDefinitions(b, "api/v1")
+	for _, tc := range []struct {
+		name   string
+		shared bool
+	}{
+		{name: "shared refs", shared: true},
+		{name: "unshared refs"},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			r := NewDefinitionsSchemaResolver(getDefinitions, scheme.Scheme)
+			resolve := r.ResolveSchema
+			if tc.shared {
+				resolve = r.ResolveSchemaShared
+			}
+			gvks := coreGVKs(b, r)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, gvk := range gvks {
+					if _, err := resolve(gvk); err != nil {
+						b.Fatalf("unexpected error: %v", err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ResolveDependencyGraph returns the graph of the definitions that the type of
+// the GVK transitively refers to, before the Refs are inlined, as an adjacency
+// list from each definition name, including that of the type itself, to the
+// sorted names of the definitions it refers to directly. Cycles are recorded
+// as edges. Referred definitions that do not exist appear as edges only.
+func (d *DefinitionsSchemaResolver) ResolveDependencyGraph(gvk schema.GroupVersionKind) (map[string][]string, error) {
+	root, ok := d.gvkToRef[gvk]
+	if !ok {
+		return nil, fmt.Errorf("cannot resolve %v: %w", gvk, ErrSchemaNotFound)
+	}
+	graph := map[string][]string{}
+	queue := []string{root}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if _, ok := graph[name]; ok {
+			continue
+		}
+		def, ok := d.defs[name]
+		if !ok {
+			continue
+		}
+		refs := sets.New[string]()
+		collectRefs(&def.Schema, refs)
+		graph[name] = sets.List(refs)
+		queue = append(queue, graph[name]...)
+	}
+	return graph, nil
+}
+
+// FindCycles returns the cycles of Refs that the type of the GVK transitively
+// reaches, e.g. the recursion of the JSONSchemaProps of a
+// CustomResourceDefinition, which PopulateRefs breaks with placeholders or
+// keeps as Refs with KeepCircularRefs. Each cycle lists the definition names
+// of a loop once, starting with the least name, each referring to the next
+// and the last to the first; a definition that refers to itself is a cycle
+// of one name. The cycles are sorted, and none is returned if the type is not
+// recursive.
+func (d *DefinitionsSchemaResolver) FindCycles(gvk schema.GroupVersionKind) ([][]string, error) {
+	graph, err := d.ResolveDependencyGraph(gvk)
+	if err != nil {
+		return nil, err
+	}
+	return findCycles(graph), nil
+}
+
+// findCycles returns the elementary cycles of the graph, as FindCycles. Every
+// cycle is found from its least name, through names greater than it only.
+func findCycles(graph map[string][]string) [][]string {
+	var cycles [][]string
+	for _, start := range sets.List(sets.KeySet(graph)) {
+		path := []string{start}
+		onPath := sets.New(start)
+		var visit func(name string)
+		visit = func(name string) {
+			for _, next := range graph[name] {
+				switch {
+				case next == start:
+					cycles = append(cycles, append([]string(nil), path...))
+				case next > start && !onPath.Has(next):
+					path = append(path, next)
+					onPath.Insert(next)
+					visit(next)
+					path = path[:len(path)-1]
+					onPath.Delete(next)
+				}
+			}
+		}
+		visit(start)
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		a, b := cycles[i], cycles[j]
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return cycles
+}
+
+// collectRefs adds the Refs anywhere in the tree of s to refs, without
+// following them.
+func collectRefs(s *spec.Schema, refs sets.Set[string]) {
+	if s.Ref.GetURL() != nil {
+		refs.Insert(s.Ref.String())
+	}
+	for name := range s.Properties {
+		prop := s.Properties[name]
+		collectRefs(&prop, refs)
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		collectRefs(s.AdditionalProperties.Schema, refs)
+	}
+	if s.Items != nil {
+		if s.Items.Schema != nil {
+			collectRefs(s.Items.Schema, refs)
+		}
+		for i := range s.Items.Schemas {
+			collectRefs(&s.Items.Schemas[i], refs)
+		}
+	}
+	for _, union := range [][]spec.Schema{s.AllOf, s.OneOf, s.AnyOf} {
+		for i := range union {
+			collectRefs(&union[i], refs)
+		}
+	}
+	if s.Not != nil {
+		collectRefs(s.Not, refs)
+	}
+}
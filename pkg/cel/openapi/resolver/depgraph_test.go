@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestResolveDependencyGraph(t *testing.T) {
+	getDefinitions := func(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		refTo := func(name string) spec.Schema {
+			return spec.Schema{SchemaProps: spec.SchemaProps{Ref: ref(name)}}
+		}
+		return map[string]common.OpenAPIDefinition{
+			"k8s.io/api/core/v1.Pod": {Schema: *objectSchema(map[string]spec.Schema{
+				"metadata": {SchemaProps: spec.SchemaProps{AllOf: []spec.Schema{refTo("k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta")}}},
+				"spec":     refTo("k8s.io/api/core/v1.PodSpec"),
+			})},
+			"k8s.io/api/core/v1.PodSpec": {Schema: *objectSchema(map[string]spec.Schema{
+				"containers": {SchemaProps: spec.SchemaProps{
+					Type:  []string{"array"},
+					Items: &spec.SchemaOrArray{Schema: &spec.Schema{SchemaProps: spec.SchemaProps{Ref: ref("k8s.io/api/core/v1.Container")}}},
+				}},
+				"affinity": refTo("k8s.io/api/core/v1.Affinity"),
+			})},
+			"k8s.io/api/core/v1.Container": {Schema: *objectSchema(map[string]spec.Schema{
+				"labels": {SchemaProps: spec.SchemaProps{
+					Type:                 []string{"object"},
+					AdditionalProperties: &spec.SchemaOrBool{Allows: true, Schema: &spec.Schema{SchemaProps: spec.SchemaProps{Ref: ref("k8s.io/api/core/v1.PodSpec")}}},
+				}},
+			})},
+			"k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta": {Schema: *objectSchema(map[string]spec.Schema{
+				"name": scalarSchema("string", ""),
+			})},
+			"k8s.io/api/core/v1.Service": {Schema: *objectSchema(nil)},
+		}
+	}
+	r := NewDefinitionsSchemaResolver(getDefinitions, scheme.Scheme)
+
+	graph, err := r.ResolveDependencyGraph(schema.GroupVersionKind{Version: "v1", Kind: "Pod"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string][]string{
+		"k8s.io/api/core/v1.Pod": {"k8s.io/api/core/v1.PodSpec", "k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"},
+		// the missing Affinity is an edge only.
+		"k8s.io/api/core/v1.PodSpec": {"k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container"},
+		// the cycle back to PodSpec is recorded as an edge.
+		"k8s.io/api/core/v1.Container":                    {"k8s.io/api/core/v1.PodSpec"},
+		"k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta": {},
+	}
+	if !reflect.DeepEqual(graph, expected) {
+		t.Errorf("expected %v, got %v", expected, graph)
+	}
+
+	if _, err := r.ResolveDependencyGraph(schema.GroupVersionKind{Version: "v1", Kind: "Missing"}); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound, got %v", err)
+	}
+}
+
+func TestFindCycles(t *testing.T) {
+	const (
+		props       = "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1.JSONSchemaProps"
+		propsOrBool = "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1.JSONSchemaPropsOrBool"
+		validation  = "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1.CustomResourceValidation"
+		crd         = "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1.CustomResourceDefinition"
+	)
+	getDefinitions := func(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		refTo := func(name string) spec.Schema {
+			return spec.Schema{SchemaProps: spec.SchemaProps{Ref: ref(name)}}
+		}
+		return map[string]common.OpenAPIDefinition{
+			crd:        {Schema: *objectSchema(map[string]spec.Schema{"validation": refTo(validation)})},
+			validation: {Schema: *objectSchema(map[string]spec.Schema{"openAPIV3Schema": refTo(props)})},
+			props: {Schema: *objectSchema(map[string]spec.Schema{
+				"not":                  refTo(props),
+				"additionalProperties": refTo(propsOrBool),
+			})},
+			propsOrBool: {Schema: *objectSchema(map[string]spec.Schema{"schema": refTo(props)})},
+			"k8s.io/api/core/v1.Pod": {Schema: *objectSchema(map[string]spec.Schema{
+				"metadata": refTo("k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"),
+			})},
+			"k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta": {Schema: *objectSchema(nil)},
+		}
+	}
+	r := NewDefinitionsSchemaResolverWithOverrides(getDefinitions, map[string]schema.GroupVersionKind{
+		crd: {Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"},
+	}, scheme.Scheme)
+
+	cycles, err := r.FindCycles(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := [][]string{{props}, {props, propsOrBool}}
+	if !reflect.DeepEqual(cycles, expected) {
+		t.Errorf("expected %v, got %v", expected, cycles)
+	}
+
+	cycles, err = r.FindCycles(schema.GroupVersionKind{Version: "v1", Kind: "Pod"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cycles) != 0 {
+		t.Errorf("expected no cycles, got %v", cycles)
+	}
+	if _, err := r.FindCycles(schema.GroupVersionKind{Version: "v1", Kind: "Missing"}); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// FieldDescriptions returns the descriptions of the fields of a resolved
+// schema, keyed by their paths in the notation of WalkSchema, for example for
+// generating documentation. The description of the root, if any, is keyed by
+// the empty path. Fields without a description are omitted.
+func FieldDescriptions(s *spec.Schema) map[string]string {
+	descriptions := map[string]string{}
+	_ = WalkSchema(s, func(path string, node *spec.Schema) error {
+		if len(node.Description) > 0 {
+			descriptions[path] = node.Description
+		}
+		return nil
+	})
+	return descriptions
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestFieldDescriptions(t *testing.T) {
+	s, err := newEmbeddedDiscoveryResolver().ResolveSchema(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	descriptions := FieldDescriptions(s)
+	for path, prefix := range map[string]string{
+		// top-level type
+		"": "Deployment enables declarative updates",
+		// description at the referencing site of an allOf-wrapped ref
+		"metadata": "Standard object's metadata.",
+		// inlined referenced types
+		"metadata.name":                           "Name must be unique within a namespace.",
+		"spec.template.spec.containers[*].image":  "Container image name.",
+		"spec.template.metadata.labels":           "Map of string keys and values",
+		"status.conditions[*].lastTransitionTime": "Last time the condition transitioned",
+	} {
+		if got, ok := descriptions[path]; !ok {
+			t.Errorf("expected a description for %q", path)
+		} else if !strings.HasPrefix(got, prefix) {
+			t.Errorf("expected the description of %q to start with %q, got %q", path, prefix, got)
+		}
+	}
+	if _, ok := descriptions["metadata.labels[*]"]; ok {
+		t.Errorf("expected fields without a description to be omitted")
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// DiagnosticSource names where a resolved schema came from.
+type DiagnosticSource string
+
+const (
+	DiagnosticSourceCache       DiagnosticSource = "cache"
+	DiagnosticSourceDiscovery   DiagnosticSource = "discovery"
+	DiagnosticSourceDefinitions DiagnosticSource = "definitions"
+)
+
+// ResolveDiagnostics describes the cost of resolving a schema, for tuning
+// caching and limits.
+type ResolveDiagnostics struct {
+	// Duration is the time spent resolving the schema.
+	Duration time.Duration
+	// Source is where the schema came from, or empty if unknown.
+	Source DiagnosticSource
+	// DocumentBytes is the size of the OpenAPI document that the schema was
+	// resolved from, if it was downloaded.
+	DocumentBytes int64
+	// RefExpansions is the number of Refs that were replaced by the schema
+	// they refer to.
+	RefExpansions int
+	// Nodes is the number of nodes of the resolved schema, as visited by
+	// WalkSchema.
+	Nodes int
+}
+
+// DiagnosticResolver is implemented by resolvers that report diagnostics of
+// the schemas they resolve. The diagnostics are only collected by
+// ResolveSchemaWithDiagnostics, so ResolveSchema does not pay for them.
+type DiagnosticResolver interface {
+	SchemaResolver
+
+	// ResolveSchemaWithDiagnostics resolves the schema like ResolveSchema and
+	// also returns diagnostics of the resolution.
+	ResolveSchemaWithDiagnostics(gvk schema.GroupVersionKind) (*spec.Schema, ResolveDiagnostics, error)
+}
+
+// resolveWithDiagnostics resolves the schema of gvk with r and fills diag, if
+// not nil, with the diagnostics reported by r, if any. ctx is passed to r
+// unless diagnostics are collected.
+func resolveWithDiagnostics(ctx context.Context, r SchemaResolver, gvk schema.GroupVersionKind, diag *ResolveDiagnostics) (*spec.Schema, error) {
+	if diag != nil {
+		if d, ok := r.(DiagnosticResolver); ok {
+			s, got, err := d.ResolveSchemaWithDiagnostics(gvk)
+			*diag = got
+			return s, err
+		}
+	}
+	return r.ResolveSchemaWithContext(ctx, gvk)
+}
+
+// countingSchemaOf wraps the schemaOf callback of PopulateRefs so that every
+// call but the first, which resolves the root, is counted in expansions, if
+// not nil.
+func countingSchemaOf(schemaOf func(ref string) (*spec.Schema, bool), expansions *int) func(ref string) (*spec.Schema, bool) {
+	if expansions == nil {
+		return schemaOf
+	}
+	root := true
+	return func(ref string) (*spec.Schema, bool) {
+		if root {
+			root = false
+		} else {
+			*expansions++
+		}
+		return schemaOf(ref)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestResolveSchemaWithDiagnostics(t *testing.T) {
+	getDefinitions := func(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		return map[string]common.OpenAPIDefinition{
+			"k8s.io/api/core/v1.Pod": {Schema: *objectSchema(map[string]spec.Schema{
+				"spec": {SchemaProps: spec.SchemaProps{Ref: ref("k8s.io/api/core/v1.PodSpec")}},
+			})},
+			"k8s.io/api/core/v1.PodSpec": {Schema: *objectSchema(map[string]spec.Schema{
+				"nodeName": scalarSchema("string", ""),
+				"containers": {SchemaProps: spec.SchemaProps{
+					Type:  []string{"array"},
+					Items: &spec.SchemaOrArray{Schema: &spec.Schema{SchemaProps: spec.SchemaProps{Ref: ref("k8s.io/api/core/v1.Container")}}},
+				}},
+			})},
+			"k8s.io/api/core/v1.Container": {Schema: *objectSchema(map[string]spec.Schema{
+				"image": scalarSchema("string", ""),
+			})},
+		}
+	}
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	definitions := NewDefinitionsSchemaResolver(getDefinitions, scheme.Scheme)
+
+	_, diag, err := definitions.ResolveSchemaWithDiagnostics(gvk)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diag.Source != DiagnosticSourceDefinitions {
+		t.Errorf("expected source %q, got %q", DiagnosticSourceDefinitions, diag.Source)
+	}
+	if diag.RefExpansions != 2 {
+		t.Errorf("expected 2 ref expansions, got %d", diag.RefExpansions)
+	}
+	// the root, spec, spec.containers, spec.containers[*],
+	// spec.containers[*].image and spec.nodeName
+	if diag.Nodes != 6 {
+		t.Errorf("expected 6 nodes, got %d", diag.Nodes)
+	}
+	if diag.DocumentBytes != 0 {
+		t.Errorf("expected no document bytes for definitions, got %d", diag.DocumentBytes)
+	}
+	if diag.Duration <= 0 {
+		t.Errorf("expected a positive duration, got %v", diag.Duration)
+	}
+
+	caching := NewCachingResolver(definitions)
+	_, diag, err = caching.ResolveSchemaWithDiagnostics(gvk)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diag.Source != DiagnosticSourceDefinitions || diag.RefExpansions != 2 {
+		t.Errorf("expected the diagnostics of the delegate on a cache miss, got %+v", diag)
+	}
+	_, diag, err = caching.ResolveSchemaWithDiagnostics(gvk)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diag.Source != DiagnosticSourceCache || diag.RefExpansions != 0 || diag.Nodes != 6 {
+		t.Errorf("expected the diagnostics of a cache hit, got %+v", diag)
+	}
+}
+
+func TestDiscoveryResolveSchemaWithDiagnostics(t *testing.T) {
+	_, diag, err := newEmbeddedDiscoveryResolver().ResolveSchemaWithDiagnostics(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diag.Source != DiagnosticSourceDiscovery {
+		t.Errorf("expected source %q, got %q", DiagnosticSourceDiscovery, diag.Source)
+	}
+	if diag.DocumentBytes == 0 || diag.RefExpansions == 0 || diag.Nodes == 0 {
+		t.Errorf("expected document bytes, ref expansions and nodes, got %+v", diag)
+	}
+}
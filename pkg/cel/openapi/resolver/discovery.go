@@ -17,70 +17,631 @@ limitations under the License.
 package resolver
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/openapi"
 	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/utils/clock"
 )
 
 // ClientDiscoveryResolver uses client-go discovery to resolve schemas at run time.
 type ClientDiscoveryResolver struct {
 	Discovery discovery.DiscoveryInterface
+
+	// ClosestVersionFallback makes the resolver fall back to the closest
+	// served version of the group, as ordered by Kubernetes version priority,
+	// if the requested version is not served or lacks the kind. The
+	// substitution is reported by ResolveSchemaWithSource.
+	ClosestVersionFallback bool
+
+	// RefreshOnMiss makes the resolver invalidate the cache of Discovery, or
+	// its own document cache, and retry once if a GVK is not found, so that
+	// types that were installed after the cache was populated resolve
+	// promptly, from the OpenAPI v3 documents or, with AllowV2Fallback, the
+	// v2 document. This only has an effect if Discovery is a
+	// discovery.CachedDiscoveryInterface or DocumentCacheTTL is set.
+	RefreshOnMiss bool
+	// MinRefreshInterval is the minimum interval between two refreshes
+	// triggered by misses in the same group-version, which guards against
+	// misses for GVKs that do not exist. Defaults to DefaultMinRefreshInterval.
+	MinRefreshInterval time.Duration
+
+	// ByteBudget, if positive, caps the total number of bytes of OpenAPI
+	// documents that the resolver downloads over its lifetime. Once the
+	// budget is consumed, further downloads fail with ErrByteBudgetExceeded.
+	ByteBudget int64
+
+	// DisablePanicRecovery disables recovering from panics during resolution.
+	// Since the documents served by discovery are untrusted, a panic is by
+	// default recovered and returned as an error wrapping
+	// ErrSchemaResolution, as with RecoverPanics.
+	DisablePanicRecovery bool
+
+	// CrossDocumentRefs makes the resolver look up Refs that the document of a
+	// group-version does not define in the documents of the other
+	// group-versions, those of the same group first. Each document is
+	// fetched at most once per resolution.
+	CrossDocumentRefs bool
+
+	// RefOptions configures how Refs of the documents are populated, e.g. to
+	// share the item schemas of arrays of the same type.
+	RefOptions PopulateRefsOptions
+
+	// RefPrefixes are the base paths of the component schemas that the Refs
+	// of the documents may use, e.g. of documents produced by other OpenAPI
+	// generators, besides "#/components/schemas/" and "#/definitions/",
+	// which are always recognized.
+	RefPrefixes []string
+
+	// DefaultBounds, if not nil, are declared on the unbounded lists, maps
+	// and strings of the resolved schemas, as with ApplyDefaultBounds, so that
+	// the cost estimation and the compilation of CEL rules behave
+	// predictably.
+	DefaultBounds *DefaultBounds
+
+	// AllowV2Fallback makes the resolver fall back to the OpenAPI v2 document
+	// of Discovery if the OpenAPI v3 endpoint is not served, or does not serve
+	// the group-version, e.g. by older or aggregated apiservers. It is opt-in
+	// since the v2 schemas lack v3 constructs such as nullable and oneOf. The
+	// v2 document is cached along with the v3 documents, if DocumentCacheTTL
+	// is set.
+	AllowV2Fallback bool
+
+	// PreferredVersions overrides, by group, the versions that
+	// ResolvePreferred tries, most preferred first, instead of those that
+	// Discovery lists, e.g. to pin a version or in tests.
+	PreferredVersions map[string][]string
+
+	// RequireFresh makes the resolver fail with an error wrapping
+	// ErrStaleDiscovery if aggregated discovery reports the group-version
+	// that a schema was resolved from as stale, see StaleGroupVersions, e.g.
+	// so that the schema of a lagging child cluster is not trusted. Each
+	// resolution lists the aggregated discovery of Discovery, which should
+	// therefore be cached, e.g. with memory.NewMemCacheClient.
+	RequireFresh bool
+
+	// DocumentCacheTTL, if positive, makes the resolver cache the OpenAPI v3
+	// paths and the parsed documents of the group-versions for that long,
+	// instead of listing the paths and fetching and parsing a whole document
+	// for every resolution. The cache is not used if UsesDiscoveryCache,
+	// since Discovery caches the documents already. Invalidate drops the
+	// cached documents, and InvalidateGroupVersion the document of a
+	// group-version. Concurrent misses may fetch a document more than once.
+	DocumentCacheTTL time.Duration
+
+	// Metrics, if not nil, observes the resolutions of the resolver.
+	Metrics Metrics
+
+	lock        sync.Mutex
+	lastRefresh map[schema.GroupVersion]time.Time
+	clock       clock.PassiveClock
+	// fetchedBytes is the number of bytes downloaded so far.
+	fetchedBytes int64
+	// cachedPaths and documents are the document cache, by resource path.
+	cachedPaths *cachedPaths
+	documents   map[string]*cachedDocument
+}
+
+// cachedPaths are the OpenAPI v3 paths of Discovery, as cached with
+// DocumentCacheTTL.
+type cachedPaths struct {
+	paths   map[string]openapi.GroupVersion
+	fetched time.Time
+}
+
+// cachedDocument is a parsed document of a group-version, as cached with
+// DocumentCacheTTL.
+type cachedDocument struct {
+	resp *schemaResponse
+	// size is the size of the document in bytes.
+	size    int64
+	fetched time.Time
 }
 
+// ErrByteBudgetExceeded is wrapped and returned if a ClientDiscoveryResolver
+// cannot download a document because its ByteBudget is consumed.
+var ErrByteBudgetExceeded = errors.New("byte budget exceeded")
+
+// ErrDiscoveryUnavailable is wrapped and returned if a ClientDiscoveryResolver
+// cannot list the OpenAPI paths or fetch a document from Discovery, e.g. if
+// the server is unreachable or fails the request. The error of the request
+// is wrapped as well, so that it can still be inspected, e.g. with
+// apierrors.IsNotFound. Unlike ErrSchemaNotFound, which is returned only if
+// Discovery did serve the documents and the schema is absent from them, it
+// usually indicates a transient condition that is worth a retry.
+var ErrDiscoveryUnavailable = errors.New("discovery unavailable")
+
+// DefaultMinRefreshInterval is the default of MinRefreshInterval.
+const DefaultMinRefreshInterval = 10 * time.Second
+
 var _ SchemaResolver = (*ClientDiscoveryResolver)(nil)
+var _ SourceResolver = (*ClientDiscoveryResolver)(nil)
+var _ GroupVersionInvalidator = (*ClientDiscoveryResolver)(nil)
+var _ DiagnosticResolver = (*ClientDiscoveryResolver)(nil)
 
+// ResolveSchema resolves the schema of gvk from the OpenAPI documents of
+// Discovery. The returned error wraps ErrDiscoveryUnavailable if Discovery
+// fails to serve the documents, and ErrSchemaNotFound if it serves them but
+// none defines gvk, so that callers can tell the two apart with errors.Is.
+// Every error is prefixed with "cannot resolve <gvk>: ".
 func (r *ClientDiscoveryResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
-	p, err := r.Discovery.OpenAPIV3().Paths()
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema and aborts
+// once ctx is done. The OpenAPI v3 client of client-go does not accept a
+// context, so its pending round trip is abandoned rather than cancelled.
+func (r *ClientDiscoveryResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	s, _, err := r.resolve(ctx, gvk)
+	return s, err
+}
+
+// ResolveSchemaWithSource resolves the schema like ResolveSchema and also
+// returns its provenance, which records whether another version was
+// substituted for the requested one.
+func (r *ClientDiscoveryResolver) ResolveSchemaWithSource(gvk schema.GroupVersionKind) (*spec.Schema, ResolveSource, error) {
+	return r.resolve(context.Background(), gvk)
+}
+
+// resolve implements ResolveSchemaWithContext and ResolveSchemaWithSource.
+func (r *ClientDiscoveryResolver) resolve(ctx context.Context, gvk schema.GroupVersionKind) (s *spec.Schema, source ResolveSource, err error) {
+	start := time.Now()
+	defer func() { observeResolve(r.Metrics, start, err) }()
+	if !r.DisablePanicRecovery {
+		defer recoverResolution(gvk, &err)
+	}
+	s, source, err = r.resolveSchemaWithSource(ctx, gvk, nil)
+	return s, source, wrapResolutionError(gvk, err)
+}
+
+// ResolveSchemaWithDiagnostics resolves the schema like ResolveSchema and also
+// returns diagnostics of the resolution, which describe the document that the
+// schema was eventually resolved from.
+func (r *ClientDiscoveryResolver) ResolveSchemaWithDiagnostics(gvk schema.GroupVersionKind) (s *spec.Schema, diag ResolveDiagnostics, err error) {
+	start := time.Now()
+	defer func() { observeResolve(r.Metrics, start, err) }()
+	if !r.DisablePanicRecovery {
+		defer recoverResolution(gvk, &err)
+	}
+	diag.Source = DiagnosticSourceDiscovery
+	s, _, err = r.resolveSchemaWithSource(context.Background(), gvk, &diag)
 	if err != nil {
-		return nil, err
+		return nil, ResolveDiagnostics{}, wrapResolutionError(gvk, err)
+	}
+	diag.Nodes = countNodes(s)
+	diag.Duration = time.Since(start)
+	return s, diag, nil
+}
+
+// resolveSchemaWithSource implements ResolveSchemaWithSource and fills diag,
+// if not nil.
+func (r *ClientDiscoveryResolver) resolveSchemaWithSource(ctx context.Context, gvk schema.GroupVersionKind, diag *ResolveDiagnostics) (*spec.Schema, ResolveSource, error) {
+	s, source, err := r.resolveFromDiscovery(ctx, gvk, diag)
+	if err != nil || !r.RequireFresh {
+		return s, source, err
+	}
+	if err := r.checkFresh(source); err != nil {
+		return nil, ResolveSource{}, err
+	}
+	return s, source, nil
+}
+
+// resolveFromDiscovery resolves the schema of gvk from the documents of
+// Discovery, regardless of their freshness, and fills diag, if not nil.
+func (r *ClientDiscoveryResolver) resolveFromDiscovery(ctx context.Context, gvk schema.GroupVersionKind, diag *ResolveDiagnostics) (*spec.Schema, ResolveSource, error) {
+	p, err := r.paths(ctx)
+	if err != nil {
+		if r.AllowV2Fallback && apierrors.IsNotFound(err) {
+			return r.resolveFromV2(ctx, gvk, diag)
+		}
+		return nil, ResolveSource{}, err
+	}
+	s, err := r.resolveFromPaths(ctx, p, gvk, diag)
+	if errors.Is(err, ErrSchemaNotFound) && r.refresh(gvk.GroupVersion()) {
+		p, err = r.paths(ctx)
+		if err != nil {
+			return nil, ResolveSource{}, err
+		}
+		s, err = r.resolveFromPaths(ctx, p, gvk, diag)
+	}
+	if _, served := p[resourcePathFromGV(gvk.GroupVersion())]; errors.Is(err, ErrSchemaNotFound) && !served && r.AllowV2Fallback {
+		return r.resolveFromV2(ctx, gvk, diag)
+	}
+	if err == nil || !errors.Is(err, ErrSchemaNotFound) || !r.ClosestVersionFallback {
+		return s, discoverySource(gvk, false), err
+	}
+	for _, version := range closestVersions(gvk.Version, servedVersions(p, gvk.Group)) {
+		substitute := gvk.GroupKind().WithVersion(version)
+		s, fallbackErr := r.resolveFromPaths(ctx, p, substitute, diag)
+		if errors.Is(fallbackErr, ErrSchemaNotFound) {
+			continue
+		}
+		if fallbackErr != nil {
+			return nil, ResolveSource{}, fallbackErr
+		}
+		return s, discoverySource(substitute, true), nil
+	}
+	return nil, ResolveSource{}, err
+}
+
+// refresh invalidates the cache of Discovery after a miss in the
+// group-version, unless RefreshOnMiss is disabled or the group-version was
+// refreshed within MinRefreshInterval. It returns true if the cache was
+// invalidated.
+func (r *ClientDiscoveryResolver) refresh(gv schema.GroupVersion) bool {
+	if !r.RefreshOnMiss {
+		return false
+	}
+	cached, ok := r.Discovery.(discovery.CachedDiscoveryInterface)
+	if !ok && !r.cachesDocuments() {
+		return false
+	}
+	interval := r.MinRefreshInterval
+	if interval == 0 {
+		interval = DefaultMinRefreshInterval
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	now := r.now()
+	if last, ok := r.lastRefresh[gv]; ok && now.Sub(last) < interval {
+		return false
+	}
+	if r.lastRefresh == nil {
+		r.lastRefresh = make(map[schema.GroupVersion]time.Time)
+	}
+	r.lastRefresh[gv] = now
+	if cached != nil {
+		cached.Invalidate()
+	} else {
+		r.dropGroupVersion(gv)
+	}
+	return true
+}
+
+// dropGroupVersion drops the cached document of the group-version gv and the
+// cached paths, which list the URL of the document. It must be called with
+// the lock held.
+func (r *ClientDiscoveryResolver) dropGroupVersion(gv schema.GroupVersion) {
+	r.cachedPaths = nil
+	delete(r.documents, resourcePathFromGV(gv))
+	// the group-version may have been resolved from, or may have been
+	// added to, the v2 document with AllowV2Fallback.
+	delete(r.documents, v2Path)
+}
+
+// now returns the current time of the clock of the resolver. It must be
+// called with the lock held.
+func (r *ClientDiscoveryResolver) now() time.Time {
+	if r.clock == nil {
+		r.clock = clock.RealClock{}
+	}
+	return r.clock.Now()
+}
+
+// cachesDocuments checks if the resolver caches documents itself, see
+// DocumentCacheTTL.
+func (r *ClientDiscoveryResolver) cachesDocuments() bool {
+	return r.DocumentCacheTTL > 0 && !r.UsesDiscoveryCache()
+}
+
+// UsesDiscoveryCache returns true if Discovery is a
+// discovery.CachedDiscoveryInterface, e.g. the memory-cached discovery client
+// of client-go, which caches the paths and the documents of OpenAPI v3. The
+// resolver then defers caching documents to Discovery rather than keeping
+// copies of its own, so that the two cannot be inconsistent, and Invalidate
+// invalidates the cache of Discovery.
+func (r *ClientDiscoveryResolver) UsesDiscoveryCache() bool {
+	_, ok := r.Discovery.(discovery.CachedDiscoveryInterface)
+	return ok
+}
+
+// Invalidate makes subsequent resolutions fetch fresh documents, by
+// invalidating the cache of Discovery if UsesDiscoveryCache, and by dropping
+// the documents cached with DocumentCacheTTL.
+func (r *ClientDiscoveryResolver) Invalidate() {
+	if cached, ok := r.Discovery.(discovery.CachedDiscoveryInterface); ok {
+		cached.Invalidate()
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.cachedPaths = nil
+	r.documents = nil
+}
+
+// InvalidateGroupVersion makes subsequent resolutions in the group-version gv
+// fetch a fresh document, by dropping its document cached with
+// DocumentCacheTTL and relisting the paths, so that e.g. a DiscoveryWatcher
+// keeps the documents of the other group-versions. If UsesDiscoveryCache,
+// the cache of Discovery can only be invalidated as a whole.
+func (r *ClientDiscoveryResolver) InvalidateGroupVersion(gv schema.GroupVersion) {
+	if cached, ok := r.Discovery.(discovery.CachedDiscoveryInterface); ok {
+		cached.Invalidate()
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.dropGroupVersion(gv)
+}
+
+// RemainingByteBudget returns the number of bytes that the resolver may still
+// download, or -1 if ByteBudget is not set.
+func (r *ClientDiscoveryResolver) RemainingByteBudget() int64 {
+	if r.ByteBudget <= 0 {
+		return -1
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.fetchedBytes >= r.ByteBudget {
+		return 0
+	}
+	return r.ByteBudget - r.fetchedBytes
+}
+
+// paths lists the OpenAPI v3 paths of Discovery, unless ctx is done first,
+// or returns the cached paths.
+func (r *ClientDiscoveryResolver) paths(ctx context.Context) (map[string]openapi.GroupVersion, error) {
+	caching := r.cachesDocuments()
+	if caching {
+		r.lock.Lock()
+		cached := r.cachedPaths
+		fresh := cached != nil && r.now().Sub(cached.fetched) < r.DocumentCacheTTL
+		r.lock.Unlock()
+		if fresh {
+			return cached.paths, nil
+		}
+	}
+	p, err := callWithContext(ctx, func() (map[string]openapi.GroupVersion, error) {
+		return r.Discovery.OpenAPIV3().Paths()
+	})
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("cannot list the OpenAPI v3 paths: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot list the OpenAPI v3 paths: %w: %w", ErrDiscoveryUnavailable, err)
+	}
+	if caching {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		r.cachedPaths = &cachedPaths{paths: p, fetched: r.now()}
+	}
+	return p, nil
+}
+
+// document returns the parsed document of the group-version gv at
+// resourcePath, fetching it if it is not cached.
+func (r *ClientDiscoveryResolver) document(ctx context.Context, p map[string]openapi.GroupVersion, resourcePath string, gv schema.GroupVersion) (*schemaResponse, int64, error) {
+	caching := r.cachesDocuments()
+	if caching {
+		r.lock.Lock()
+		cached, ok := r.documents[resourcePath]
+		fresh := ok && r.now().Sub(cached.fetched) < r.DocumentCacheTTL
+		r.lock.Unlock()
+		if fresh {
+			return cached.resp, cached.size, nil
+		}
 	}
-	resourcePath := resourcePathFromGV(gvk.GroupVersion())
 	c, ok := p[resourcePath]
 	if !ok {
-		return nil, fmt.Errorf("cannot resolve group version %q: %w", gvk.GroupVersion(), ErrSchemaNotFound)
+		return nil, 0, fmt.Errorf("cannot resolve group version %q: %w", gv, ErrSchemaNotFound)
+	}
+	// the document is requested as JSON rather than protobuf, which would
+	// have to be converted through YAML, see BenchmarkDecodeDocument.
+	contentType := runtime.ContentTypeJSON
+	b, err := r.fetch(ctx, fmt.Sprintf("the document of %q", gv), func() ([]byte, error) {
+		return c.Schema(contentType)
+	})
+	if err != nil && (ctx.Err() != nil || errors.Is(err, ErrByteBudgetExceeded)) {
+		return nil, 0, err
 	}
-	b, err := c.Schema(runtime.ContentTypeJSON)
 	if err != nil {
-		return nil, err
+		return nil, 0, documentError(gv, resourcePath, contentType, err)
 	}
 	resp := new(schemaResponse)
-	err = json.Unmarshal(b, resp)
+	if err := json.Unmarshal(b, resp); err != nil {
+		return nil, 0, err
+	}
+	if caching {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		if r.documents == nil {
+			r.documents = make(map[string]*cachedDocument)
+		}
+		r.documents[resourcePath] = &cachedDocument{resp: resp, size: int64(len(b)), fetched: r.now()}
+	}
+	return resp, int64(len(b)), nil
+}
+
+// documentError wraps the error of fetching the document of the
+// group-version gv at resourcePath as contentType with these details. The
+// OpenAPI v3 client does not expose the content types that the server
+// serves, so if the server refuses the content type, its response, which
+// usually lists them, is the best detail available and kept in err.
+func documentError(gv schema.GroupVersion, resourcePath, contentType string, err error) error {
+	if apierrors.IsNotAcceptable(err) || apierrors.IsUnsupportedMediaType(err) {
+		return fmt.Errorf("cannot fetch the document of %q at %q: the server does not serve content type %q: %w: %w", gv, resourcePath, contentType, ErrDiscoveryUnavailable, err)
+	}
+	return fmt.Errorf("cannot fetch the document of %q at %q as content type %q: %w: %w", gv, resourcePath, contentType, ErrDiscoveryUnavailable, err)
+}
+
+// fetch downloads a document with get, within the byte budget, unless ctx is
+// done first. document names the document in errors.
+func (r *ClientDiscoveryResolver) fetch(ctx context.Context, document string, get func() ([]byte, error)) ([]byte, error) {
+	if r.RemainingByteBudget() == 0 {
+		return nil, fmt.Errorf("cannot fetch %s: %w", document, ErrByteBudgetExceeded)
+	}
+	b, err := callWithContext(ctx, get)
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("cannot fetch %s: %w", document, err)
+	}
 	if err != nil {
 		return nil, err
 	}
-	ref, err := resolveRef(resp, gvk)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.fetchedBytes += int64(len(b))
+	return b, nil
+}
+
+// resolveFromPaths resolves the schema of gvk from the document of its
+// group-version and records the size of the document and the number of
+// expanded Refs in diag, if not nil.
+func (r *ClientDiscoveryResolver) resolveFromPaths(ctx context.Context, p map[string]openapi.GroupVersion, gvk schema.GroupVersionKind, diag *ResolveDiagnostics) (*spec.Schema, error) {
+	resourcePath := resourcePathFromGV(gvk.GroupVersion())
+	resp, size, err := r.document(ctx, p, resourcePath, gvk.GroupVersion())
 	if err != nil {
 		return nil, err
 	}
-	s, err := PopulateRefs(func(ref string) (*spec.Schema, bool) {
-		s, ok := resp.Components.Schemas[strings.TrimPrefix(ref, refPrefix)]
+	schemaOf := func(ref string) (*spec.Schema, bool) {
+		s, ok := resp.Components.Schemas[schemaName(ref, r.RefPrefixes...)]
 		return s, ok
-	}, ref)
+	}
+	if r.CrossDocumentRefs {
+		schemaOf = r.crossDocumentSchemaOf(ctx, p, resourcePath, resp)
+	}
+	return r.resolveFromDocument(ctx, resp, size, schemaOf, gvk, diag)
+}
+
+// resolveFromDocument resolves the schema of gvk from the document resp of
+// the given size, looking up its Refs with schemaOf, and records the size and
+// the number of expanded Refs in diag, if not nil.
+func (r *ClientDiscoveryResolver) resolveFromDocument(ctx context.Context, resp *schemaResponse, size int64, schemaOf func(ref string) (*spec.Schema, bool), gvk schema.GroupVersionKind, diag *ResolveDiagnostics) (*spec.Schema, error) {
+	var expansions *int
+	if diag != nil {
+		diag.DocumentBytes = size
+		diag.RefExpansions = 0
+		expansions = &diag.RefExpansions
+	}
+	ref, err := resolveRef(resp, gvk)
+	if err != nil {
+		return nil, err
+	}
+	s, err := PopulateRefsWithOptions(countingSchemaOf(schemaOf, expansions), ref, r.RefOptions)
 	if err != nil {
+		// a sibling document that could not be fetched because ctx is done
+		// surfaces as a missing Ref.
+		if ctxErr := checkContext(ctx, gvk); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, err
 	}
+	if r.DefaultBounds != nil {
+		return ApplyDefaultBounds(s, *r.DefaultBounds)
+	}
 	return s, nil
 }
 
-func resolveRef(resp *schemaResponse, gvk schema.GroupVersionKind) (string, error) {
-	for ref, s := range resp.Components.Schemas {
-		var gvks []schema.GroupVersionKind
-		err := s.Extensions.GetObject(extGVK, &gvks)
-		if err != nil {
-			return "", err
+// crossDocumentSchemaOf returns a schemaOf callback for PopulateRefs that
+// looks up Refs in the document resp of resourcePath and, on a miss, in the
+// documents of the other paths, fetching each of them at most once.
+func (r *ClientDiscoveryResolver) crossDocumentSchemaOf(ctx context.Context, p map[string]openapi.GroupVersion, resourcePath string, resp *schemaResponse) func(ref string) (*spec.Schema, bool) {
+	current, _ := gvFromResourcePath(resourcePath)
+	var siblings []string
+	for path := range p {
+		if _, ok := gvFromResourcePath(path); ok && path != resourcePath {
+			siblings = append(siblings, path)
+		}
+	}
+	// the documents of the same group are the most likely to define the
+	// Ref, look them up first.
+	sort.Slice(siblings, func(i, j int) bool {
+		gi, _ := gvFromResourcePath(siblings[i])
+		gj, _ := gvFromResourcePath(siblings[j])
+		if sameI, sameJ := gi.Group == current.Group, gj.Group == current.Group; sameI != sameJ {
+			return sameI
+		}
+		return siblings[i] < siblings[j]
+	})
+	docs := []*schemaResponse{resp}
+	return func(ref string) (*spec.Schema, bool) {
+		name := schemaName(ref, r.RefPrefixes...)
+		for _, doc := range docs {
+			if s, ok := doc.Components.Schemas[name]; ok {
+				return s, true
+			}
 		}
-		for _, g := range gvks {
-			if g == gvk {
-				return ref, nil
+		for len(siblings) > 0 {
+			path := siblings[0]
+			siblings = siblings[1:]
+			gv, _ := gvFromResourcePath(path)
+			doc, _, err := r.document(ctx, p, path, gv)
+			if err != nil {
+				continue
 			}
+			docs = append(docs, doc)
+			if s, ok := doc.Components.Schemas[name]; ok {
+				return s, true
+			}
+		}
+		return nil, false
+	}
+}
+
+// servedVersions returns the versions of the group that have a document.
+func servedVersions(p map[string]openapi.GroupVersion, group string) []string {
+	var versions []string
+	for path := range p {
+		if gv, ok := gvFromResourcePath(path); ok && gv.Group == group {
+			versions = append(versions, gv.Version)
 		}
 	}
-	return "", fmt.Errorf("cannot resolve group version kind %q: %w", gvk, ErrSchemaNotFound)
+	return versions
+}
+
+// closestVersions orders the served versions, other than the requested one,
+// by their distance to the requested version in Kubernetes version priority
+// order. On ties, the version of higher priority comes first.
+func closestVersions(requested string, served []string) []string {
+	all := sets.New(served...).Insert(requested).UnsortedList()
+	sort.Slice(all, func(i, j int) bool {
+		return version.CompareKubeAwareVersionStrings(all[i], all[j]) > 0
+	})
+	index := make(map[string]int, len(all))
+	for i, v := range all {
+		index[v] = i
+	}
+	distance := func(v string) int {
+		d := index[v] - index[requested]
+		if d < 0 {
+			return -d
+		}
+		return d
+	}
+	var result []string
+	for _, v := range all {
+		if v != requested {
+			result = append(result, v)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return distance(result[i]) < distance(result[j])
+	})
+	return result
+}
+
+// resolveRef returns the name of the component schema of gvk in resp, looked
+// up in the index of resp, which is built on first use.
+func resolveRef(resp *schemaResponse, gvk schema.GroupVersionKind) (string, error) {
+	resp.indexOnce.Do(resp.buildIndex)
+	if resp.indexErr != nil {
+		return "", resp.indexErr
+	}
+	if ref, ok := resp.index[gvk]; ok {
+		return ref, nil
+	}
+	return "", fmt.Errorf("the document declares no schema of %v: %w", gvk, ErrSchemaNotFound)
 }
 
 func resourcePathFromGV(gv schema.GroupVersion) string {
@@ -93,12 +654,75 @@ func resourcePathFromGV(gv schema.GroupVersion) string {
 	return resourcePath
 }
 
+// gvFromResourcePath is the inverse of resourcePathFromGV. It returns false
+// if the path is not the path of a group-version.
+func gvFromResourcePath(resourcePath string) (schema.GroupVersion, bool) {
+	parts := strings.Split(resourcePath, "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "api":
+		return schema.GroupVersion{Version: parts[1]}, true
+	case len(parts) == 3 && parts[0] == "apis":
+		return schema.GroupVersion{Group: parts[1], Version: parts[2]}, true
+	}
+	return schema.GroupVersion{}, false
+}
+
 type schemaResponse struct {
 	Components struct {
 		Schemas map[string]*spec.Schema `json:"schemas"`
 	} `json:"components"`
+
+	// index holds the names of the component schemas by the GVKs of their
+	// x-kubernetes-group-version-kind extensions, or indexErr the error of
+	// parsing the extensions. They are built once, since the documents of
+	// a group-version may be cached and resolved from many times.
+	indexOnce sync.Once
+	index     map[schema.GroupVersionKind]string
+	indexErr  error
+}
+
+// buildIndex builds the index of resp. If several schemas declare the same
+// GVK, the one of the first name in sorted order is indexed.
+func (resp *schemaResponse) buildIndex() {
+	names := make([]string, 0, len(resp.Components.Schemas))
+	for name := range resp.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	index := make(map[schema.GroupVersionKind]string)
+	for _, name := range names {
+		var gvks []schema.GroupVersionKind
+		if err := resp.Components.Schemas[name].Extensions.GetObject(extGVK, &gvks); err != nil {
+			resp.indexErr = err
+			return
+		}
+		for _, gvk := range gvks {
+			if _, ok := index[gvk]; !ok {
+				index[gvk] = name
+			}
+		}
+	}
+	resp.index = index
 }
 
 const refPrefix = "#/components/schemas/"
 
+// schemaName returns the name of the component schema that ref refers to,
+// without the first of refPrefix, definitionsRefPrefix and the given
+// prefixes that it has, so that documents whose Refs use the base path of
+// OpenAPI v2 or of other generators are resolved alike.
+func schemaName(ref string, prefixes ...string) string {
+	for _, prefix := range [2]string{refPrefix, definitionsRefPrefix} {
+		if strings.HasPrefix(ref, prefix) {
+			return ref[len(prefix):]
+		}
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(ref, prefix) {
+			return ref[len(prefix):]
+		}
+	}
+	return ref
+}
+
 const extGVK = "x-kubernetes-group-version-kind"
@@ -0,0 +1,523 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/openapi"
+	"k8s.io/client-go/openapi/openapitest"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+// fakeCachedDiscovery serves stale documents until it is invalidated.
+type fakeCachedDiscovery struct {
+	*fakeDiscovery
+	fresh         openapi.Client
+	invalidations int
+}
+
+func (d *fakeCachedDiscovery) Fresh() bool {
+	return d.invalidations > 0
+}
+
+func (d *fakeCachedDiscovery) Invalidate() {
+	d.invalidations++
+	d.openAPIV3 = d.fresh
+}
+
+func TestRefreshOnMiss(t *testing.T) {
+	ws := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	missing := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Missing"}
+	s := withGVK(*objectSchema(nil), ws)
+	fresh := openapitest.NewFakeClient()
+	fresh.PathsMap["apis/apps.clusternet.io/v1alpha1"] = openapitest.FakeGroupVersion{
+		GVSpec: newDocument(map[string]*spec.Schema{"io.clusternet.apis.apps.v1alpha1.Subscription": &s}),
+	}
+	d := &fakeCachedDiscovery{fakeDiscovery: newFakeDiscovery(openapitest.NewFakeClient()), fresh: fresh}
+	fakeClock := testingclock.NewFakePassiveClock(time.Now())
+	r := &ClientDiscoveryResolver{Discovery: d, RefreshOnMiss: true, clock: fakeClock}
+
+	if _, err := r.ResolveSchema(ws); err != nil {
+		t.Fatalf("expected the GVK to resolve after a refresh, got %v", err)
+	}
+	if d.invalidations != 1 {
+		t.Errorf("expected 1 invalidation, got %d", d.invalidations)
+	}
+
+	// misses within the interval do not refresh again.
+	for i := 0; i < 3; i++ {
+		if _, err := r.ResolveSchema(missing); !errors.Is(err, ErrSchemaNotFound) {
+			t.Fatalf("expected ErrSchemaNotFound, got %v", err)
+		}
+	}
+	if d.invalidations != 1 {
+		t.Errorf("expected refreshes to be rate limited, got %d invalidations", d.invalidations)
+	}
+
+	fakeClock.SetTime(fakeClock.Now().Add(DefaultMinRefreshInterval))
+	if _, err := r.ResolveSchema(missing); !errors.Is(err, ErrSchemaNotFound) {
+		t.Fatalf("expected ErrSchemaNotFound, got %v", err)
+	}
+	if d.invalidations != 2 {
+		t.Errorf("expected a refresh after the interval, got %d invalidations", d.invalidations)
+	}
+}
+
+func TestDefersToDiscoveryCache(t *testing.T) {
+	ws := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	newClient := func(description string) openapi.Client {
+		s := withGVK(*objectSchema(nil), ws)
+		s.Description = description
+		client := openapitest.NewFakeClient()
+		client.PathsMap["apis/apps.clusternet.io/v1alpha1"] = openapitest.FakeGroupVersion{
+			GVSpec: newDocument(map[string]*spec.Schema{"io.clusternet.apis.apps.v1alpha1.Subscription": &s}),
+		}
+		return client
+	}
+	d := &fakeCachedDiscovery{fakeDiscovery: newFakeDiscovery(newClient("stale")), fresh: newClient("fresh")}
+	r := &ClientDiscoveryResolver{Discovery: d}
+	if !r.UsesDiscoveryCache() {
+		t.Errorf("expected the resolver to use the discovery cache")
+	}
+
+	for i := 0; i < 2; i++ {
+		s, err := r.ResolveSchema(ws)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if s.Description != "stale" {
+			t.Errorf("expected the cached document of discovery, got %q", s.Description)
+		}
+	}
+	r.Invalidate()
+	if d.invalidations != 1 {
+		t.Errorf("expected the discovery cache to be invalidated once, got %d invalidations", d.invalidations)
+	}
+	s, err := r.ResolveSchema(ws)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Description != "fresh" {
+		t.Errorf("expected the fresh document right after the invalidation, got %q", s.Description)
+	}
+
+	if uncached := newEmbeddedDiscoveryResolver(); uncached.UsesDiscoveryCache() {
+		t.Errorf("expected a resolver over plain discovery not to use the discovery cache")
+	}
+}
+
+// countingOpenAPIClient counts the round trips to its delegate.
+type countingOpenAPIClient struct {
+	delegate openapi.Client
+	paths    atomic.Int32
+	schemas  atomic.Int32
+}
+
+func (c *countingOpenAPIClient) Paths() (map[string]openapi.GroupVersion, error) {
+	c.paths.Add(1)
+	p, err := c.delegate.Paths()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]openapi.GroupVersion, len(p))
+	for path, gv := range p {
+		result[path] = countingGroupVersion{delegate: gv, schemas: &c.schemas}
+	}
+	return result, nil
+}
+
+type countingGroupVersion struct {
+	delegate openapi.GroupVersion
+	schemas  *atomic.Int32
+}
+
+func (gv countingGroupVersion) Schema(contentType string) ([]byte, error) {
+	gv.schemas.Add(1)
+	return gv.delegate.Schema(contentType)
+}
+
+func TestDocumentCache(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	service := schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	client := &countingOpenAPIClient{delegate: openapitest.NewEmbeddedFileClient()}
+	fakeClock := testingclock.NewFakePassiveClock(time.Now())
+	r := &ClientDiscoveryResolver{Discovery: newFakeDiscovery(client), DocumentCacheTTL: time.Minute, clock: fakeClock}
+	expectRoundTrips := func(paths, schemas int32) {
+		t.Helper()
+		if got := client.paths.Load(); got != paths {
+			t.Errorf("expected %d listings of the paths, got %d", paths, got)
+		}
+		if got := client.schemas.Load(); got != schemas {
+			t.Errorf("expected %d fetched documents, got %d", schemas, got)
+		}
+	}
+
+	// concurrent misses may fetch the document more than once, resolve once
+	// to populate the cache.
+	if _, err := r.ResolveSchema(pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, gvk := range []schema.GroupVersionKind{pod, service} {
+				if _, err := r.ResolveSchema(gvk); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	expectRoundTrips(1, 1)
+
+	// expired entries are fetched again.
+	fakeClock.SetTime(fakeClock.Now().Add(time.Minute))
+	if _, err := r.ResolveSchema(pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectRoundTrips(2, 2)
+
+	r.Invalidate()
+	if _, err := r.ResolveSchema(pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectRoundTrips(3, 3)
+
+	// misses in unknown group-versions are served from the cached paths,
+	// unless RefreshOnMiss drops them.
+	missing := schema.GroupVersionKind{Group: "missing.example.com", Version: "v1", Kind: "Missing"}
+	if _, err := r.ResolveSchema(missing); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound, got %v", err)
+	}
+	expectRoundTrips(3, 3)
+	r.RefreshOnMiss = true
+	if _, err := r.ResolveSchema(missing); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound, got %v", err)
+	}
+	expectRoundTrips(4, 3)
+}
+
+func TestDocumentCacheDisabled(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	for _, tc := range []struct {
+		name      string
+		discovery func(client openapi.Client) discovery.DiscoveryInterface
+		ttl       time.Duration
+	}{
+		{
+			name: "no ttl",
+			discovery: func(client openapi.Client) discovery.DiscoveryInterface {
+				return newFakeDiscovery(client)
+			},
+		},
+		{
+			name: "cached discovery",
+			discovery: func(client openapi.Client) discovery.DiscoveryInterface {
+				return &fakeCachedDiscovery{fakeDiscovery: newFakeDiscovery(client), fresh: client}
+			},
+			ttl: time.Minute,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &countingOpenAPIClient{delegate: openapitest.NewEmbeddedFileClient()}
+			r := &ClientDiscoveryResolver{Discovery: tc.discovery(client), DocumentCacheTTL: tc.ttl}
+			for i := 0; i < 2; i++ {
+				if _, err := r.ResolveSchema(pod); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if got := client.schemas.Load(); got != 2 {
+				t.Errorf("expected the document to be fetched for every resolution, got %d fetches", got)
+			}
+		})
+	}
+}
+
+func TestByteBudget(t *testing.T) {
+	docs := make(map[string][]byte)
+	var gvks []schema.GroupVersionKind
+	for _, group := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		gvk := schema.GroupVersionKind{Group: group, Version: "v1", Kind: "Widget"}
+		s := withGVK(*objectSchema(nil), gvk)
+		docs[resourcePathFromGV(gvk.GroupVersion())] = newDocument(map[string]*spec.Schema{group + ".Widget": &s})
+		gvks = append(gvks, gvk)
+	}
+	size := int64(len(docs[resourcePathFromGV(gvks[0].GroupVersion())]))
+
+	r := newDocumentsDiscoveryResolver(docs)
+	if remaining := r.RemainingByteBudget(); remaining != -1 {
+		t.Errorf("expected no budget, got %d", remaining)
+	}
+	r.ByteBudget = 2*size - 1
+	if _, err := r.ResolveSchema(gvks[0]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if remaining := r.RemainingByteBudget(); remaining != size-1 {
+		t.Errorf("expected %d remaining bytes, got %d", size-1, remaining)
+	}
+	// the budget is consumed by this fetch.
+	if _, err := r.ResolveSchema(gvks[1]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if remaining := r.RemainingByteBudget(); remaining != 0 {
+		t.Errorf("expected the budget to be consumed, got %d remaining bytes", remaining)
+	}
+	if _, err := r.ResolveSchema(gvks[2]); !errors.Is(err, ErrByteBudgetExceeded) {
+		t.Errorf("expected ErrByteBudgetExceeded, got %v", err)
+	}
+}
+
+func TestCrossDocumentRefs(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	subscription := withGVK(*objectSchema(map[string]spec.Schema{
+		"spec":   {SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef(refPrefix + "io.clusternet.apis.shared.v1.Feed")}},
+		"status": {SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef(refPrefix + "io.clusternet.apis.missing.v1.Status")}},
+	}), gvk)
+	feed := *objectSchema(map[string]spec.Schema{"kind": scalarSchema("string", "")})
+	docs := map[string][]byte{
+		"apis/apps.clusternet.io/v1alpha1": newDocument(map[string]*spec.Schema{"io.clusternet.apis.apps.v1alpha1.Subscription": &subscription}),
+		"apis/shared.clusternet.io/v1":     newDocument(map[string]*spec.Schema{"io.clusternet.apis.shared.v1.Feed": &feed}),
+	}
+
+	r := newDocumentsDiscoveryResolver(docs)
+	if _, err := r.ResolveSchema(gvk); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound without cross-document refs, got %v", err)
+	}
+
+	r.CrossDocumentRefs = true
+	if _, err := r.ResolveSchema(gvk); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound for a ref defined in no document, got %v", err)
+	}
+	// one fetch without and one with cross-document refs, plus the sibling.
+	if expected := int64(2*len(docs["apis/apps.clusternet.io/v1alpha1"]) + len(docs["apis/shared.clusternet.io/v1"])); r.fetchedBytes != expected {
+		t.Errorf("expected each document to be fetched once per resolution, fetched %d bytes instead of %d", r.fetchedBytes, expected)
+	}
+	delete(subscription.Properties, "status")
+	docs["apis/apps.clusternet.io/v1alpha1"] = newDocument(map[string]*spec.Schema{"io.clusternet.apis.apps.v1alpha1.Subscription": &subscription})
+	r = newDocumentsDiscoveryResolver(docs)
+	r.CrossDocumentRefs = true
+	s, err := r.ResolveSchema(gvk)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := s.Properties["spec"].Properties["kind"]; !ok {
+		t.Errorf("expected the ref to be resolved from the sibling document, got %v", s.Properties["spec"])
+	}
+}
+
+func TestResolveRefIndex(t *testing.T) {
+	v1 := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	v2 := v1.GroupKind().WithVersion("v1alpha2")
+	subscription := withGVK(*objectSchema(nil), v1, v2)
+	base := withGVK(*objectSchema(nil), v1.GroupVersion().WithKind("Base"))
+	resp := new(schemaResponse)
+	resp.Components.Schemas = map[string]*spec.Schema{
+		"Subscription": &subscription,
+		"Base":         &base,
+		"Status":       objectSchema(nil),
+	}
+
+	for _, tc := range []struct {
+		gvk     schema.GroupVersionKind
+		wantRef string
+		wantErr error
+	}{
+		{gvk: v1, wantRef: "Subscription"},
+		{gvk: v2, wantRef: "Subscription"},
+		{gvk: v1.GroupVersion().WithKind("Base"), wantRef: "Base"},
+		{gvk: v1.GroupVersion().WithKind("Status"), wantErr: ErrSchemaNotFound},
+	} {
+		t.Run(tc.gvk.String(), func(t *testing.T) {
+			// repeated lookups are served by the same index.
+			for i := 0; i < 2; i++ {
+				ref, err := resolveRef(resp, tc.gvk)
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+				}
+				if ref != tc.wantRef {
+					t.Errorf("expected ref %q, got %q", tc.wantRef, ref)
+				}
+			}
+		})
+	}
+	if len(resp.index) != 3 {
+		t.Errorf("expected 3 indexed GVKs, got %v", resp.index)
+	}
+
+	malformed := *objectSchema(nil)
+	malformed.AddExtension(extGVK, "Subscription")
+	resp = new(schemaResponse)
+	resp.Components.Schemas = map[string]*spec.Schema{"Subscription": &malformed}
+	if _, err := resolveRef(resp, v1); err == nil || errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected an error for the malformed extension, got %v", err)
+	}
+}
+
+func TestDocumentErrors(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	for _, tc := range []struct {
+		name         string
+		err          error
+		wantContains []string
+		wantReason   func(error) bool
+	}{
+		{
+			name:         "content type not served",
+			err:          apierrors.NewGenericServerResponse(http.StatusNotAcceptable, "get", schema.GroupResource{}, "", "only application/com.github.proto-openapi.spec.v3@v1.0+protobuf is served", 0, true),
+			wantContains: []string{`"apis/apps/v1"`, `does not serve content type "application/json"`, "only application/com.github.proto-openapi.spec.v3@v1.0+protobuf is served"},
+			wantReason:   apierrors.IsNotAcceptable,
+		},
+		{
+			name:         "not found",
+			err:          apierrors.NewNotFound(schema.GroupResource{}, "apis/apps/v1"),
+			wantContains: []string{`"apis/apps/v1"`, `as content type "application/json"`},
+			wantReason:   apierrors.IsNotFound,
+		},
+		{
+			name:         "transport error",
+			err:          errors.New("connection refused"),
+			wantContains: []string{`"apis/apps/v1"`, `as content type "application/json"`, "connection refused"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := openapitest.NewFakeClient()
+			client.PathsMap["apis/apps/v1"] = openapitest.FakeGroupVersion{ForcedErr: tc.err}
+			r := &ClientDiscoveryResolver{Discovery: newFakeDiscovery(client)}
+			_, err := r.ResolveSchema(gvk)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected the error to wrap %v, got %v", tc.err, err)
+			}
+			if !errors.Is(err, ErrDiscoveryUnavailable) || errors.Is(err, ErrSchemaNotFound) {
+				t.Errorf("expected the error to wrap ErrDiscoveryUnavailable only, got %v", err)
+			}
+			for _, expected := range tc.wantContains {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("expected the error to contain %q, got %v", expected, err)
+				}
+			}
+			if tc.wantReason != nil && !tc.wantReason(err) {
+				t.Errorf("expected the reason of the error to be kept, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDiscoveryUnavailable(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	refused := errors.New("connection refused")
+	for _, tc := range []struct {
+		name     string
+		fallback bool
+		// pathsErr is the error of listing the OpenAPI v3 paths.
+		pathsErr error
+		// v2Err is the error of fetching the OpenAPI v2 document.
+		v2Err error
+		// docs are the OpenAPI v3 documents by group-version path.
+		docs            map[string][]byte
+		wantUnavailable bool
+		wantNotFound    bool
+	}{
+		{name: "paths unavailable", pathsErr: refused, wantUnavailable: true},
+		{name: "v3 not served", pathsErr: apierrors.NewNotFound(schema.GroupResource{}, "openapi/v3"), wantUnavailable: true},
+		{
+			name:            "v2 unavailable",
+			fallback:        true,
+			pathsErr:        apierrors.NewNotFound(schema.GroupResource{}, "openapi/v3"),
+			v2Err:           refused,
+			wantUnavailable: true,
+		},
+		{name: "group-version absent", docs: map[string][]byte{"apis/apps/v1": newDocument(nil)}, wantNotFound: true},
+		{name: "kind absent", docs: map[string][]byte{"api/v1": newDocument(nil)}, wantNotFound: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := openapitest.NewFakeClient()
+			client.ForcedErr = tc.pathsErr
+			for path, doc := range tc.docs {
+				client.PathsMap[path] = openapitest.FakeGroupVersion{GVSpec: doc}
+			}
+			d := newFakeDiscovery(client)
+			d.openAPIV2Err = tc.v2Err
+			r := &ClientDiscoveryResolver{Discovery: d, AllowV2Fallback: tc.fallback}
+			_, err := r.ResolveSchema(pod)
+			if unavailable := errors.Is(err, ErrDiscoveryUnavailable); unavailable != tc.wantUnavailable {
+				t.Errorf("expected the error to wrap ErrDiscoveryUnavailable to be %v, got %v", tc.wantUnavailable, err)
+			}
+			if notFound := errors.Is(err, ErrSchemaNotFound); notFound != tc.wantNotFound {
+				t.Errorf("expected the error to wrap ErrSchemaNotFound to be %v, got %v", tc.wantNotFound, err)
+			}
+			if tc.pathsErr != nil && tc.v2Err == nil && !errors.Is(err, tc.pathsErr) {
+				t.Errorf("expected the error to wrap %v, got %v", tc.pathsErr, err)
+			}
+		})
+	}
+}
+
+func TestRefPrefixes(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	for _, tc := range []struct {
+		name        string
+		prefix      string
+		refPrefixes []string
+		wantErr     bool
+	}{
+		{name: "components", prefix: refPrefix},
+		{name: "definitions", prefix: "#/definitions/"},
+		{name: "custom prefix", prefix: "#/$defs/", refPrefixes: []string{"#/$defs/"}},
+		{name: "unknown prefix", prefix: "#/$defs/", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			subscription := withGVK(*objectSchema(map[string]spec.Schema{
+				"spec": {SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef(tc.prefix + "io.clusternet.apis.apps.v1alpha1.SubscriptionSpec")}},
+			}), gvk)
+			r := newDocumentsDiscoveryResolver(map[string][]byte{
+				"apis/apps.clusternet.io/v1alpha1": newDocument(map[string]*spec.Schema{
+					"io.clusternet.apis.apps.v1alpha1.Subscription": &subscription,
+					"io.clusternet.apis.apps.v1alpha1.SubscriptionSpec": objectSchema(map[string]spec.Schema{
+						"subscribers": scalarSchema("string", ""),
+					}),
+				}),
+			})
+			r.RefPrefixes = tc.refPrefixes
+			s, err := r.ResolveSchema(gvk)
+			if tc.wantErr {
+				if !errors.Is(err, ErrSchemaNotFound) {
+					t.Errorf("expected ErrSchemaNotFound for the unresolved Ref, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok := s.Properties["spec"].Properties["subscribers"]; !ok {
+				t.Errorf("expected the Ref to be populated, got %v", s.Properties["spec"])
+			}
+		})
+	}
+}
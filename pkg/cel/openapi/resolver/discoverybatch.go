@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ResolveSchemas resolves the schemas of the given GVKs like ResolveSchema,
+// but lists the OpenAPI v3 paths once and fetches and parses the document of
+// each group-version at most once for the whole batch, rather than for every
+// GVK, e.g. to build a CEL environment that spans related types. With
+// CrossDocumentRefs, the GVKs of a group-version also share the other
+// documents that their Refs are looked up in. A GVK that the document of its
+// group-version does not define is resolved on its own, so that
+// RefreshOnMiss, AllowV2Fallback and ClosestVersionFallback apply to it as
+// with ResolveSchema.
+// The returned map holds the schemas that resolved, and the returned error
+// joins the errors of the GVKs that failed, each naming its GVK, rather than
+// aborting the batch.
+func (r *ClientDiscoveryResolver) ResolveSchemas(gvks []schema.GroupVersionKind) (map[schema.GroupVersionKind]*spec.Schema, error) {
+	ctx := context.Background()
+	result := make(map[schema.GroupVersionKind]*spec.Schema, len(gvks))
+	var errs []error
+	record := func(gvk schema.GroupVersionKind, s *spec.Schema, err error) {
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		result[gvk] = s
+	}
+
+	byPath := make(map[string][]schema.GroupVersionKind)
+	for _, gvk := range sets.New(gvks...).UnsortedList() {
+		path := resourcePathFromGV(gvk.GroupVersion())
+		byPath[path] = append(byPath[path], gvk)
+	}
+	for _, gvks := range byPath {
+		sortGVKs(gvks)
+	}
+	p, err := r.paths(ctx)
+	var stale sets.Set[schema.GroupVersion]
+	if err == nil && r.RequireFresh {
+		stale, err = r.StaleGroupVersions()
+	}
+	if err != nil {
+		// each GVK fails the same, unless it falls back to the OpenAPI v2
+		// document.
+		for _, path := range sets.List(sets.KeySet(byPath)) {
+			for _, gvk := range byPath[path] {
+				s, err := r.ResolveSchema(gvk)
+				record(gvk, s, err)
+			}
+		}
+		return result, errors.Join(errs...)
+	}
+
+	for _, path := range sets.List(sets.KeySet(byPath)) {
+		gv := byPath[path][0].GroupVersion()
+		resp, size, docErr := r.document(ctx, p, path, gv)
+		var schemaOf func(ref string) (*spec.Schema, bool)
+		if docErr == nil {
+			schemaOf = func(ref string) (*spec.Schema, bool) {
+				s, ok := resp.Components.Schemas[schemaName(ref, r.RefPrefixes...)]
+				return s, ok
+			}
+			if r.CrossDocumentRefs {
+				schemaOf = r.crossDocumentSchemaOf(ctx, p, path, resp)
+			}
+		}
+		for _, gvk := range byPath[path] {
+			if errors.Is(docErr, ErrSchemaNotFound) {
+				s, err := r.ResolveSchema(gvk)
+				record(gvk, s, err)
+				continue
+			}
+			if docErr != nil {
+				record(gvk, nil, wrapResolutionError(gvk, docErr))
+				continue
+			}
+			start := time.Now()
+			s, err := r.resolveInBatch(ctx, resp, size, schemaOf, gvk, stale)
+			if errors.Is(err, ErrSchemaNotFound) {
+				s, err = r.ResolveSchema(gvk)
+			} else {
+				observeResolve(r.Metrics, start, err)
+			}
+			record(gvk, s, err)
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// resolveInBatch resolves the schema of gvk from the document resp of its
+// group-version for ResolveSchemas, failing with ErrStaleDiscovery if its
+// group-version is in stale.
+func (r *ClientDiscoveryResolver) resolveInBatch(ctx context.Context, resp *schemaResponse, size int64, schemaOf func(ref string) (*spec.Schema, bool), gvk schema.GroupVersionKind, stale sets.Set[schema.GroupVersion]) (s *spec.Schema, err error) {
+	if !r.DisablePanicRecovery {
+		defer recoverResolution(gvk, &err)
+	}
+	s, err = r.resolveFromDocument(ctx, resp, size, schemaOf, gvk, nil)
+	if err == nil && stale.Has(gvk.GroupVersion()) {
+		err = staleError(gvk)
+	}
+	if err != nil {
+		return nil, wrapResolutionError(gvk, err)
+	}
+	return s, nil
+}
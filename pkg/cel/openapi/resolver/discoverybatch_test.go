@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/openapi/openapitest"
+)
+
+func TestResolveSchemas(t *testing.T) {
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	replicaSet := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	podTemplate := schema.GroupVersionKind{Version: "v1", Kind: "PodTemplate"}
+	configMap := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	job := schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}
+	gvks := []schema.GroupVersionKind{deployment, replicaSet, pod, podTemplate, configMap, job, deployment}
+
+	client := &countingOpenAPIClient{delegate: openapitest.NewEmbeddedFileClient()}
+	r := &ClientDiscoveryResolver{Discovery: newFakeDiscovery(client)}
+	schemas, err := r.ResolveSchemas(gvks)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(schemas) != 6 {
+		t.Errorf("expected 6 schemas, got %d", len(schemas))
+	}
+	for _, gvk := range gvks {
+		expected, err := newEmbeddedDiscoveryResolver().ResolveSchema(gvk)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if s, ok := schemas[gvk]; !ok || !reflect.DeepEqual(s, expected) {
+			t.Errorf("expected the schema of %v as with ResolveSchema, got %v", gvk, s)
+		}
+	}
+	if paths, fetched := client.paths.Load(), client.schemas.Load(); paths != 1 || fetched != 3 {
+		t.Errorf("expected the paths to be listed once and 3 documents to be fetched, got %d and %d", paths, fetched)
+	}
+}
+
+func TestResolveSchemasPartialFailures(t *testing.T) {
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	missingKind := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Missing"}
+	missingGroup := schema.GroupVersionKind{Group: "missing.example.com", Version: "v1", Kind: "Missing"}
+	malformed := schema.GroupVersionKind{Group: "malformed.example.com", Version: "v1", Kind: "Widget"}
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+
+	client := openapitest.NewEmbeddedFileClient()
+	paths, err := client.Paths()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fake := openapitest.NewFakeClient()
+	for path, gv := range paths {
+		fake.PathsMap[path] = gv
+	}
+	fake.PathsMap["apis/malformed.example.com/v1"] = openapitest.FakeGroupVersion{GVSpec: []byte("{")}
+	r := &ClientDiscoveryResolver{Discovery: newFakeDiscovery(fake)}
+
+	schemas, err := r.ResolveSchemas([]schema.GroupVersionKind{deployment, missingKind, missingGroup, malformed, pod})
+	if len(schemas) != 2 || schemas[deployment] == nil || schemas[pod] == nil {
+		t.Errorf("expected the schemas of %v and %v, got %v", deployment, pod, schemas)
+	}
+	if !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound to be wrapped, got %v", err)
+	}
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) || len(joined.Unwrap()) != 3 {
+		t.Fatalf("expected the errors of 3 GVKs to be joined, got %v", err)
+	}
+	for _, gvk := range []schema.GroupVersionKind{missingKind, missingGroup, malformed} {
+		if !strings.Contains(err.Error(), fmt.Sprintf("cannot resolve %v: ", gvk)) {
+			t.Errorf("expected the error to name %v, got %v", gvk, err)
+		}
+	}
+}
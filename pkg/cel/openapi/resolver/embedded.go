@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// processEmbeddedResource completes the schema of an object marked with
+// x-kubernetes-embedded-resource, e.g. the template of a workload declared
+// by a CRD, with the apiVersion, kind and metadata of a Kubernetes object,
+// which such schemas usually leave undeclared, so that CEL rules that reach
+// into e.g. metadata.labels type-check. Declared fields are kept, and a
+// declared metadata is completed with the fields of ObjectMeta it lacks.
+func processEmbeddedResource(node *spec.Schema, value interface{}) (*spec.Schema, error) {
+	if embedded, ok := value.(bool); !ok || !embedded {
+		return node, nil
+	}
+	result := *node
+	props := make(map[string]spec.Schema, len(node.Properties)+3)
+	for name, prop := range node.Properties {
+		props[name] = prop
+	}
+	for _, name := range []string{"apiVersion", "kind"} {
+		if _, ok := props[name]; !ok {
+			props[name] = *spec.StringProperty()
+		}
+	}
+	meta := objectMetaSchema()
+	if declared, ok := props["metadata"]; ok {
+		merged := declared
+		merged.Type = meta.Type
+		merged.Properties = meta.Properties
+		for name, prop := range declared.Properties {
+			merged.Properties[name] = prop
+		}
+		meta = &merged
+	}
+	props["metadata"] = *meta
+	result.Properties = props
+	if len(result.Type) == 0 {
+		result.Type = spec.StringOrArray{"object"}
+	}
+	return &result, nil
+}
+
+// objectMetaSchema returns a new schema of the fields of ObjectMeta that
+// describe an object, leaving out managedFields and ownerReferences.
+func objectMetaSchema() *spec.Schema {
+	finalizers := spec.ArrayProperty(spec.StringProperty())
+	finalizers.AddExtension(extListType, "set")
+	return &spec.Schema{SchemaProps: spec.SchemaProps{
+		Type: []string{"object"},
+		Properties: map[string]spec.Schema{
+			"name":                       *spec.StringProperty(),
+			"generateName":               *spec.StringProperty(),
+			"namespace":                  *spec.StringProperty(),
+			"uid":                        *spec.StringProperty(),
+			"resourceVersion":            *spec.StringProperty(),
+			"generation":                 *spec.Int64Property(),
+			"creationTimestamp":          *spec.DateTimeProperty(),
+			"deletionTimestamp":          *spec.DateTimeProperty(),
+			"deletionGracePeriodSeconds": *spec.Int64Property(),
+			"labels":                     *spec.MapProperty(spec.StringProperty()),
+			"annotations":                *spec.MapProperty(spec.StringProperty()),
+			"finalizers":                 *finalizers,
+		},
+	}}
+}
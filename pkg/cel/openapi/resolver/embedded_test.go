@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"testing"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/utils/ptr"
+)
+
+func TestPopulateRefsEmbeddedResource(t *testing.T) {
+	name := scalarSchema("string", "")
+	name.MaxLength = ptr.To[int64](63)
+	template := *objectSchema(map[string]spec.Schema{
+		"metadata": *objectSchema(map[string]spec.Schema{"name": name}),
+		"spec":     *objectSchema(nil),
+	})
+	template.AddExtension(extEmbeddedResource, true)
+	notEmbedded := *objectSchema(nil)
+	notEmbedded.AddExtension(extEmbeddedResource, false)
+	schemas := map[string]*spec.Schema{
+		"Root": objectSchema(map[string]spec.Schema{
+			"spec": {SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef("Spec")}},
+		}),
+		"Spec": objectSchema(map[string]spec.Schema{
+			"template": template,
+			"other":    notEmbedded,
+		}),
+	}
+	s, err := PopulateRefs(func(ref string) (*spec.Schema, bool) {
+		s, ok := schemas[ref]
+		return s, ok
+	}, "Root")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	embedded := s.Properties["spec"].Properties["template"]
+	for _, name := range []string{"apiVersion", "kind", "metadata", "spec"} {
+		if _, ok := embedded.Properties[name]; !ok {
+			t.Errorf("expected %s to be declared by the embedded resource", name)
+		}
+	}
+	meta := embedded.Properties["metadata"]
+	if labels := meta.Properties["labels"]; labels.AdditionalProperties == nil || !labels.AdditionalProperties.Schema.Type.Contains("string") {
+		t.Errorf("expected the labels of the embedded resource to be a map of strings, got %v", labels)
+	}
+	if got := meta.Properties["name"].MaxLength; got == nil || *got != 63 {
+		t.Errorf("expected the declared name to be kept, got maxLength %v", got)
+	}
+	if other := s.Properties["spec"].Properties["other"]; len(other.Properties) != 0 {
+		t.Errorf("expected an object that is not embedded to be kept, got %v", other)
+	}
+	if len(schemas["Spec"].Properties["template"].Properties) != 2 || len(template.Properties["metadata"].Properties) != 1 {
+		t.Errorf("expected the original schema not to be mutated")
+	}
+
+	declType, err := SchemaToDeclType(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	labels := declType.Fields["spec"].Type.Fields["template"].Type.Fields["metadata"].Type.Fields["labels"]
+	if labels == nil || !labels.Type.IsMap() {
+		t.Errorf("expected the labels of the embedded resource to be exposed to CEL as a map, got %v", labels)
+	}
+}
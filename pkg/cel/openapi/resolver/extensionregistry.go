@@ -0,0 +1,131 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"sort"
+	"strings"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ExtensionHandler defines how resolution treats an x-kubernetes-* or other
+// vendor extension.
+type ExtensionHandler struct {
+	// OverridesRef makes the extension declared at a referencing site, e.g.
+	// next to a Ref wrapped in allOf, take precedence over that of the
+	// referred schema when the Ref is inlined. An extension of the site that
+	// the referred schema lacks is kept regardless.
+	OverridesRef bool
+	// Process, if not nil, is invoked after the Refs are inlined for every
+	// node that carries the extension, with the value of the extension. It
+	// returns the replacement of the node, or the node itself if it is kept
+	// unchanged, and must not mutate the node.
+	Process func(node *spec.Schema, value interface{}) (*spec.Schema, error)
+}
+
+// ExtensionRegistry holds the ExtensionHandlers by extension key. It must not
+// be modified while in use by a resolution.
+type ExtensionRegistry struct {
+	handlers map[string]ExtensionHandler
+	// keys are the sorted keys of handlers, so that handlers run in a
+	// deterministic order.
+	keys []string
+}
+
+// NewExtensionRegistry returns a registry with the built-in handlers, to
+// which custom handlers can be added.
+func NewExtensionRegistry() *ExtensionRegistry {
+	r := &ExtensionRegistry{}
+	// the list and map types affect how CEL and server-side apply treat the
+	// list or map of the field, which may differ from those of its type, as
+	// may whether the field preserves unknown fields.
+	for _, key := range []string{extListType, extListMapKeys, extMapType, extPreserveUnknownFields} {
+		r.Register(key, ExtensionHandler{OverridesRef: true})
+	}
+	// embedded resources are completed with the fields of a Kubernetes object.
+	r.Register(extEmbeddedResource, ExtensionHandler{Process: processEmbeddedResource})
+	return r
+}
+
+// defaultExtensions is the registry used if none is configured.
+var defaultExtensions = NewExtensionRegistry()
+
+// Register sets the handler of the extension key, replacing any previous one.
+// Keys are case-insensitive, like those of spec.Extensions.
+func (r *ExtensionRegistry) Register(key string, handler ExtensionHandler) {
+	key = strings.ToLower(key)
+	if r.handlers == nil {
+		r.handlers = map[string]ExtensionHandler{}
+	}
+	if _, ok := r.handlers[key]; !ok {
+		r.keys = append(r.keys, key)
+		sort.Strings(r.keys)
+	}
+	r.handlers[key] = handler
+}
+
+// overrideRef merges the extensions of the referencing site into result,
+// which is a shallow copy of the referred schema: those that the referred
+// schema lacks are added, and those that it declares as well are replaced if
+// they override it.
+func (r *ExtensionRegistry) overrideRef(site *spec.Schema, result *spec.Schema) {
+	copied := false
+	for key, value := range site.Extensions {
+		if _, ok := result.Extensions[key]; ok && !r.handlers[strings.ToLower(key)].OverridesRef {
+			continue
+		}
+		if !copied {
+			extensions := make(spec.Extensions, len(result.Extensions)+1)
+			for k, v := range result.Extensions {
+				extensions[k] = v
+			}
+			result.Extensions = extensions
+			copied = true
+		}
+		result.Extensions[key] = value
+	}
+}
+
+// process runs the Process functions of the handlers over the tree of s.
+func (r *ExtensionRegistry) process(s *spec.Schema) (*spec.Schema, error) {
+	hasProcess := false
+	for _, key := range r.keys {
+		hasProcess = hasProcess || r.handlers[key].Process != nil
+	}
+	if !hasProcess {
+		return s, nil
+	}
+	return transformSchema(s, func(node *spec.Schema) (*spec.Schema, error) {
+		for _, key := range r.keys {
+			process := r.handlers[key].Process
+			if process == nil {
+				continue
+			}
+			value, ok := node.Extensions[key]
+			if !ok {
+				continue
+			}
+			processed, err := process(node, value)
+			if err != nil {
+				return nil, err
+			}
+			node = processed
+		}
+		return node, nil
+	})
+}
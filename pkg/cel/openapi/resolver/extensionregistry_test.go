@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestExtensionRegistry(t *testing.T) {
+	const extSensitive = "x-clusternet-sensitive"
+	const extOwner = "x-clusternet-owner"
+	token := scalarSchema("string", "")
+	token.AddExtension(extSensitive, true)
+	site := spec.Schema{SchemaProps: spec.SchemaProps{
+		AllOf: []spec.Schema{{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef("Credentials")}}},
+	}}
+	site.AddExtension(extOwner, "hub")
+	credentials := objectSchema(map[string]spec.Schema{"token": token})
+	credentials.AddExtension(extOwner, "agent")
+	schemas := map[string]*spec.Schema{
+		"Root":        objectSchema(map[string]spec.Schema{"credentials": site}),
+		"Credentials": credentials,
+	}
+	schemaOf := func(ref string) (*spec.Schema, bool) {
+		s, ok := schemas[ref]
+		return s, ok
+	}
+
+	var invoked []interface{}
+	registry := NewExtensionRegistry()
+	registry.Register(extOwner, ExtensionHandler{OverridesRef: true})
+	registry.Register(extSensitive, ExtensionHandler{Process: func(node *spec.Schema, value interface{}) (*spec.Schema, error) {
+		invoked = append(invoked, value)
+		result := *node
+		result.Format = "password"
+		return &result, nil
+	}})
+	s, err := PopulateRefsWithOptions(schemaOf, "Root", PopulateRefsOptions{Extensions: registry})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(invoked) != 1 || invoked[0] != true {
+		t.Errorf("expected the handler to be invoked once with the value of the extension, got %v", invoked)
+	}
+	resolvedCredentials := s.Properties["credentials"]
+	if format := resolvedCredentials.Properties["token"].Format; format != "password" {
+		t.Errorf("expected the node to be replaced by the handler, got format %q", format)
+	}
+	if owner, _ := resolvedCredentials.Extensions.GetString(extOwner); owner != "hub" {
+		t.Errorf("expected the extension of the referencing site to take precedence, got %q", owner)
+	}
+	if owner, _ := credentials.Extensions.GetString(extOwner); owner != "agent" || credentials.Properties["token"].Format != "" {
+		t.Errorf("expected the referred schema not to be mutated")
+	}
+
+	// without the registry, the custom extensions are left as they are.
+	s, err = PopulateRefs(schemaOf, "Root")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resolvedCredentials = s.Properties["credentials"]
+	if owner, _ := resolvedCredentials.Extensions.GetString(extOwner); owner != "agent" {
+		t.Errorf("expected the extension of the referred schema, got %q", owner)
+	}
+
+	errRejected := errors.New("rejected")
+	registry.Register(extSensitive, ExtensionHandler{Process: func(*spec.Schema, interface{}) (*spec.Schema, error) {
+		return nil, errRejected
+	}})
+	if _, err := PopulateRefsWithOptions(schemaOf, "Root", PopulateRefsOptions{Extensions: registry}); !errors.Is(err, errRejected) {
+		t.Errorf("expected the error of the handler, got %v", err)
+	}
+}
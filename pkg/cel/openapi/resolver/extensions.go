@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// MapType returns the x-kubernetes-map-type, either "granular" or "atomic",
+// declared for the object or map field at fieldPath of a resolved schema.
+// fieldPath follows the notation of WalkSchema. It returns false if the field
+// does not exist or declares no map type.
+func MapType(s *spec.Schema, fieldPath string) (string, bool) {
+	node, ok := lookupPath(s, fieldPath)
+	if !ok {
+		return "", false
+	}
+	mapType := getXMapType(node)
+	return mapType, len(mapType) > 0
+}
+
+// PropertyOrder returns the names of the properties of an object node in a
+// deterministic order, e.g. for generating documentation or forms. The
+// properties listed by the x-kubernetes-property-order extension of the node,
+// if any, come first in the order of the extension; the remaining properties
+// follow in sorted order.
+func PropertyOrder(s *spec.Schema) []string {
+	result := make([]string, 0, len(s.Properties))
+	listed := sets.New[string]()
+	for _, name := range getXPropertyOrder(s) {
+		if _, ok := s.Properties[name]; ok && !listed.Has(name) {
+			listed.Insert(name)
+			result = append(result, name)
+		}
+	}
+	var rest []string
+	for name := range s.Properties {
+		if !listed.Has(name) {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(result, rest...)
+}
+
+func isExtension(schema *spec.Schema, key string) bool {
+	v, ok := schema.Extensions.GetBool(key)
+	return v && ok
+}
+
+func isXPreserveUnknownFields(schema *spec.Schema) bool {
+	return isExtension(schema, extPreserveUnknownFields)
+}
+
+func getXListType(schema *spec.Schema) string {
+	s, _ := schema.Extensions.GetString(extListType)
+	return s
+}
+
+func getXMapType(schema *spec.Schema) string {
+	s, _ := schema.Extensions.GetString(extMapType)
+	return s
+}
+
+func getXListMapKeys(schema *spec.Schema) []string {
+	mapKeys, ok := schema.Extensions.GetStringSlice(extListMapKeys)
+	if !ok {
+		return nil
+	}
+	return mapKeys
+}
+
+func getXPropertyOrder(schema *spec.Schema) []string {
+	order, ok := schema.Extensions.GetStringSlice(extPropertyOrder)
+	if !ok {
+		return nil
+	}
+	return order
+}
+
+const extPreserveUnknownFields = "x-kubernetes-preserve-unknown-fields"
+const extListType = "x-kubernetes-list-type"
+const extMapType = "x-kubernetes-map-type"
+const extListMapKeys = "x-kubernetes-list-map-keys"
+const extPropertyOrder = "x-kubernetes-property-order"
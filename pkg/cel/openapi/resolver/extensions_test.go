@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestMapType(t *testing.T) {
+	getDefinitions := func(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		selector := *objectSchema(map[string]spec.Schema{
+			"matchLabels": {SchemaProps: spec.SchemaProps{
+				Type:                 []string{"object"},
+				AdditionalProperties: &spec.SchemaOrBool{Allows: true, Schema: &spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"string"}}}},
+			}},
+		})
+		selector.AddExtension(extMapType, "atomic")
+		nodeSelector := spec.Schema{SchemaProps: spec.SchemaProps{
+			AllOf: []spec.Schema{{SchemaProps: spec.SchemaProps{Ref: ref("k8s.io/api/core/v1.NodeSelector")}}},
+		}}
+		nodeSelector.AddExtension(extMapType, "granular")
+		return map[string]common.OpenAPIDefinition{
+			"k8s.io/api/apps/v1.Deployment": {Schema: *objectSchema(map[string]spec.Schema{
+				"spec": *objectSchema(map[string]spec.Schema{
+					"selector":     {SchemaProps: spec.SchemaProps{Ref: ref("k8s.io/apimachinery/pkg/apis/meta/v1.LabelSelector")}},
+					"nodeSelector": nodeSelector,
+				}),
+			})},
+			"k8s.io/apimachinery/pkg/apis/meta/v1.LabelSelector": {Schema: selector},
+			"k8s.io/api/core/v1.NodeSelector":                    {Schema: selector},
+		}
+	}
+	r := NewDefinitionsSchemaResolver(getDefinitions, scheme.Scheme)
+	s, err := r.ResolveSchema(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		path     string
+		expected string
+		ok       bool
+	}{
+		{path: "spec.selector", expected: "atomic", ok: true},
+		// the map type of the referencing site takes precedence.
+		{path: "spec.nodeSelector", expected: "granular", ok: true},
+		{path: "spec.selector.matchLabels"},
+		{path: "spec.missing"},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			mapType, ok := MapType(s, tc.path)
+			if mapType != tc.expected || ok != tc.ok {
+				t.Errorf("expected (%q, %v), got (%q, %v)", tc.expected, tc.ok, mapType, ok)
+			}
+		})
+	}
+	def := r.defs["k8s.io/api/core/v1.NodeSelector"].Schema
+	if getXMapType(&def) != "atomic" {
+		t.Errorf("the referred definition must not be mutated")
+	}
+}
+
+func TestPropertyOrder(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	status := *objectSchema(map[string]spec.Schema{
+		"phase":     scalarSchema("string", ""),
+		"reason":    scalarSchema("string", ""),
+		"completed": scalarSchema("boolean", ""),
+	})
+	status.AddExtension(extPropertyOrder, []any{"phase", "reason"})
+	subscription := withGVK(*objectSchema(map[string]spec.Schema{
+		"apiVersion": scalarSchema("string", ""),
+		"kind":       scalarSchema("string", ""),
+		"metadata":   *objectSchema(nil),
+		"spec":       *objectSchema(nil),
+		"status":     {SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef(refPrefix + "io.clusternet.apis.apps.v1alpha1.SubscriptionStatus")}},
+	}), gvk)
+	// unknown and duplicate names in the hint are ignored.
+	subscription.AddExtension(extPropertyOrder, []any{"kind", "apiVersion", "missing", "spec", "kind"})
+	r := newDocumentsDiscoveryResolver(map[string][]byte{
+		"apis/apps.clusternet.io/v1alpha1": newDocument(map[string]*spec.Schema{
+			"io.clusternet.apis.apps.v1alpha1.Subscription":       &subscription,
+			"io.clusternet.apis.apps.v1alpha1.SubscriptionStatus": &status,
+		}),
+	})
+
+	for i := 0; i < 3; i++ {
+		s, err := r.ResolveSchema(gvk)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, expected := PropertyOrder(s), []string{"kind", "apiVersion", "spec", "metadata", "status"}; !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+		statusSchema := s.Properties["status"]
+		if got, expected := PropertyOrder(&statusSchema), []string{"phase", "reason", "completed"}; !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %v for the referenced type, got %v", expected, got)
+		}
+		specSchema := s.Properties["spec"]
+		if got := PropertyOrder(&specSchema); len(got) != 0 {
+			t.Errorf("expected no properties, got %v", got)
+		}
+	}
+	if got, expected := PropertyOrder(objectSchema(map[string]spec.Schema{"b": {}, "c": {}, "a": {}})), []string{"a", "b", "c"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected sorted order without a hint %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// MergeMode controls how FederatedResolver merges the schemas of a GVK
+// resolved in several clusters.
+type MergeMode int
+
+const (
+	// MergeIntersect keeps only the fields that are present, with the same
+	// type, in every cluster, down to the types of their items and
+	// additionalProperties. CEL rules checked against the result are
+	// portable to all the clusters.
+	MergeIntersect MergeMode = iota
+	// MergeUnion keeps every field that is present in any cluster. On type
+	// conflicts, the field of the first cluster in name order is kept.
+	MergeUnion
+)
+
+// ErrMergeConflict is wrapped and returned by FederatedResolver with
+// MergeIntersect if the root schemas of a GVK cannot be intersected, i.e.
+// their items or additionalProperties differ in type, so that no schema
+// fits every cluster. Fields that conflict this way are dropped instead.
+var ErrMergeConflict = errors.New("conflicting schemas across clusters")
+
+// FederatedResolver resolves the schema of a GVK across several member
+// clusters, each with its own SchemaResolver keyed by the cluster name.
+type FederatedResolver struct {
+	Clusters map[string]SchemaResolver
+}
+
+// FederatedSchema is the result of merging the schemas of a GVK across clusters.
+type FederatedSchema struct {
+	// Schema is the merged schema.
+	Schema *spec.Schema
+	// Clusters are the names of the clusters whose schemas were merged, sorted.
+	Clusters []string
+	// Excluded are the names of the clusters that do not serve the GVK, sorted.
+	Excluded []string
+}
+
+// IntersectResolve resolves the GVK in every cluster and merges the results by
+// intersection. See MergeIntersect.
+func (r *FederatedResolver) IntersectResolve(gvk schema.GroupVersionKind) (*FederatedSchema, error) {
+	return r.ResolveMerged(gvk, MergeIntersect)
+}
+
+// UnionResolve resolves the GVK in every cluster and merges the results by
+// union. See MergeUnion.
+func (r *FederatedResolver) UnionResolve(gvk schema.GroupVersionKind) (*FederatedSchema, error) {
+	return r.ResolveMerged(gvk, MergeUnion)
+}
+
+// ResolveMerged resolves the GVK in every cluster and merges the results
+// according to mode. Clusters where the GVK cannot be found are excluded and
+// reported in the result. If no cluster serves the GVK, the returned error
+// wraps ErrSchemaNotFound. Any other resolution error aborts the merge.
+func (r *FederatedResolver) ResolveMerged(gvk schema.GroupVersionKind, mode MergeMode) (*FederatedSchema, error) {
+	names := make([]string, 0, len(r.Clusters))
+	for name := range r.Clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := &FederatedSchema{}
+	for _, name := range names {
+		s, err := r.Clusters[name].ResolveSchema(gvk)
+		if errors.Is(err, ErrSchemaNotFound) {
+			result.Excluded = append(result.Excluded, name)
+			continue
+		}
+		if err != nil {
+			return nil, wrapClusterError(gvk, name, err)
+		}
+		result.Clusters = append(result.Clusters, name)
+		if result.Schema == nil {
+			result.Schema = s
+			continue
+		}
+		merged, ok := mergeSchemas(result.Schema, s, mode)
+		if !ok {
+			return nil, fmt.Errorf("cannot merge the schema of %v in cluster %q: %w", gvk, name, ErrMergeConflict)
+		}
+		result.Schema = merged
+	}
+	if result.Schema == nil {
+		return nil, fmt.Errorf("cannot resolve %v in any cluster: %w", gvk, ErrSchemaNotFound)
+	}
+	return result, nil
+}
+
+// mergeSchemas merges two schemas of the same node according to mode, without
+// mutating either of them. The types of a and b must match unless mode is
+// MergeUnion, in which case a wins. With MergeIntersect, it returns false if
+// the items or additionalProperties of a and b differ in type, in which case
+// the node fits neither cluster and the field is dropped.
+func mergeSchemas(a, b *spec.Schema, mode MergeMode) (*spec.Schema, bool) {
+	result := *a
+
+	if len(a.Properties) > 0 || len(b.Properties) > 0 {
+		props := make(map[string]spec.Schema, len(a.Properties))
+		for name, pa := range a.Properties {
+			pb, ok := b.Properties[name]
+			switch {
+			case ok && sameType(&pa, &pb):
+				if merged, ok := mergeSchemas(&pa, &pb, mode); ok {
+					props[name] = *merged
+				}
+			case mode == MergeUnion:
+				props[name] = pa
+			}
+		}
+		if mode == MergeUnion {
+			for name, pb := range b.Properties {
+				if _, ok := a.Properties[name]; !ok {
+					props[name] = pb
+				}
+			}
+		}
+		result.Properties = props
+	}
+
+	// a field is only required if it is required everywhere.
+	required := sets.New(a.Required...).Intersection(sets.New(b.Required...))
+	result.Required = nil
+	for _, name := range sets.List(required) {
+		if _, ok := result.Properties[name]; ok {
+			result.Required = append(result.Required, name)
+		}
+	}
+
+	if a.Items != nil && a.Items.Schema != nil && b.Items != nil && b.Items.Schema != nil {
+		items, ok := mergeSubschemas(a.Items.Schema, b.Items.Schema, mode)
+		if !ok {
+			return nil, false
+		}
+		result.Items = &spec.SchemaOrArray{Schema: items}
+	}
+	if a.AdditionalProperties != nil && a.AdditionalProperties.Schema != nil &&
+		b.AdditionalProperties != nil && b.AdditionalProperties.Schema != nil {
+		additionalProperties, ok := mergeSubschemas(a.AdditionalProperties.Schema, b.AdditionalProperties.Schema, mode)
+		if !ok {
+			return nil, false
+		}
+		result.AdditionalProperties = &spec.SchemaOrBool{Allows: true, Schema: additionalProperties}
+	}
+	return &result, true
+}
+
+// mergeSubschemas merges the items or additionalProperties of two schemas
+// like mergeSchemas. With MergeUnion, a is kept if their types differ.
+func mergeSubschemas(a, b *spec.Schema, mode MergeMode) (*spec.Schema, bool) {
+	if sameType(a, b) {
+		return mergeSchemas(a, b, mode)
+	}
+	return a, mode == MergeUnion
+}
+
+func sameType(a, b *spec.Schema) bool {
+	return sets.New(a.Type...).Equal(sets.New(b.Type...)) && a.Format == b.Format
+}
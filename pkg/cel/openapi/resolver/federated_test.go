@@ -0,0 +1,156 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestFederatedResolver(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	r := &FederatedResolver{Clusters: map[string]SchemaResolver{
+		"child-a": staticResolver{gvk: objectSchema(map[string]spec.Schema{
+			"spec": *objectSchema(map[string]spec.Schema{
+				"replicas": scalarSchema("integer", "int32"),
+				"paused":   scalarSchema("boolean", ""),
+			}, "replicas"),
+		})},
+		"child-b": staticResolver{gvk: objectSchema(map[string]spec.Schema{
+			"spec": *objectSchema(map[string]spec.Schema{
+				"replicas": scalarSchema("integer", "int32"),
+			}, "replicas"),
+		})},
+		"child-c": staticResolver{},
+	}}
+
+	for _, tc := range []struct {
+		name      string
+		mode      MergeMode
+		wantProps []string
+	}{
+		{name: "intersect", mode: MergeIntersect, wantProps: []string{"replicas"}},
+		{name: "union", mode: MergeUnion, wantProps: []string{"paused", "replicas"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := r.ResolveMerged(gvk, tc.mode)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := []string{"child-a", "child-b"}; !reflect.DeepEqual(result.Clusters, want) {
+				t.Errorf("expected clusters %v, got %v", want, result.Clusters)
+			}
+			if want := []string{"child-c"}; !reflect.DeepEqual(result.Excluded, want) {
+				t.Errorf("expected excluded clusters %v, got %v", want, result.Excluded)
+			}
+			specSchema := result.Schema.Properties["spec"]
+			var got []string
+			if err := WalkSchema(&specSchema, func(path string, _ *spec.Schema) error {
+				if path != "" {
+					got = append(got, path)
+				}
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.wantProps) {
+				t.Errorf("expected properties %v, got %v", tc.wantProps, got)
+			}
+			if want := []string{"replicas"}; !reflect.DeepEqual(specSchema.Required, want) {
+				t.Errorf("expected required %v, got %v", want, specSchema.Required)
+			}
+		})
+	}
+
+	_, err := r.IntersectResolve(schema.GroupVersionKind{Kind: "Missing"})
+	if !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound, got %v", err)
+	}
+}
+
+func TestFederatedResolverConflicts(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	array := func(items spec.Schema) spec.Schema {
+		return spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"array"}, Items: &spec.SchemaOrArray{Schema: &items}}}
+	}
+	dict := func(values spec.Schema) spec.Schema {
+		return spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"object"}, AdditionalProperties: &spec.SchemaOrBool{Allows: true, Schema: &values}}}
+	}
+	r := &FederatedResolver{Clusters: map[string]SchemaResolver{
+		"child-a": staticResolver{gvk: objectSchema(map[string]spec.Schema{
+			"ports":    array(scalarSchema("integer", "int32")),
+			"labels":   dict(scalarSchema("string", "")),
+			"matrix":   array(array(scalarSchema("integer", "int32"))),
+			"replicas": scalarSchema("integer", "int32"),
+		})},
+		"child-b": staticResolver{gvk: objectSchema(map[string]spec.Schema{
+			"ports":    array(scalarSchema("string", "")),
+			"labels":   dict(scalarSchema("integer", "")),
+			"matrix":   array(array(scalarSchema("string", ""))),
+			"replicas": scalarSchema("integer", "int32"),
+		})},
+	}}
+
+	intersected, err := r.IntersectResolve(gvk)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := sets.List(sets.KeySet(intersected.Schema.Properties)); !reflect.DeepEqual(got, []string{"replicas"}) {
+		t.Errorf("expected the conflicting fields to be dropped, got %v", got)
+	}
+	united, err := r.UnionResolve(gvk)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ports := united.Schema.Properties["ports"]; !ports.Items.Schema.Type.Contains("integer") {
+		t.Errorf("expected the items of the first cluster, got %v", ports.Items.Schema)
+	}
+	if matrix := united.Schema.Properties["matrix"]; !matrix.Items.Schema.Items.Schema.Type.Contains("integer") {
+		t.Errorf("expected the nested items of the first cluster, got %v", matrix.Items.Schema.Items.Schema)
+	}
+
+	// the root cannot be dropped.
+	list := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "List"}
+	ints, strs := array(scalarSchema("integer", "")), array(scalarSchema("string", ""))
+	r = &FederatedResolver{Clusters: map[string]SchemaResolver{
+		"child-a": staticResolver{list: &ints},
+		"child-b": staticResolver{list: &strs},
+	}}
+	if _, err := r.IntersectResolve(list); !errors.Is(err, ErrMergeConflict) {
+		t.Errorf("expected ErrMergeConflict, got %v", err)
+	}
+
+	// other errors abort the merge and name the cluster.
+	errBoom := errors.New("boom")
+	r = &FederatedResolver{Clusters: map[string]SchemaResolver{
+		"child-a": FuncResolver(func(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+			return nil, wrapResolutionError(gvk, errBoom)
+		}),
+	}}
+	_, err = r.IntersectResolve(gvk)
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected the error of the cluster, got %v", err)
+	}
+	if want := `cannot resolve apps.clusternet.io/v1alpha1, Kind=Subscription: cluster "child-a": boom`; err.Error() != want {
+		t.Errorf("expected error %q, got %q", want, err.Error())
+	}
+}
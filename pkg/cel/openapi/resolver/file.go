@@ -0,0 +1,160 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// openAPIFileSuffix is the suffix of the names of the OpenAPI v3 documents in
+// a directory, as prepared in api/openapi-spec/v3 of Kubernetes.
+const openAPIFileSuffix = "_openapi.json"
+
+// FileSchemaResolver resolves schemas from OpenAPI v3 documents on disk, e.g.
+// generated at build time, for deterministic resolution without an
+// apiserver. Each document is read and parsed once, on first use.
+type FileSchemaResolver struct {
+	// files are the paths of the documents by group-version path.
+	files map[string]string
+
+	lock sync.Mutex
+	// documents are the parsed documents by group-version path.
+	documents map[string]*schemaResponse
+}
+
+var _ SchemaResolver = (*FileSchemaResolver)(nil)
+
+// NewFileSchemaResolver creates a FileSchemaResolver over the given files of
+// OpenAPI v3 documents by group-version path, e.g. "apis/apps/v1".
+func NewFileSchemaResolver(files map[string]string) *FileSchemaResolver {
+	return &FileSchemaResolver{files: files, documents: make(map[string]*schemaResponse)}
+}
+
+// NewFileSchemaResolverFromDir creates a FileSchemaResolver over the OpenAPI
+// v3 documents in dir, which are named after their group-version path as in
+// api/openapi-spec/v3 of Kubernetes, e.g. "apis__apps__v1_openapi.json".
+// Other files are ignored.
+func NewFileSchemaResolverFromDir(dir string) (*FileSchemaResolver, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]string)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), openAPIFileSuffix) {
+			continue
+		}
+		path := strings.ReplaceAll(strings.TrimSuffix(e.Name(), openAPIFileSuffix), "__", "/")
+		files[path] = filepath.Join(dir, e.Name())
+	}
+	return NewFileSchemaResolver(files), nil
+}
+
+func (r *FileSchemaResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema unless ctx
+// is already done.
+func (r *FileSchemaResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	if err := checkContext(ctx, gvk); err != nil {
+		return nil, err
+	}
+	resp, err := r.document(resourcePathFromGV(gvk.GroupVersion()))
+	if err != nil {
+		return nil, wrapResolutionError(gvk, err)
+	}
+	return resolveSelfContained(resp, gvk)
+}
+
+// document returns the parsed document of the group-version at
+// resourcePath, reading it if it was not read yet. A document that cannot be
+// read or parsed, or refers to schemas it does not define, is retried on next
+// use.
+func (r *FileSchemaResolver) document(resourcePath string) (*schemaResponse, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if resp, ok := r.documents[resourcePath]; ok {
+		return resp, nil
+	}
+	file, ok := r.files[resourcePath]
+	if !ok {
+		return nil, fmt.Errorf("no file for group version path %q: %w", resourcePath, ErrSchemaNotFound)
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read OpenAPI document %q: %w", file, err)
+	}
+	resp, err := parseDocument(file, b)
+	if err != nil {
+		return nil, err
+	}
+	r.documents[resourcePath] = resp
+	return resp, nil
+}
+
+// parseDocument parses the OpenAPI v3 document b with the given name, and
+// checks that it defines schemas and every schema it refers to.
+func parseDocument(name string, b []byte) (*schemaResponse, error) {
+	resp := new(schemaResponse)
+	if err := json.Unmarshal(b, resp); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document %q: %w", name, err)
+	}
+	if len(resp.Components.Schemas) == 0 {
+		return nil, fmt.Errorf("invalid OpenAPI document %q: no components.schemas", name)
+	}
+	refs := sets.New[string]()
+	for schemaName, s := range resp.Components.Schemas {
+		if s == nil {
+			return nil, fmt.Errorf("invalid OpenAPI document %q: schema %q is null", name, schemaName)
+		}
+		collectRefs(s, refs)
+	}
+	for ref := range refs {
+		if _, ok := resp.Components.Schemas[schemaName(ref)]; !ok {
+			return nil, fmt.Errorf("invalid OpenAPI document %q: Ref %q is not defined", name, ref)
+		}
+	}
+	return resp, nil
+}
+
+// resolveSelfContained resolves the schema of gvk from the parsed document
+// resp, populating its Refs from the same document.
+func resolveSelfContained(resp *schemaResponse, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	ref, err := resolveRef(resp, gvk)
+	if err != nil {
+		return nil, wrapResolutionError(gvk, err)
+	}
+	s, err := PopulateRefs(func(ref string) (*spec.Schema, bool) {
+		s, ok := resp.Components.Schemas[schemaName(ref)]
+		return s, ok
+	}, ref)
+	if err != nil {
+		return nil, wrapResolutionError(gvk, err)
+	}
+	return s, nil
+}
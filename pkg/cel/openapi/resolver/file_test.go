@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/openapi/openapitest"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestFileSchemaResolverFromDir(t *testing.T) {
+	dir := t.TempDir()
+	paths, err := openapitest.NewEmbeddedFileClient().Paths()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, path := range []string{"api/v1", "apis/apps/v1"} {
+		b, err := paths[path].Schema(runtime.ContentTypeJSON)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		name := strings.ReplaceAll(path, "/", "__") + openAPIFileSuffix
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a document"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r, err := NewFileSchemaResolverFromDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected, err := newEmbeddedDiscoveryResolver().ResolveSchema(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, err := r.ResolveSchema(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("expected the same schema as resolved by discovery, got %v", s)
+	}
+	if _, err := r.ResolveSchema(schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound for a group-version without file, got %v", err)
+	}
+	if _, err := r.ResolveSchema(schema.GroupVersionKind{Version: "v1", Kind: "Missing"}); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound for a missing kind, got %v", err)
+	}
+}
+
+func TestFileSchemaResolverInvalidDocuments(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	podSchema := withGVK(*objectSchema(map[string]spec.Schema{
+		"spec": {SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef(refPrefix + "io.k8s.api.core.v1.PodSpec")}},
+	}), pod)
+
+	for _, tc := range []struct {
+		name string
+		doc  []byte
+	}{
+		{name: "not json", doc: []byte("{")},
+		{name: "no schemas", doc: newDocument(nil)},
+		{name: "null schema", doc: []byte(`{"components": {"schemas": {"io.k8s.api.core.v1.Pod": null}}}`)},
+		{name: "undefined ref", doc: newDocument(map[string]*spec.Schema{"io.k8s.api.core.v1.Pod": &podSchema})},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "api__v1_openapi.json")
+			if err := os.WriteFile(file, tc.doc, 0o644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			r := NewFileSchemaResolver(map[string]string{"api/v1": file})
+			_, err := r.ResolveSchema(pod)
+			if err == nil || errors.Is(err, ErrSchemaNotFound) || !strings.Contains(err.Error(), file) {
+				t.Errorf("expected an error naming %q, got %v", file, err)
+			}
+		})
+	}
+}
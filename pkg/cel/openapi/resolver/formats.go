@@ -0,0 +1,156 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// UsedFormats returns the distinct formats, e.g. "date-time" or "int64",
+// declared anywhere in a resolved schema, sorted.
+func UsedFormats(s *spec.Schema) []string {
+	formats := sets.New[string]()
+	_ = WalkSchema(s, func(_ string, node *spec.Schema) error {
+		if len(node.Format) > 0 {
+			formats.Insert(node.Format)
+		}
+		return nil
+	})
+	return sets.List(formats)
+}
+
+// ByteFields returns the sorted paths, in the notation of WalkSchema, of the
+// base64-encoded string fields of a resolved schema, i.e. those with the
+// format "byte", such as the values of the data of a Secret. The CEL type
+// adapter already treats such fields as bytes, so rules that operate on them
+// see the decoded contents; this lists them, e.g. to reject rules that
+// compare them to strings.
+func ByteFields(s *spec.Schema) []string {
+	var paths []string
+	_ = WalkSchema(s, func(path string, node *spec.Schema) error {
+		if node.Format == "byte" {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths
+}
+
+// stringFormats are the known formats of strings that the CEL type adapter
+// maps to other types, e.g. "date-time" to timestamps.
+var stringFormats = sets.New("byte", "date", "date-time", "duration")
+
+// intOrStringFormat is the format of int-or-string fields, as declared by the
+// definitions of intstr.IntOrString.
+const intOrStringFormat = "int-or-string"
+
+// CanonicalizeFormats returns a resolved schema with the formats that CEL
+// treats specially declared consistently, and warnings about the formats that
+// CEL does not know, so that consumers need not re-derive the intent of each
+// representation:
+//   - an int-or-string field, declared with the int-or-string format as by the
+//     built-in types, or with the x-kubernetes-int-or-string extension as by
+//     custom resources, declares both, no type, and its integer and string
+//     alternatives in anyOf, as the structural schemas of custom resources do;
+//   - a field with the byte, date, date-time or duration format and no type is
+//     declared a string.
+//
+// A format that is neither known, as with UnknownFormatResolver, nor in
+// formats, is kept, with a warning of the form `field "<path>": <message>`,
+// in the order of WalkSchema; so is a string format on a field of another
+// type, which CEL ignores. The nodes that need no change are shared with s.
+func CanonicalizeFormats(s *spec.Schema, formats ...string) (*spec.Schema, []string, error) {
+	result, err := transformSchema(s, canonicalizeFormat)
+	if err != nil {
+		return nil, nil, err
+	}
+	known := sets.New(formats...)
+	var warnings []string
+	_ = WalkSchema(result, func(path string, node *spec.Schema) error {
+		switch {
+		case len(node.Format) == 0 || node.Format == intOrStringFormat:
+		case !knownFormats.Has(node.Format) && !known.Has(node.Format):
+			warnings = append(warnings, fmt.Sprintf("field %q: unknown format %q", path, node.Format))
+		case stringFormats.Has(node.Format) && !node.Type.Contains("string"):
+			warnings = append(warnings, fmt.Sprintf("field %q: format %q on a field of type %v", path, node.Format, node.Type))
+		}
+		return nil
+	})
+	return result, warnings, nil
+}
+
+func canonicalizeFormat(s *spec.Schema) (*spec.Schema, error) {
+	if isXIntOrString(s) {
+		return canonicalIntOrString(s), nil
+	}
+	if stringFormats.Has(s.Format) && len(s.Type) == 0 {
+		result := *s
+		result.Type = spec.StringOrArray{"string"}
+		return &result, nil
+	}
+	return s, nil
+}
+
+// isXIntOrString checks if the schema declares an int-or-string field, in any
+// of its representations.
+func isXIntOrString(s *spec.Schema) bool {
+	intOrString, _ := s.Extensions.GetBool(extIntOrString)
+	return intOrString || s.Format == intOrStringFormat
+}
+
+// canonicalIntOrString returns the int-or-string schema s in the canonical
+// form of CanonicalizeFormats.
+func canonicalIntOrString(s *spec.Schema) *spec.Schema {
+	intOrString, _ := s.Extensions.GetBool(extIntOrString)
+	if intOrString && s.Format == intOrStringFormat && len(s.Type) == 0 && len(s.OneOf) == 0 && isIntOrStringUnion(s.AnyOf) {
+		return s
+	}
+	result := *s
+	result.Type = nil
+	result.Format = intOrStringFormat
+	result.Extensions = copyExtensions(s.Extensions)
+	if result.Extensions == nil {
+		result.Extensions = spec.Extensions{}
+	}
+	result.Extensions.Add(extIntOrString, true)
+	switch {
+	case isIntOrStringUnion(s.AnyOf):
+	case len(s.AnyOf) == 0 && isIntOrStringUnion(s.OneOf):
+		// the alternatives are disjoint, so oneOf is equivalent to anyOf.
+		result.AnyOf, result.OneOf = s.OneOf, nil
+	case len(s.AnyOf) == 0:
+		result.AnyOf = []spec.Schema{
+			{SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"integer"}}},
+			{SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"string"}}},
+		}
+	}
+	return &result
+}
+
+// isIntOrStringUnion checks if the alternatives are a typed union of integers
+// and strings.
+func isIntOrStringUnion(alternatives []spec.Schema) bool {
+	for _, alternative := range alternatives {
+		if len(alternative.Type) != 1 || (alternative.Type[0] != "integer" && alternative.Type[0] != "string") {
+			return false
+		}
+	}
+	return len(alternatives) > 0
+}
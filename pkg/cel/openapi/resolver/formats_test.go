@@ -0,0 +1,168 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestUsedFormats(t *testing.T) {
+	s := objectSchema(map[string]spec.Schema{
+		"metadata": *objectSchema(map[string]spec.Schema{
+			"creationTimestamp": scalarSchema("string", "date-time"),
+			"deletionTimestamp": scalarSchema("string", "date-time"),
+			"generation":        scalarSchema("integer", "int64"),
+			"name":              scalarSchema("string", ""),
+		}),
+		"data": {SchemaProps: spec.SchemaProps{
+			Type:                 []string{"object"},
+			AdditionalProperties: &spec.SchemaOrBool{Allows: true, Schema: &spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"string"}, Format: "byte"}}},
+		}},
+		"ports": {SchemaProps: spec.SchemaProps{
+			Type: []string{"array"},
+			Items: &spec.SchemaOrArray{Schema: objectSchema(map[string]spec.Schema{
+				"port":       scalarSchema("integer", "int32"),
+				"targetPort": scalarSchema("string", "int-or-string"),
+			})},
+		}},
+	})
+	expected := []string{"byte", "date-time", "int-or-string", "int32", "int64"}
+	if got := UsedFormats(s); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestByteFields(t *testing.T) {
+	s, err := newEmbeddedDiscoveryResolver().ResolveSchema(schema.GroupVersionKind{Version: "v1", Kind: "Secret"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"data[*]"}
+	if got := ByteFields(s); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	// the format survives resolution along with the type.
+	data := s.Properties["data"].AdditionalProperties.Schema
+	if !reflect.DeepEqual(data.Type, spec.StringOrArray{"string"}) || data.Format != "byte" {
+		t.Errorf("expected a string of format byte, got type %v and format %q", data.Type, data.Format)
+	}
+}
+
+func TestCanonicalizeFormatsOfBuiltinTypes(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	resolved, err := newEmbeddedDiscoveryResolver().ResolveSchema(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, warnings, err := CanonicalizeFormats(resolved)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(warnings) > 0 {
+		t.Errorf("expected the formats of %v to be known, got %v", pod, warnings)
+	}
+
+	// metav1.Time
+	timestamp, ok := lookupPath(s, "metadata.creationTimestamp")
+	if !ok || !reflect.DeepEqual(timestamp.Type, spec.StringOrArray{"string"}) || timestamp.Format != "date-time" {
+		t.Errorf("expected a date-time string, got %v", timestamp)
+	}
+	// intstr.IntOrString
+	port, ok := lookupPath(s, "spec.containers[*].livenessProbe.httpGet.port")
+	if !ok {
+		t.Fatalf("expected the port of the probe")
+	}
+	if intOrString, _ := port.Extensions.GetBool(extIntOrString); !intOrString || port.Format != "int-or-string" || len(port.Type) > 0 {
+		t.Errorf("expected an int-or-string with the extension and no type, got %v", port)
+	}
+	if len(port.OneOf) > 0 || !isIntOrStringUnion(port.AnyOf) {
+		t.Errorf("expected the alternatives in anyOf, got oneOf %v and anyOf %v", port.OneOf, port.AnyOf)
+	}
+	// the resolved schema is not mutated.
+	original, _ := lookupPath(resolved, "spec.containers[*].livenessProbe.httpGet.port")
+	if len(original.OneOf) != 2 || len(original.Extensions) > 0 {
+		t.Errorf("expected the resolved schema to be kept, got %v", original)
+	}
+}
+
+func TestCanonicalizeFormats(t *testing.T) {
+	integer := spec.Schema{SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"integer"}}}
+	str := spec.Schema{SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"string"}}}
+	canonical := spec.Schema{
+		SchemaProps:      spec.SchemaProps{Format: "int-or-string", AnyOf: []spec.Schema{integer, str}},
+		VendorExtensible: spec.VendorExtensible{Extensions: spec.Extensions{extIntOrString: true}},
+	}
+	for _, tc := range []struct {
+		name         string
+		field        spec.Schema
+		formats      []string
+		expected     spec.Schema
+		wantWarnings []string
+	}{
+		{
+			name:     "built-in int-or-string",
+			field:    spec.Schema{SchemaProps: spec.SchemaProps{Format: "int-or-string", OneOf: []spec.Schema{integer, str}}},
+			expected: canonical,
+		},
+		{
+			name:     "v2 int-or-string",
+			field:    scalarSchema("string", "int-or-string"),
+			expected: canonical,
+		},
+		{
+			name: "custom resource int-or-string",
+			field: spec.Schema{
+				SchemaProps:      spec.SchemaProps{AnyOf: []spec.Schema{integer, str}},
+				VendorExtensible: spec.VendorExtensible{Extensions: spec.Extensions{extIntOrString: true}},
+			},
+			expected: canonical,
+		},
+		{name: "canonical int-or-string", field: canonical, expected: canonical},
+		{name: "date-time", field: scalarSchema("string", "date-time"), expected: scalarSchema("string", "date-time")},
+		{name: "untyped byte", field: spec.Schema{SchemaProps: spec.SchemaProps{Format: "byte"}}, expected: scalarSchema("string", "byte")},
+		{
+			name:         "string format of an integer",
+			field:        scalarSchema("integer", "date-time"),
+			expected:     scalarSchema("integer", "date-time"),
+			wantWarnings: []string{`field "field": format "date-time" on a field of type [integer]`},
+		},
+		{
+			name:         "unknown format",
+			field:        scalarSchema("string", "email"),
+			expected:     scalarSchema("string", "email"),
+			wantWarnings: []string{`field "field": unknown format "email"`},
+		},
+		{name: "additional format", field: scalarSchema("string", "email"), formats: []string{"email"}, expected: scalarSchema("string", "email")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, warnings, err := CanonicalizeFormats(objectSchema(map[string]spec.Schema{"field": tc.field}), tc.formats...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := s.Properties["field"]; !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+			if !reflect.DeepEqual(warnings, tc.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tc.wantWarnings, warnings)
+			}
+		})
+	}
+}
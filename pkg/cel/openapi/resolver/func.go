@@ -0,0 +1,44 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// FuncResolver is a SchemaResolver that delegates to a function, which is
+// handy to implement resolution inline, e.g. in adapters and tests.
+type FuncResolver func(gvk schema.GroupVersionKind) (*spec.Schema, error)
+
+var _ SchemaResolver = FuncResolver(nil)
+
+// ResolveSchema calls f(gvk).
+func (f FuncResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return f(gvk)
+}
+
+// ResolveSchemaWithContext calls f(gvk) unless ctx is already done. f itself
+// does not observe ctx.
+func (f FuncResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	if err := checkContext(ctx, gvk); err != nil {
+		return nil, err
+	}
+	return f(gvk)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestFuncResolverInChain(t *testing.T) {
+	builtin := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	custom := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	expected := objectSchema(nil)
+	var calls []schema.GroupVersionKind
+	fn := FuncResolver(func(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+		calls = append(calls, gvk)
+		if gvk.Group == custom.Group {
+			return expected, nil
+		}
+		return nil, fmt.Errorf("cannot resolve %v: %w", gvk, ErrSchemaNotFound)
+	})
+	r := &ChainResolver{Resolvers: []SchemaResolver{
+		staticResolver{builtin: objectSchema(nil)},
+		fn,
+	}}
+
+	if _, err := r.ResolveSchema(builtin); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("expected the FuncResolver not to be called for a GVK resolved earlier in the chain, got %v", calls)
+	}
+	s, err := r.ResolveSchema(custom)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s != expected {
+		t.Errorf("expected the schema of the FuncResolver, got %v", s)
+	}
+	if _, err := r.ResolveSchema(schema.GroupVersionKind{Kind: "Missing"}); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound, got %v", err)
+	}
+}
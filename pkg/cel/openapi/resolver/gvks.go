@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// GVKLister is implemented by resolvers that can enumerate the GVKs they
+// currently resolve, e.g. to warm a cache or to report coverage gaps.
+type GVKLister interface {
+	// GVKs returns the resolvable GVKs, sorted.
+	GVKs() ([]schema.GroupVersionKind, error)
+}
+
+var _ GVKLister = (*DefinitionsSchemaResolver)(nil)
+var _ GVKLister = (*ClientDiscoveryResolver)(nil)
+
+// GVKs returns the GVKs of the definitions, sorted.
+func (d *DefinitionsSchemaResolver) GVKs() ([]schema.GroupVersionKind, error) {
+	result := make([]schema.GroupVersionKind, 0, len(d.gvkToRef))
+	for gvk := range d.gvkToRef {
+		result = append(result, gvk)
+	}
+	sortGVKs(result)
+	return result, nil
+}
+
+// GVKs returns the GVKs of the component schemas of the documents of all
+// group-versions, sorted. Only the GVKs of the group-version of a document
+// are returned, since the shared types, e.g. DeleteOptions, declare the GVKs
+// of all group-versions but resolve only from their own documents. Every
+// document is fetched, within ByteBudget.
+func (r *ClientDiscoveryResolver) GVKs() ([]schema.GroupVersionKind, error) {
+	ctx := context.Background()
+	p, err := r.paths(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := sets.New[schema.GroupVersionKind]()
+	for path := range p {
+		gv, ok := gvFromResourcePath(path)
+		if !ok {
+			continue
+		}
+		resp, _, err := r.document(ctx, p, path, gv)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list the GVKs of %q: %w", gv, err)
+		}
+		resp.indexOnce.Do(resp.buildIndex)
+		if resp.indexErr != nil {
+			return nil, fmt.Errorf("cannot list the GVKs of %q: %w", gv, resp.indexErr)
+		}
+		for gvk := range resp.index {
+			if gvk.GroupVersion() == gv {
+				result.Insert(gvk)
+			}
+		}
+	}
+	gvks := result.UnsortedList()
+	sortGVKs(gvks)
+	return gvks, nil
+}
+
+// sortGVKs sorts the GVKs by their string form.
+func sortGVKs(gvks []schema.GroupVersionKind) {
+	sort.Slice(gvks, func(i, j int) bool {
+		return gvks[i].String() < gvks[j].String()
+	})
+}
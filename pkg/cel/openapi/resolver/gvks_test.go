@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestDefinitionsSchemaResolverGVKs(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	subscription := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	getDefinitions := func(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		return map[string]common.OpenAPIDefinition{
+			"k8s.io/api/core/v1.Pod":     {Schema: *objectSchema(nil)},
+			"k8s.io/api/core/v1.PodSpec": {Schema: *objectSchema(nil)},
+			"github.com/clusternet/clusternet/pkg/apis/apps/v1alpha1.subscription": {Schema: *objectSchema(nil)},
+		}
+	}
+	r := NewDefinitionsSchemaResolverWithOverrides(getDefinitions, map[string]schema.GroupVersionKind{
+		"github.com/clusternet/clusternet/pkg/apis/apps/v1alpha1.subscription": subscription,
+	}, scheme.Scheme)
+
+	gvks, err := r.GVKs()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []schema.GroupVersionKind{pod, subscription}; !reflect.DeepEqual(gvks, expected) {
+		t.Errorf("expected %v, got %v", expected, gvks)
+	}
+}
+
+func TestClientDiscoveryResolverGVKs(t *testing.T) {
+	r := newEmbeddedDiscoveryResolver()
+	gvks, err := r.GVKs()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !sort.SliceIsSorted(gvks, func(i, j int) bool { return gvks[i].String() < gvks[j].String() }) {
+		t.Errorf("expected the GVKs to be sorted, got %v", gvks)
+	}
+	seen := make(map[schema.GroupVersionKind]bool, len(gvks))
+	for _, gvk := range gvks {
+		if seen[gvk] {
+			t.Errorf("expected %v to be listed once", gvk)
+		}
+		seen[gvk] = true
+		// the listed GVKs resolve from the document of their group-version.
+		if _, err := r.ResolveSchema(gvk); err != nil {
+			t.Errorf("expected %v to resolve, got %v", gvk, err)
+		}
+	}
+	for _, gvk := range []schema.GroupVersionKind{
+		{Version: "v1", Kind: "Pod"},
+		{Group: "apps", Version: "v1", Kind: "Deployment"},
+		{Group: "batch", Version: "v1", Kind: "Job"},
+	} {
+		if !seen[gvk] {
+			t.Errorf("expected %v to be listed", gvk)
+		}
+	}
+	// DeleteOptions declares the GVKs of all group-versions, but is only
+	// listed for those that serve a document.
+	if gvk := (schema.GroupVersionKind{Group: "storage.k8s.io", Version: "v1", Kind: "DeleteOptions"}); seen[gvk] {
+		t.Errorf("expected %v not to be listed", gvk)
+	}
+}
+
+func TestClientDiscoveryResolverGVKsDocumentError(t *testing.T) {
+	r := newDocumentsDiscoveryResolver(map[string][]byte{
+		"api/v1":   newDocument(map[string]*spec.Schema{"io.k8s.api.core.v1.Pod": objectSchema(nil)}),
+		"apis/x/y": []byte("not json"),
+	})
+	if _, err := r.GVKs(); err == nil {
+		t.Errorf("expected an error for a malformed document, got none")
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ResolveSchemaForGVR maps the resource to its kind with the resource lists of
+// Discovery and resolves the schema of that kind. A subresource, e.g.
+// "pods/status", resolves to the schema of the kind of its parent resource.
+// The returned error wraps ErrSchemaNotFound if the server does not serve the
+// resource or the subresource, and ErrDiscoveryUnavailable if the resources
+// cannot be listed, as with ResolveSchema.
+func (r *ClientDiscoveryResolver) ResolveSchemaForGVR(gvr schema.GroupVersionResource) (*spec.Schema, error) {
+	gv := gvr.GroupVersion()
+	list, err := r.Discovery.ServerResourcesForGroupVersion(gv.String())
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("cannot resolve resource %v: group version %q is not served: %w", gvr, gv, ErrSchemaNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot list the resources of %q: %w: %w", gv, ErrDiscoveryUnavailable, err)
+	}
+	parent, subresource, isSubresource := strings.Cut(gvr.Resource, "/")
+	var foundParent, foundSubresource bool
+	var gvk schema.GroupVersionKind
+	for _, resource := range list.APIResources {
+		switch resource.Name {
+		case parent:
+			foundParent = true
+			gvk = kindOfResource(gv, resource)
+		case gvr.Resource:
+			foundSubresource = true
+		}
+	}
+	if !foundParent {
+		return nil, fmt.Errorf("cannot resolve resource %v: the resource is not served: %w", gvr, ErrSchemaNotFound)
+	}
+	if isSubresource && !foundSubresource {
+		return nil, fmt.Errorf("cannot resolve resource %v: subresource %q is not served: %w", gvr, subresource, ErrSchemaNotFound)
+	}
+	return r.ResolveSchema(gvk)
+}
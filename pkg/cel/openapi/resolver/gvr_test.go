@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/openapi/openapitest"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestResolveSchemaForGVR(t *testing.T) {
+	d := newFakeDiscovery(openapitest.NewEmbeddedFileClient())
+	d.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "pods", Kind: "Pod", Namespaced: true},
+			{Name: "pods/status", Kind: "Pod", Namespaced: true},
+			{Name: "pods/eviction", Kind: "Eviction", Group: "policy", Version: "v1", Namespaced: true},
+		}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{
+			{Name: "deployments", Kind: "Deployment", Namespaced: true},
+			{Name: "deployments/scale", Kind: "Scale", Group: "autoscaling", Version: "v1", Namespaced: true},
+		}},
+	}
+	r := &ClientDiscoveryResolver{Discovery: d}
+
+	for _, tc := range []struct {
+		name         string
+		gvr          schema.GroupVersionResource
+		expectedKind string
+		expectedErr  error
+	}{
+		{name: "resource", gvr: schema.GroupVersionResource{Version: "v1", Resource: "pods"}, expectedKind: "Pod"},
+		{name: "grouped resource", gvr: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, expectedKind: "Deployment"},
+		{name: "subresource", gvr: schema.GroupVersionResource{Version: "v1", Resource: "pods/status"}, expectedKind: "Pod"},
+		{name: "subresource of another kind", gvr: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments/scale"}, expectedKind: "Deployment"},
+		{name: "unknown resource", gvr: schema.GroupVersionResource{Version: "v1", Resource: "widgets"}, expectedErr: ErrSchemaNotFound},
+		{name: "unknown subresource", gvr: schema.GroupVersionResource{Version: "v1", Resource: "pods/widgets"}, expectedErr: ErrSchemaNotFound},
+		{name: "unknown group version", gvr: schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}, expectedErr: ErrSchemaNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := r.ResolveSchemaForGVR(tc.gvr)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			gvks, err := extensionsToGVKs(s.Extensions)
+			if err != nil || len(gvks) != 1 || gvks[0].Kind != tc.expectedKind {
+				t.Errorf("expected the schema of %s, got the schema of %v", tc.expectedKind, gvks)
+			}
+		})
+	}
+
+	errTransport := errors.New("connection refused")
+	d.PrependReactor("get", "resource", func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errTransport
+	})
+	_, err := r.ResolveSchemaForGVR(schema.GroupVersionResource{Version: "v1", Resource: "pods"})
+	if !errors.Is(err, ErrDiscoveryUnavailable) || !errors.Is(err, errTransport) || errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrDiscoveryUnavailable wrapping the error of the listing, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+)
+
+// NewImpersonatingDiscoveryResolver returns a ClientDiscoveryResolver whose
+// discovery requests are made against the apiserver of config while
+// impersonating the given identity, so that schemas are only resolved if the
+// identity is authorized to read the OpenAPI documents. Any impersonation
+// configured in config is replaced in a copy; config is not mutated. The
+// returned resolver can be further configured before use.
+func NewImpersonatingDiscoveryResolver(config *rest.Config, impersonate rest.ImpersonationConfig) (*ClientDiscoveryResolver, error) {
+	if config == nil {
+		return nil, errors.New("cannot create a discovery client without a rest config")
+	}
+	config = rest.CopyConfig(config)
+	config.Impersonate = impersonate
+	client, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create a discovery client for %q: %w", config.Host, err)
+	}
+	return &ClientDiscoveryResolver{Discovery: client}, nil
+}
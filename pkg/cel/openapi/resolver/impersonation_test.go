@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// recordingTransport serves fixed responses keyed by URL path and records the
+// headers of every request.
+type recordingTransport struct {
+	responses map[string][]byte
+
+	lock    sync.Mutex
+	headers []http.Header
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.lock.Lock()
+	t.headers = append(t.headers, req.Header.Clone())
+	t.lock.Unlock()
+	body, ok := t.responses[req.URL.Path]
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestImpersonatingDiscoveryResolver(t *testing.T) {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	pod := withGVK(*objectSchema(map[string]spec.Schema{"kind": scalarSchema("string", "")}), gvk)
+	transport := &recordingTransport{responses: map[string][]byte{
+		"/openapi/v3":        []byte(`{"paths":{"api/v1":{"serverRelativeURL":"/openapi/v3/api/v1?hash=0123"}}}`),
+		"/openapi/v3/api/v1": newDocument(map[string]*spec.Schema{"io.k8s.api.core.v1.Pod": &pod}),
+	}}
+	config := &rest.Config{
+		Host:        "https://member.example.com",
+		Transport:   transport,
+		Impersonate: rest.ImpersonationConfig{UserName: "replaced"},
+	}
+	r, err := NewImpersonatingDiscoveryResolver(config, rest.ImpersonationConfig{
+		UserName: "system:serviceaccount:clusternet-system:clusternet-hub",
+		Groups:   []string{"system:serviceaccounts", "system:authenticated"},
+		Extra:    map[string][]string{"scopes": {"view"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Impersonate.UserName != "replaced" {
+		t.Errorf("expected config not to be mutated")
+	}
+	if _, err := r.ResolveSchema(gvk); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(transport.headers) == 0 {
+		t.Fatalf("expected discovery requests, got none")
+	}
+	for _, header := range transport.headers {
+		if got, expected := header.Get("Impersonate-User"), "system:serviceaccount:clusternet-system:clusternet-hub"; got != expected {
+			t.Errorf("expected user %q, got %q", expected, got)
+		}
+		if got, expected := header.Values("Impersonate-Group"), []string{"system:serviceaccounts", "system:authenticated"}; !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected groups %v, got %v", expected, got)
+		}
+		if got, expected := header.Values("Impersonate-Extra-Scopes"), []string{"view"}; !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected extra %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestImpersonatingDiscoveryResolverNilConfig(t *testing.T) {
+	if _, err := NewImpersonatingDiscoveryResolver(nil, rest.ImpersonationConfig{UserName: "alice"}); err == nil {
+		t.Errorf("expected an error for a nil config")
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ErrInternedSchemaMutated is wrapped and returned by InterningResolver in
+// debug mode if an interned schema was mutated.
+var ErrInternedSchemaMutated = errors.New("interned schema was mutated")
+
+// InterningResolver wraps a SchemaResolver and deduplicates identical subtrees
+// of the resolved schemas across calls, keyed by their SchemaHash, so that
+// schemas of many kinds that share e.g. ObjectMeta or PodSpec hold a single
+// instance of each shared subtree.
+//
+// The returned schemas are read-only: they share their subtrees with the
+// schemas returned by other calls and must never be mutated. Callers that
+// need to modify a schema must deep copy it first.
+//
+// Interning hashes every subtree of every resolved schema, which makes
+// resolution considerably slower; it is meant for schemas that are retained,
+// e.g. behind a cache. The pool grows with the distinct subtrees resolved and
+// is never pruned.
+type InterningResolver struct {
+	Delegate SchemaResolver
+	// Debug makes the resolver verify, whenever an interned subtree is reused,
+	// that it was not mutated since it was interned. Resolution fails with
+	// ErrInternedSchemaMutated otherwise. This is expensive and meant for
+	// tests.
+	Debug bool
+
+	lock sync.Mutex
+	pool map[string]*spec.Schema
+}
+
+var _ SchemaResolver = (*InterningResolver)(nil)
+
+// ResolveSchema resolves the schema with the delegate and returns it with its
+// subtrees interned. The result must not be mutated.
+func (r *InterningResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema, passing ctx
+// to the delegate.
+func (r *InterningResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	s, err := r.Delegate.ResolveSchemaWithContext(ctx, gvk)
+	if err != nil {
+		return nil, err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.pool == nil {
+		r.pool = make(map[string]*spec.Schema)
+	}
+	return transformSchema(s, r.intern)
+}
+
+// intern returns the pooled instance of s, adding s to the pool if it is not
+// yet pooled. It must be called with the lock held.
+func (r *InterningResolver) intern(s *spec.Schema) (*spec.Schema, error) {
+	hash, err := SchemaHash(s)
+	if err != nil {
+		// schemas that cannot be hashed are not interned.
+		return s, nil
+	}
+	pooled, ok := r.pool[hash]
+	if !ok {
+		r.pool[hash] = s
+		return s, nil
+	}
+	if r.Debug {
+		if current, err := SchemaHash(pooled); err != nil || current != hash {
+			return nil, fmt.Errorf("subtree with hash %s: %w", hash, ErrInternedSchemaMutated)
+		}
+	}
+	return pooled, nil
+}
+
+// Verify checks every interned subtree for mutations and returns an error
+// wrapping ErrInternedSchemaMutated that names the hashes of the mutated
+// subtrees, if any.
+func (r *InterningResolver) Verify() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var mutated []string
+	for hash, s := range r.pool {
+		if current, err := SchemaHash(s); err != nil || current != hash {
+			mutated = append(mutated, hash)
+		}
+	}
+	if len(mutated) == 0 {
+		return nil
+	}
+	sort.Strings(mutated)
+	return fmt.Errorf("subtrees with hashes %s: %w", strings.Join(mutated, ", "), ErrInternedSchemaMutated)
+}
+
+// Len returns the number of distinct subtrees in the pool.
+func (r *InterningResolver) Len() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.pool)
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// workloadKinds are ten kinds of the embedded documents that share ObjectMeta
+// and, except for the last one, PodSpec.
+var workloadKinds = []schema.GroupVersionKind{
+	{Version: "v1", Kind: "Pod"},
+	{Version: "v1", Kind: "PodTemplate"},
+	{Version: "v1", Kind: "ReplicationController"},
+	{Group: "apps", Version: "v1", Kind: "Deployment"},
+	{Group: "apps", Version: "v1", Kind: "StatefulSet"},
+	{Group: "apps", Version: "v1", Kind: "DaemonSet"},
+	{Group: "apps", Version: "v1", Kind: "ReplicaSet"},
+	{Group: "batch", Version: "v1", Kind: "Job"},
+	{Group: "batch", Version: "v1", Kind: "CronJob"},
+	{Group: "apps", Version: "v1", Kind: "ControllerRevision"},
+}
+
+func containersItems(t *testing.T, s *spec.Schema, path string) *spec.Schema {
+	t.Helper()
+	containers, ok := lookupPath(s, path)
+	if !ok || containers.Items == nil || containers.Items.Schema == nil {
+		t.Fatalf("expected an array at %q", path)
+	}
+	return containers.Items.Schema
+}
+
+func TestInterningResolver(t *testing.T) {
+	r := &InterningResolver{Delegate: newEmbeddedDiscoveryResolver(), Debug: true}
+	pod, err := r.ResolveSchema(workloadKinds[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deployment, err := r.ResolveSchema(workloadKinds[3])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if containersItems(t, pod, "spec.containers") != containersItems(t, deployment, "spec.template.spec.containers") {
+		t.Errorf("expected the container schema to be shared between Pod and Deployment")
+	}
+	again, err := r.ResolveSchema(workloadKinds[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again != pod {
+		t.Errorf("expected the same instance when resolving the same kind again")
+	}
+	if err := r.Verify(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// illegally mutate a shared subtree.
+	containersItems(t, pod, "spec.containers").Description = "mutated"
+	if err := r.Verify(); !errors.Is(err, ErrInternedSchemaMutated) {
+		t.Errorf("expected ErrInternedSchemaMutated from Verify, got %v", err)
+	}
+	if _, err := r.ResolveSchema(workloadKinds[4]); !errors.Is(err, ErrInternedSchemaMutated) {
+		t.Errorf("expected ErrInternedSchemaMutated in debug mode, got %v", err)
+	}
+}
+
+// BenchmarkInterningResolver reports the heap retained by the schemas of
+// workloadKinds with and without interning.
+func BenchmarkInterningResolver(b *testing.B) {
+	for _, bc := range []struct {
+		name   string
+		intern bool
+	}{
+		{name: "plain"},
+		{name: "interned", intern: true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			var retained uint64
+			for i := 0; i < b.N; i++ {
+				var r SchemaResolver = newEmbeddedDiscoveryResolver()
+				if bc.intern {
+					r = &InterningResolver{Delegate: r}
+				}
+				before := heapInUse()
+				schemas := make([]*spec.Schema, 0, len(workloadKinds))
+				for _, gvk := range workloadKinds {
+					s, err := r.ResolveSchema(gvk)
+					if err != nil {
+						b.Fatalf("unexpected error: %v", err)
+					}
+					schemas = append(schemas, s)
+				}
+				// drop the pool, retain the schemas only.
+				r = nil
+				if after := heapInUse(); after > before {
+					retained += after - before
+				}
+				runtime.KeepAlive(schemas)
+			}
+			b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+		})
+	}
+}
+
+func heapInUse() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// JSONOptions configures ResolveSchemaJSON.
+type JSONOptions struct {
+	// Pretty indents the JSON with two spaces per level. By default, the JSON
+	// is compact.
+	Pretty bool
+}
+
+// ResolveSchemaJSON resolves the schema of gvk with r and returns its JSON
+// serialization, e.g. for frontends or templating engines. The keys of all
+// objects are sorted, so that the bytes are deterministic and diffs between
+// runs are meaningful, and HTML characters are not escaped.
+func ResolveSchemaJSON(r SchemaResolver, gvk schema.GroupVersionKind, opts JSONOptions) ([]byte, error) {
+	s, err := r.ResolveSchema(gvk)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal schema of %v: %w", gvk, err)
+	}
+	// the schema is decoded generically and encoded again, since
+	// encoding/json sorts the keys of maps, but not the fields of structs.
+	// Numbers are kept as they are rather than converted to float64.
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, fmt.Errorf("cannot marshal schema of %v: %w", gvk, err)
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if opts.Pretty {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(v); err != nil {
+		return nil, fmt.Errorf("cannot marshal schema of %v: %w", gvk, err)
+	}
+	// Encode terminates the value with a newline, which Marshal does not.
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
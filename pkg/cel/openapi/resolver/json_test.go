@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/utils/ptr"
+)
+
+func TestResolveSchemaJSON(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	replicas := scalarSchema("integer", "int64")
+	replicas.Maximum = ptr.To[float64](9007199254740993)
+	replicas.Description = "Replicas is <either> 0 & or 1."
+	r := staticResolver{gvk: objectSchema(map[string]spec.Schema{
+		"zone":     scalarSchema("string", ""),
+		"replicas": replicas,
+		"apps":     scalarSchema("string", ""),
+	}, "zone", "apps")}
+
+	compact, err := ResolveSchemaJSON(r, gvk, JSONOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"properties":{"apps":{"type":"string"},"replicas":{"description":"Replicas is <either> 0 & or 1.","format":"int64","maximum":9007199254740992,"type":"integer"},"zone":{"type":"string"}},"required":["zone","apps"],"type":"object"}`
+	if string(compact) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, compact)
+	}
+	for i := 0; i < 5; i++ {
+		again, err := ResolveSchemaJSON(r, gvk, JSONOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(again, compact) {
+			t.Fatalf("expected deterministic bytes, got\n%s\nand\n%s", compact, again)
+		}
+	}
+
+	pretty, err := ResolveSchemaJSON(r, gvk, JSONOptions{Pretty: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(string(pretty), "{\n  \"properties\": {\n    \"apps\": {") || strings.HasSuffix(string(pretty), "\n") {
+		t.Errorf("expected indented JSON, got\n%s", pretty)
+	}
+	var c, p interface{}
+	if err := json.Unmarshal(compact, &c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := json.Unmarshal(pretty, &p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(c, p) {
+		t.Errorf("expected the same schema in compact and pretty JSON")
+	}
+
+	if _, err := ResolveSchemaJSON(r, schema.GroupVersionKind{Kind: "Missing"}, JSONOptions{}); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ErrUnsupportedConstruct is wrapped and returned by ToJSONSchemaProps if a
+// schema uses a construct that CRD validation schemas cannot express.
+var ErrUnsupportedConstruct = errors.New("unsupported construct")
+
+const extIntOrString = "x-kubernetes-int-or-string"
+const extEmbeddedResource = "x-kubernetes-embedded-resource"
+const extValidations = "x-kubernetes-validations"
+
+// jsonSchemaPropsExtensions are the extensions that JSONSchemaProps carries,
+// as its XPreserveUnknownFields, XEmbeddedResource, XIntOrString, XListType,
+// XListMapKeys, XMapType and XValidations fields.
+var jsonSchemaPropsExtensions = sets.New(
+	extPreserveUnknownFields,
+	extEmbeddedResource,
+	extIntOrString,
+	extListType,
+	extListMapKeys,
+	extMapType,
+	extValidations,
+)
+
+// ToJSONSchemaProps converts a resolved schema into the shape of the
+// apiextensions.k8s.io/v1 JSONSchemaProps of CRD validation schemas, e.g. to
+// synthesize a CRD whose schema mirrors a built-in type. The result is
+// returned in its unstructured form, since this module cannot depend on
+// k8s.io/apiextensions-apiserver; it decodes into JSONSchemaProps with
+// runtime.DefaultUnstructuredConverter.
+//
+// The x-kubernetes extensions that JSONSchemaProps supports are carried over
+// and the other extensions, such as the patch strategies, are dropped. Fields
+// of the int-or-string format are converted into x-kubernetes-int-or-string.
+// Unresolved Refs, multiple types and tuple items are not supported.
+func ToJSONSchemaProps(s *spec.Schema) (map[string]interface{}, error) {
+	refs := sets.New[string]()
+	collectRefs(s, refs)
+	if refs.Len() > 0 {
+		return nil, fmt.Errorf("%w: unresolved refs %v", ErrUnsupportedConstruct, sets.List(refs))
+	}
+	err := WalkSchema(s, func(path string, node *spec.Schema) error {
+		if len(node.Type) > 1 {
+			return fmt.Errorf("field %q: %w: multiple types %v", path, ErrUnsupportedConstruct, node.Type)
+		}
+		if node.Items != nil && len(node.Items.Schemas) > 0 {
+			return fmt.Errorf("field %q: %w: tuple items", path, ErrUnsupportedConstruct)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	converted, err := transformSchema(s, func(s *spec.Schema) (*spec.Schema, error) {
+		result := *s
+		result.Extensions = nil
+		for key, value := range s.Extensions {
+			if jsonSchemaPropsExtensions.Has(strings.ToLower(key)) {
+				result.AddExtension(key, value)
+			}
+		}
+		if s.Format == "int-or-string" {
+			// CRDs express int-or-string as an extension rather than as a
+			// format, and without a type.
+			result.Type = nil
+			result.Format = ""
+			result.OneOf = nil
+			result.AnyOf = nil
+			result.AddExtension(extIntOrString, true)
+		}
+		// these are only defined by OpenAPI, not by JSONSchemaProps.
+		result.ReadOnly = false
+		result.Discriminator = ""
+		return &result, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(converted)
+	if err != nil {
+		return nil, err
+	}
+	var props map[string]interface{}
+	if err := json.Unmarshal(b, &props); err != nil {
+		return nil, err
+	}
+	return props, nil
+}
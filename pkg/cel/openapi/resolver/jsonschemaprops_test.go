@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestToJSONSchemaPropsOfPodContainers(t *testing.T) {
+	r := newEmbeddedDiscoveryResolver()
+	pod, err := r.ResolveSchema(schema.GroupVersionKind{Version: "v1", Kind: "Pod"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	containers := pod.Properties["spec"].Properties["containers"]
+	props, err := ToJSONSchemaProps(&containers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		path     []string
+		expected interface{}
+	}{
+		{name: "type", path: []string{"type"}, expected: "array"},
+		{name: "item type", path: []string{"items", "type"}, expected: "object"},
+		{name: "required", path: []string{"items", "required"}, expected: []interface{}{"name"}},
+		{name: "nested list type", path: []string{"items", "properties", "ports", "x-kubernetes-list-type"}, expected: "map"},
+		{
+			name:     "nested list map keys",
+			path:     []string{"items", "properties", "ports", "x-kubernetes-list-map-keys"},
+			expected: []interface{}{"containerPort", "protocol"},
+		},
+		{
+			name:     "int or string",
+			path:     []string{"items", "properties", "livenessProbe", "properties", "httpGet", "properties", "port", "x-kubernetes-int-or-string"},
+			expected: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, found, err := unstructured.NestedFieldNoCopy(props, tc.path...)
+			if err != nil || !found {
+				t.Fatalf("expected %v to be set, got found %v, error %v", tc.path, found, err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+
+	port, _, _ := unstructured.NestedMap(props, "items", "properties", "livenessProbe", "properties", "httpGet", "properties", "port")
+	for _, field := range []string{"type", "format", "oneOf"} {
+		if _, ok := port[field]; ok {
+			t.Errorf("expected the int-or-string port not to declare %q, got %v", field, port)
+		}
+	}
+	ports, _, _ := unstructured.NestedMap(props, "items", "properties", "ports")
+	for _, ext := range []string{"x-kubernetes-patch-merge-key", "x-kubernetes-patch-strategy"} {
+		if _, ok := ports[ext]; ok {
+			t.Errorf("expected %q to be dropped, got %v", ext, ports)
+		}
+	}
+}
+
+func TestToJSONSchemaPropsUnsupported(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		schema *spec.Schema
+	}{
+		{
+			name: "unresolved ref",
+			schema: objectSchema(map[string]spec.Schema{
+				"spec": {SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef("#/components/schemas/io.k8s.api.core.v1.PodSpec")}},
+			}),
+		},
+		{
+			name: "multiple types",
+			schema: objectSchema(map[string]spec.Schema{
+				"value": {SchemaProps: spec.SchemaProps{Type: []string{"string", "integer"}}},
+			}),
+		},
+		{
+			name: "tuple items",
+			schema: &spec.Schema{SchemaProps: spec.SchemaProps{
+				Type:  []string{"array"},
+				Items: &spec.SchemaOrArray{Schemas: []spec.Schema{scalarSchema("string", ""), scalarSchema("integer", "")}},
+			}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ToJSONSchemaProps(tc.schema); !errors.Is(err, ErrUnsupportedConstruct) {
+				t.Errorf("expected ErrUnsupportedConstruct, got %v", err)
+			}
+		})
+	}
+}
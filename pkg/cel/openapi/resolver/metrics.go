@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"time"
+
+	"k8s.io/component-base/metrics"
+)
+
+const (
+	metricsNamespace = "apiserver"
+	metricsSubsystem = "cel_openapi_resolver"
+)
+
+// ResolveResult is the outcome of a resolution, as observed by Metrics.
+type ResolveResult string
+
+const (
+	// ResolveResultHit is the result of a resolution that returned a schema.
+	ResolveResultHit ResolveResult = "hit"
+	// ResolveResultNotFound is the result of a resolution that failed with
+	// ErrSchemaNotFound.
+	ResolveResultNotFound ResolveResult = "not_found"
+	// ResolveResultError is the result of a resolution that failed otherwise.
+	ResolveResultError ResolveResult = "error"
+)
+
+// Metrics observes the resolutions of the resolvers whose Metrics field is
+// set. Implementations must be safe for concurrent use. NewMetrics returns
+// an implementation that records Prometheus metrics.
+type Metrics interface {
+	// ObserveResolve records a resolution with its result and the time it
+	// took.
+	ObserveResolve(result ResolveResult, elapsed time.Duration)
+	// ObserveCacheLookup records a lookup of a schema in the cache of a
+	// CachingResolver.
+	ObserveCacheLookup(hit bool)
+}
+
+// NewMetrics creates the metrics of schema resolution and registers them with
+// registry, which must happen at most once per registry. If registry is nil,
+// the returned Metrics records nothing.
+func NewMetrics(registry metrics.KubeRegistry) Metrics {
+	if registry == nil {
+		return noopMetrics{}
+	}
+	m := &resolverMetrics{
+		resolutions: metrics.NewCounterVec(&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "resolutions_total",
+			Help:           "Number of schema resolutions, by result.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"result"}),
+		resolutionDuration: metrics.NewHistogramVec(&metrics.HistogramOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "resolution_duration_seconds",
+			Help:           "Schema resolution latency in seconds, by result.",
+			Buckets:        metrics.ExponentialBuckets(0.001, 4, 8),
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"result"}),
+		cacheLookups: metrics.NewCounterVec(&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "cache_lookups_total",
+			Help:           "Number of lookups in the schema cache, by result.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"result"}),
+	}
+	registry.MustRegister(m.resolutions, m.resolutionDuration, m.cacheLookups)
+	return m
+}
+
+type resolverMetrics struct {
+	resolutions        *metrics.CounterVec
+	resolutionDuration *metrics.HistogramVec
+	cacheLookups       *metrics.CounterVec
+}
+
+func (m *resolverMetrics) ObserveResolve(result ResolveResult, elapsed time.Duration) {
+	m.resolutions.WithLabelValues(string(result)).Inc()
+	m.resolutionDuration.WithLabelValues(string(result)).Observe(elapsed.Seconds())
+}
+
+func (m *resolverMetrics) ObserveCacheLookup(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheLookups.WithLabelValues(result).Inc()
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveResolve(ResolveResult, time.Duration) {}
+
+func (noopMetrics) ObserveCacheLookup(bool) {}
+
+// observeResolve records the resolution that started at start and failed
+// with err, if not nil, with m, if not nil.
+func observeResolve(m Metrics, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	result := ResolveResultHit
+	switch {
+	case errors.Is(err, ErrSchemaNotFound):
+		result = ResolveResultNotFound
+	case err != nil:
+		result = ResolveResultError
+	}
+	m.ObserveResolve(result, time.Since(start))
+}
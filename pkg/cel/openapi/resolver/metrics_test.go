@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := metrics.NewKubeRegistry()
+	m := NewMetrics(registry)
+	discovery := newEmbeddedDiscoveryResolver()
+	discovery.Metrics = m
+	r := NewCachingResolver(discovery)
+	r.Metrics = m
+
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	for _, gvk := range []schema.GroupVersionKind{pod, pod, {Version: "v1", Kind: "Missing"}} {
+		_, _ = r.ResolveSchema(gvk)
+	}
+	// resolving a group-version that is not served fails with
+	// ErrSchemaNotFound, too.
+	_, _ = discovery.ResolveSchema(schema.GroupVersionKind{Group: "missing.example.com", Version: "v1", Kind: "Missing"})
+
+	expected := `
+# HELP apiserver_cel_openapi_resolver_cache_lookups_total [ALPHA] Number of lookups in the schema cache, by result.
+# TYPE apiserver_cel_openapi_resolver_cache_lookups_total counter
+apiserver_cel_openapi_resolver_cache_lookups_total{result="hit"} 1
+apiserver_cel_openapi_resolver_cache_lookups_total{result="miss"} 2
+# HELP apiserver_cel_openapi_resolver_resolutions_total [ALPHA] Number of schema resolutions, by result.
+# TYPE apiserver_cel_openapi_resolver_resolutions_total counter
+apiserver_cel_openapi_resolver_resolutions_total{result="hit"} 1
+apiserver_cel_openapi_resolver_resolutions_total{result="not_found"} 2
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"apiserver_cel_openapi_resolver_cache_lookups_total",
+		"apiserver_cel_openapi_resolver_resolutions_total",
+	); err != nil {
+		t.Error(err)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var observed uint64
+	for _, family := range families {
+		if family.GetName() != "apiserver_cel_openapi_resolver_resolution_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			observed += metric.GetHistogram().GetSampleCount()
+		}
+	}
+	if observed != 3 {
+		t.Errorf("expected the latency of 3 resolutions to be observed, got %d", observed)
+	}
+}
+
+func TestNewMetricsWithoutRegistry(t *testing.T) {
+	m := NewMetrics(nil)
+	if _, ok := m.(noopMetrics); !ok {
+		t.Errorf("expected no-op metrics without a registry, got %T", m)
+	}
+	r := newEmbeddedDiscoveryResolver()
+	r.Metrics = m
+	if _, err := r.ResolveSchema(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/parser"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ResolveSchemaForPaths resolves the schema of the given GVK and prunes it to
+// the given paths, in the notation of WalkSchema. The complete subtree below
+// each path is retained, as are the nodes leading to it; everything else is
+// dropped. A path that is empty retains the whole schema. Paths that do not
+// exist in the schema are ignored.
+func ResolveSchemaForPaths(r SchemaResolver, gvk schema.GroupVersionKind, paths []string) (*spec.Schema, error) {
+	s, err := r.ResolveSchema(gvk)
+	if err != nil {
+		return nil, err
+	}
+	tree := &pathTree{}
+	for _, path := range paths {
+		tree.add(splitPath(path))
+	}
+	return pruneToPaths(s, tree), nil
+}
+
+// ResolveMinimalForExpressions resolves the schema of the given GVK pruned to
+// the fields referenced by the given CEL expressions through the object and
+// oldObject variables, so that a type environment built from it covers
+// exactly what the expressions may access. A field that is used as a whole,
+// e.g. as the range of a macro or as a function argument, is retained
+// completely. If any expression references object or oldObject itself, the
+// full schema is returned.
+func ResolveMinimalForExpressions(r SchemaResolver, gvk schema.GroupVersionKind, expressions []string) (*spec.Schema, error) {
+	tree := &pathTree{}
+	for _, expression := range expressions {
+		parsed, errs := parser.Parse(common.NewTextSource(expression))
+		if len(errs.GetErrors()) > 0 {
+			return nil, fmt.Errorf("failed to parse expression %q: %s", expression, errs.ToDisplayString())
+		}
+		collectObjectReferences(parsed.Expr(), tree)
+	}
+	s, err := r.ResolveSchema(gvk)
+	if err != nil {
+		return nil, err
+	}
+	return pruneToPaths(s, tree), nil
+}
+
+// objectVariables are the CEL variables that are bound to an object of the
+// resolved kind.
+var objectVariables = map[string]bool{"object": true, "oldObject": true}
+
+// collectObjectReferences adds to tree the paths of the longest field
+// selections rooted at one of the objectVariables within e.
+func collectObjectReferences(e ast.Expr, tree *pathTree) {
+	if path, ok := objectPath(e, tree); ok {
+		tree.add(path)
+		return
+	}
+	switch e.Kind() {
+	case ast.SelectKind:
+		collectObjectReferences(e.AsSelect().Operand(), tree)
+	case ast.CallKind:
+		call := e.AsCall()
+		if call.IsMemberFunction() {
+			collectObjectReferences(call.Target(), tree)
+		}
+		for _, arg := range call.Args() {
+			collectObjectReferences(arg, tree)
+		}
+	case ast.ComprehensionKind:
+		comprehension := e.AsComprehension()
+		collectObjectReferences(comprehension.IterRange(), tree)
+		collectObjectReferences(comprehension.AccuInit(), tree)
+		collectObjectReferences(comprehension.LoopCondition(), tree)
+		collectObjectReferences(comprehension.LoopStep(), tree)
+		collectObjectReferences(comprehension.Result(), tree)
+	case ast.ListKind:
+		for _, element := range e.AsList().Elements() {
+			collectObjectReferences(element, tree)
+		}
+	case ast.MapKind:
+		for _, entry := range e.AsMap().Entries() {
+			collectObjectReferences(entry.AsMapEntry().Key(), tree)
+			collectObjectReferences(entry.AsMapEntry().Value(), tree)
+		}
+	case ast.StructKind:
+		for _, field := range e.AsStruct().Fields() {
+			collectObjectReferences(field.AsStructField().Value(), tree)
+		}
+	}
+}
+
+// objectPath returns the path of e if it is a chain of field selections and
+// indexing rooted at one of the objectVariables. References within the index
+// expressions of the chain are added to tree.
+func objectPath(e ast.Expr, tree *pathTree) ([]string, bool) {
+	switch e.Kind() {
+	case ast.IdentKind:
+		return nil, objectVariables[e.AsIdent()]
+	case ast.SelectKind:
+		sel := e.AsSelect()
+		path, ok := objectPath(sel.Operand(), tree)
+		if !ok {
+			return nil, false
+		}
+		return append(path, sel.FieldName()), true
+	case ast.CallKind:
+		call := e.AsCall()
+		if call.FunctionName() != operators.Index || len(call.Args()) != 2 {
+			return nil, false
+		}
+		path, ok := objectPath(call.Args()[0], tree)
+		if !ok {
+			return nil, false
+		}
+		collectObjectReferences(call.Args()[1], tree)
+		return append(path, "[*]"), true
+	}
+	return nil, false
+}
+
+// pathTree is a set of paths, where a node that is all retains its complete
+// subtree.
+type pathTree struct {
+	all      bool
+	children map[string]*pathTree
+}
+
+func (t *pathTree) add(path []string) {
+	for _, segment := range path {
+		if t.all {
+			return
+		}
+		if t.children == nil {
+			t.children = map[string]*pathTree{}
+		}
+		child, ok := t.children[segment]
+		if !ok {
+			child = &pathTree{}
+			t.children[segment] = child
+		}
+		t = child
+	}
+	t.all = true
+	t.children = nil
+}
+
+// splitPath splits a path in the notation of WalkSchema into its segments.
+func splitPath(path string) []string {
+	if len(path) == 0 {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(path, "[*]", ".[*]"), ".")
+}
+
+// merge adds the paths of other to t.
+func (t *pathTree) merge(other *pathTree) {
+	if t.all {
+		return
+	}
+	if other.all {
+		t.all = true
+		t.children = nil
+		return
+	}
+	for segment, child := range other.children {
+		if t.children == nil {
+			t.children = map[string]*pathTree{}
+		}
+		if existing, ok := t.children[segment]; ok {
+			existing.merge(child)
+			continue
+		}
+		t.children[segment] = child
+	}
+}
+
+// pruneToPaths returns a copy of s that retains only the nodes in tree. The
+// retained subtrees are shared with s. A segment that is not a property
+// selects the additionalProperties, like a key of a map, as with
+// ResolveSubSchema.
+func pruneToPaths(s *spec.Schema, tree *pathTree) *spec.Schema {
+	if tree.all {
+		return s
+	}
+	result := *s
+	result.Properties = nil
+	result.Required = nil
+	result.AdditionalProperties = nil
+	result.Items = nil
+	hasAdditionalProperties := s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil
+	// the keys of a map all select its additionalProperties, whose paths
+	// are merged.
+	var values *pathTree
+	for segment, child := range tree.children {
+		prop, isProperty := s.Properties[segment]
+		if segment == "[*]" && s.Items != nil && s.Items.Schema != nil {
+			result.Items = &spec.SchemaOrArray{Schema: pruneToPaths(s.Items.Schema, child)}
+		}
+		if segment == "[*]" || !isProperty {
+			if hasAdditionalProperties {
+				if values == nil {
+					values = &pathTree{}
+				}
+				values.merge(child)
+			}
+			continue
+		}
+		if result.Properties == nil {
+			result.Properties = map[string]spec.Schema{}
+		}
+		result.Properties[segment] = *pruneToPaths(&prop, child)
+	}
+	if values != nil {
+		result.AdditionalProperties = &spec.SchemaOrBool{
+			Allows: true,
+			Schema: pruneToPaths(s.AdditionalProperties.Schema, values),
+		}
+	}
+	for _, name := range s.Required {
+		if _, ok := result.Properties[name]; ok {
+			result.Required = append(result.Required, name)
+		}
+	}
+	return &result
+}
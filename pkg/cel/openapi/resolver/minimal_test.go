@@ -0,0 +1,167 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestResolveMinimalForExpressions(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	container := objectSchema(map[string]spec.Schema{
+		"image": scalarSchema("string", ""),
+		"name":  scalarSchema("string", ""),
+	}, "name")
+	r := staticResolver{gvk: objectSchema(map[string]spec.Schema{
+		"metadata": *objectSchema(map[string]spec.Schema{
+			"name": scalarSchema("string", ""),
+			"labels": {SchemaProps: spec.SchemaProps{
+				Type:                 []string{"object"},
+				AdditionalProperties: &spec.SchemaOrBool{Allows: true, Schema: &spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"string"}}}},
+			}},
+		}),
+		"spec": *objectSchema(map[string]spec.Schema{
+			"replicas": scalarSchema("integer", "int32"),
+			"paused":   scalarSchema("boolean", ""),
+			"containers": {SchemaProps: spec.SchemaProps{
+				Type:  []string{"array"},
+				Items: &spec.SchemaOrArray{Schema: container},
+			}},
+		}, "containers"),
+	})}
+	for _, tc := range []struct {
+		name        string
+		expressions []string
+		expected    []string
+	}{
+		{
+			name: "two programs",
+			expressions: []string{
+				"object.spec.replicas > 1",
+				"object.metadata.labels['app'] == 'web'",
+			},
+			expected: []string{"", "metadata", "metadata.labels", "metadata.labels[*]", "spec", "spec.replicas"},
+		},
+		{
+			name: "map key selection",
+			expressions: []string{
+				"object.metadata.labels.app == 'x' && object.metadata.labels.tier != ''",
+			},
+			expected: []string{"", "metadata", "metadata.labels", "metadata.labels[*]"},
+		},
+		{
+			name: "macro over field",
+			expressions: []string{
+				"object.spec.containers.all(c, c.image != '')",
+			},
+			expected: []string{"", "spec", "spec.containers", "spec.containers[*]", "spec.containers[*].image", "spec.containers[*].name"},
+		},
+		{
+			name: "index and old object",
+			expressions: []string{
+				"object.spec.containers[0].image == oldObject.spec.containers[0].image && has(object.spec.paused)",
+			},
+			expected: []string{"", "spec", "spec.containers", "spec.containers[*]", "spec.containers[*].image", "spec.paused"},
+		},
+		{
+			name: "other variables",
+			expressions: []string{
+				"params.spec.replicas == request.name",
+			},
+			expected: []string{""},
+		},
+		{
+			name: "whole object",
+			expressions: []string{
+				"object.spec.replicas > 1",
+				"object == oldObject",
+			},
+			expected: []string{"", "metadata", "metadata.labels", "metadata.labels[*]", "metadata.name",
+				"spec", "spec.containers", "spec.containers[*]", "spec.containers[*].image", "spec.containers[*].name", "spec.paused", "spec.replicas"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := ResolveMinimalForExpressions(r, gvk, tc.expressions)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var paths []string
+			_ = WalkSchema(s, func(path string, _ *spec.Schema) error {
+				paths = append(paths, path)
+				return nil
+			})
+			if !reflect.DeepEqual(paths, tc.expected) {
+				t.Errorf("expected paths %v, got %v", tc.expected, paths)
+			}
+		})
+	}
+}
+
+func TestResolveMinimalForExpressionsRequired(t *testing.T) {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	r := staticResolver{gvk: objectSchema(map[string]spec.Schema{
+		"spec": *objectSchema(map[string]spec.Schema{
+			"containers": scalarSchema("string", ""),
+			"hostname":   scalarSchema("string", ""),
+		}, "containers"),
+	}, "spec")}
+	s, err := ResolveMinimalForExpressions(r, gvk, []string{"object.spec.hostname == 'a'"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"spec"}; !reflect.DeepEqual(s.Required, expected) {
+		t.Errorf("expected required %v, got %v", expected, s.Required)
+	}
+	if specSchema := s.Properties["spec"]; len(specSchema.Required) != 0 {
+		t.Errorf("expected pruned required fields to be dropped, got %v", specSchema.Required)
+	}
+	if _, ok := r[gvk].Properties["spec"].Properties["containers"]; !ok {
+		t.Errorf("expected resolved schema not to be mutated")
+	}
+}
+
+func TestResolveMinimalForExpressionsParseError(t *testing.T) {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	r := staticResolver{gvk: objectSchema(nil)}
+	if _, err := ResolveMinimalForExpressions(r, gvk, []string{"object.spec."}); err == nil {
+		t.Errorf("expected parse error, got nil")
+	}
+}
+
+func TestResolveSchemaForPaths(t *testing.T) {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	r := staticResolver{gvk: objectSchema(map[string]spec.Schema{
+		"data":     scalarSchema("string", ""),
+		"metadata": *objectSchema(map[string]spec.Schema{"name": scalarSchema("string", ""), "uid": scalarSchema("string", "")}),
+	})}
+	s, err := ResolveSchemaForPaths(r, gvk, []string{"metadata.name", "missing.field"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var paths []string
+	_ = WalkSchema(s, func(path string, _ *spec.Schema) error {
+		paths = append(paths, path)
+		return nil
+	})
+	if expected := []string{"", "metadata", "metadata.name"}; !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected paths %v, got %v", expected, paths)
+	}
+}
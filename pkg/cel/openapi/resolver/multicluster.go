@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ErrClusterNotRegistered is wrapped and returned by MultiClusterResolver if
+// the cluster has no registered resolver. It is distinct from
+// ErrSchemaNotFound, which is returned if the resolver of a registered
+// cluster cannot find the GVK.
+var ErrClusterNotRegistered = errors.New("cluster not registered")
+
+// MultiClusterResolver holds a SchemaResolver per cluster, keyed by the
+// cluster name, e.g. a ClientDiscoveryResolver for every child cluster
+// managed by clusternet. Resolvers can be registered and unregistered while
+// the MultiClusterResolver is in use, as clusters join and leave.
+type MultiClusterResolver struct {
+	lock      sync.RWMutex
+	resolvers map[string]SchemaResolver
+}
+
+// NewMultiClusterResolver creates an empty MultiClusterResolver.
+func NewMultiClusterResolver() *MultiClusterResolver {
+	return &MultiClusterResolver{resolvers: make(map[string]SchemaResolver)}
+}
+
+// Register sets the resolver of the cluster, replacing any previous one.
+func (r *MultiClusterResolver) Register(cluster string, resolver SchemaResolver) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.resolvers[cluster] = resolver
+}
+
+// Unregister removes the resolver of the cluster, if any. Resolutions for the
+// cluster that are in flight complete with the removed resolver.
+func (r *MultiClusterResolver) Unregister(cluster string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.resolvers, cluster)
+}
+
+// Resolver returns the resolver of the cluster, if registered.
+func (r *MultiClusterResolver) Resolver(cluster string) (SchemaResolver, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	resolver, ok := r.resolvers[cluster]
+	return resolver, ok
+}
+
+// Clusters returns the names of the registered clusters, sorted.
+func (r *MultiClusterResolver) Clusters() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	result := make([]string, 0, len(r.resolvers))
+	for cluster := range r.resolvers {
+		result = append(result, cluster)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ResolveSchemaForCluster resolves the schema of the GVK with the resolver
+// of the cluster. The returned error wraps ErrClusterNotRegistered if the
+// cluster is not registered, and ErrSchemaNotFound if the cluster does not
+// serve the GVK.
+func (r *MultiClusterResolver) ResolveSchemaForCluster(cluster string, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaForClusterWithContext(context.Background(), cluster, gvk)
+}
+
+// ResolveSchemaForClusterWithContext resolves the schema like
+// ResolveSchemaForCluster, passing ctx to the resolver of the cluster.
+func (r *MultiClusterResolver) ResolveSchemaForClusterWithContext(ctx context.Context, cluster string, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	resolver, ok := r.Resolver(cluster)
+	if !ok {
+		return nil, wrapClusterError(gvk, cluster, ErrClusterNotRegistered)
+	}
+	s, err := resolver.ResolveSchemaWithContext(ctx, gvk)
+	if err != nil {
+		return nil, wrapClusterError(gvk, cluster, err)
+	}
+	return s, nil
+}
+
+// wrapClusterError returns err of the resolution of gvk in cluster as
+// "cannot resolve <gvk>: cluster <cluster>: ", without repeating the prefix
+// that the resolver of the cluster already added.
+func wrapClusterError(gvk schema.GroupVersionKind, cluster string, err error) error {
+	return wrapResolutionError(gvk, fmt.Errorf("cluster %q: %w", cluster, unwrapResolutionError(gvk, err)))
+}
+
+// Federated returns a FederatedResolver over the clusters registered at the
+// time of the call, to merge the schemas of a GVK across them.
+func (r *MultiClusterResolver) Federated() *FederatedResolver {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	clusters := make(map[string]SchemaResolver, len(r.resolvers))
+	for cluster, resolver := range r.resolvers {
+		clusters[cluster] = resolver
+	}
+	return &FederatedResolver{Clusters: clusters}
+}
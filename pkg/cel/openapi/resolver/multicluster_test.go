@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestMultiClusterResolver(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	expected := objectSchema(map[string]spec.Schema{"spec": *objectSchema(nil)})
+	r := NewMultiClusterResolver()
+	r.Register("child-a", staticResolver{gvk: expected})
+	r.Register("child-b", staticResolver{})
+
+	for _, tc := range []struct {
+		name    string
+		cluster string
+		wantErr error
+		wantMsg string
+	}{
+		{name: "resolved", cluster: "child-a"},
+		{
+			name:    "GVK not found",
+			cluster: "child-b",
+			wantErr: ErrSchemaNotFound,
+			wantMsg: `cannot resolve apps.clusternet.io/v1alpha1, Kind=Subscription: cluster "child-b": schema not found`,
+		},
+		{
+			name:    "cluster not registered",
+			cluster: "child-c",
+			wantErr: ErrClusterNotRegistered,
+			wantMsg: `cannot resolve apps.clusternet.io/v1alpha1, Kind=Subscription: cluster "child-c": cluster not registered`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := r.ResolveSchemaForCluster(tc.cluster, gvk)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if err != nil && err.Error() != tc.wantMsg {
+				t.Errorf("expected error %q, got %q", tc.wantMsg, err.Error())
+			}
+			if tc.wantErr == ErrClusterNotRegistered && errors.Is(err, ErrSchemaNotFound) {
+				t.Errorf("expected an unregistered cluster not to be reported as ErrSchemaNotFound")
+			}
+			if err == nil && s != expected {
+				t.Errorf("expected the schema of the cluster, got %v", s)
+			}
+		})
+	}
+
+	if got, want := r.Clusters(), []string{"child-a", "child-b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected clusters %v, got %v", want, got)
+	}
+	federated := r.Federated()
+	r.Unregister("child-a")
+	if _, err := r.ResolveSchemaForCluster("child-a", gvk); !errors.Is(err, ErrClusterNotRegistered) {
+		t.Errorf("expected ErrClusterNotRegistered after unregistering, got %v", err)
+	}
+	if result, err := federated.IntersectResolve(gvk); err != nil || !reflect.DeepEqual(result.Clusters, []string{"child-a"}) {
+		t.Errorf("expected the federated resolver to keep the clusters registered when it was created, got %v, %v", result, err)
+	}
+}
+
+func TestMultiClusterResolverConcurrentRegistration(t *testing.T) {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	r := NewMultiClusterResolver()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				r.Register("child", staticResolver{gvk: objectSchema(nil)})
+				r.Unregister("child")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, err := r.ResolveSchemaForCluster("child", gvk)
+				if err != nil && !errors.Is(err, ErrClusterNotRegistered) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
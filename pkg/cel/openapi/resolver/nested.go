@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"errors"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ResolveNestedGVKFields returns a SchemaResolver that resolves schemas with
+// the given resolver and then replaces every nested schema that carries the
+// x-kubernetes-group-version-kind extension but declares no structure of its
+// own, as is common for polymorphic specs, with the concrete schema of that
+// GVK resolved the same way.
+// A nested GVK that is already being resolved further up the tree is left as
+// is to break cycles. A nested GVK that cannot be found is left as is, too.
+func ResolveNestedGVKFields(delegate SchemaResolver) SchemaResolver {
+	return &nestedGVKResolver{delegate: delegate}
+}
+
+type nestedGVKResolver struct {
+	delegate SchemaResolver
+}
+
+func (r *nestedGVKResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+func (r *nestedGVKResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.resolve(ctx, gvk, sets.New[schema.GroupVersionKind]())
+}
+
+func (r *nestedGVKResolver) resolve(ctx context.Context, gvk schema.GroupVersionKind, visited sets.Set[schema.GroupVersionKind]) (*spec.Schema, error) {
+	s, err := r.delegate.ResolveSchemaWithContext(ctx, gvk)
+	if err != nil {
+		return nil, err
+	}
+	visited.Insert(gvk)
+	defer visited.Delete(gvk)
+	return transformSchema(s, func(s *spec.Schema) (*spec.Schema, error) {
+		if !isOpaque(s) {
+			return s, nil
+		}
+		// a malformed extension is left as it is, like an absent one.
+		gvks, err := extensionsToGVKs(s.Extensions)
+		if err != nil || len(gvks) != 1 || visited.Has(gvks[0]) {
+			return s, nil
+		}
+		resolved, err := r.resolve(ctx, gvks[0], visited)
+		if errors.Is(err, ErrSchemaNotFound) {
+			return s, nil
+		}
+		return resolved, err
+	})
+}
+
+// isOpaque checks if the schema declares no properties, additionalProperties
+// or items.
+func isOpaque(s *spec.Schema) bool {
+	return len(s.Properties) == 0 && s.AdditionalProperties == nil && s.Items == nil
+}
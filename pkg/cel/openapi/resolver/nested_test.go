@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestResolveNestedGVKFields(t *testing.T) {
+	parent := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Manifest"}
+	child := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	missing := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Missing"}
+
+	parentSchema := withGVK(*objectSchema(map[string]spec.Schema{
+		"template": withGVK(spec.Schema{}, child),
+		"other":    withGVK(spec.Schema{}, missing),
+	}), parent)
+	childSchema := withGVK(*objectSchema(map[string]spec.Schema{
+		"replicas": scalarSchema("integer", "int32"),
+		// refers back to the parent, which must not be expanded again.
+		"owner": withGVK(spec.Schema{}, parent),
+	}), child)
+	delegate := staticResolver{
+		parent: &parentSchema,
+		child:  &childSchema,
+	}
+
+	s, err := ResolveNestedGVKFields(delegate).ResolveSchema(parent)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	template := s.Properties["template"]
+	if _, ok := template.Properties["replicas"]; !ok {
+		t.Errorf("expected the nested GVK field to be resolved, got %v", template)
+	}
+	owner := template.Properties["owner"]
+	if !isOpaque(&owner) {
+		t.Errorf("expected the cyclic nested GVK field to be left as is, got %v", owner)
+	}
+	other := s.Properties["other"]
+	if !isOpaque(&other) {
+		t.Errorf("expected the unresolvable nested GVK field to be left as is, got %v", other)
+	}
+	if orig := parentSchema.Properties["template"]; !isOpaque(&orig) {
+		t.Errorf("the schema of the delegate must not be mutated")
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ObjectKinder finds the GVKs of an object. *runtime.Scheme implements this
+// interface, which allows finding the GVK of typed objects whose TypeMeta is
+// not populated, as is the case for internally decoded objects.
+type ObjectKinder interface {
+	ObjectKinds(obj runtime.Object) ([]schema.GroupVersionKind, bool, error)
+}
+
+// TypeMetaKinder is an ObjectKinder that reads the GVK from the TypeMeta of
+// the object, e.g. the apiVersion and kind of an unstructured object.
+var TypeMetaKinder ObjectKinder = typeMetaKinder{}
+
+type typeMetaKinder struct{}
+
+func (typeMetaKinder) ObjectKinds(obj runtime.Object) ([]schema.GroupVersionKind, bool, error) {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Empty() {
+		return nil, false, fmt.Errorf("object of type %T has no apiVersion or kind set", obj)
+	}
+	return []schema.GroupVersionKind{gvk}, false, nil
+}
+
+// ResolveSchemaForObject finds the GVK of the object with the kinder and
+// resolves its schema with the resolver. If kinder is nil, TypeMetaKinder is
+// used. If the kinder reports multiple GVKs for the object, the first one is
+// resolved.
+func ResolveSchemaForObject(r SchemaResolver, kinder ObjectKinder, obj runtime.Object) (*spec.Schema, error) {
+	if kinder == nil {
+		kinder = TypeMetaKinder
+	}
+	gvks, _, err := kinder.ObjectKinds(obj)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find the kind of %T: %w", obj, err)
+	}
+	if len(gvks) == 0 {
+		return nil, fmt.Errorf("cannot find the kind of %T: no kinds reported", obj)
+	}
+	return r.ResolveSchema(gvks[0])
+}
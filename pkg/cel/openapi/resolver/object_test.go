@@ -0,0 +1,58 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestResolveSchemaForObject(t *testing.T) {
+	configMap := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	r := staticResolver{
+		configMap: objectSchema(map[string]spec.Schema{"data": scalarSchema("string", "")}),
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(configMap)
+
+	for _, tc := range []struct {
+		name    string
+		kinder  ObjectKinder
+		obj     runtime.Object
+		wantErr bool
+	}{
+		{name: "typed object with empty TypeMeta by scheme", kinder: scheme.Scheme, obj: &corev1.ConfigMap{}},
+		{name: "unstructured object by TypeMeta", obj: u},
+		{name: "typed object with empty TypeMeta by TypeMeta", obj: &corev1.ConfigMap{}, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := ResolveSchemaForObject(r, tc.kinder, tc.obj)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error: %v, got %v", tc.wantErr, err)
+			}
+			if err == nil && s != r[configMap] {
+				t.Errorf("unexpected schema: %v", s)
+			}
+		})
+	}
+}
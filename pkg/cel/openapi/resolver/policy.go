@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// SchemaPolicy inspects a resolved schema and returns a non-nil error if the
+// schema must not be handed out, e.g. because it contains constructs that CEL
+// rules are not allowed to reference.
+type SchemaPolicy func(gvk schema.GroupVersionKind, s *spec.Schema) error
+
+// PolicyResolver wraps a SchemaResolver and runs a SchemaPolicy over every
+// schema the delegate resolves. Schemas rejected by the policy are not returned.
+type PolicyResolver struct {
+	Delegate SchemaResolver
+	Policy   SchemaPolicy
+}
+
+var _ SchemaResolver = (*PolicyResolver)(nil)
+
+// ResolveSchema resolves the schema with the delegate and returns it only if
+// the policy admits it. Errors of the delegate are returned as is. Errors from
+// the policy are wrapped with the GVK, so callers may still inspect them with
+// errors.Is and errors.As.
+func (r *PolicyResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema, passing ctx
+// to the delegate.
+func (r *PolicyResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	s, err := r.Delegate.ResolveSchemaWithContext(ctx, gvk)
+	if err != nil {
+		return nil, err
+	}
+	if r.Policy == nil {
+		return s, nil
+	}
+	if err := r.Policy(gvk, s); err != nil {
+		return nil, fmt.Errorf("schema of %v rejected by policy: %w", gvk, err)
+	}
+	return s, nil
+}
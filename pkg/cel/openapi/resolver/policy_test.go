@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+var errPreserveUnknownFields = errors.New("preserve-unknown-fields is not allowed")
+
+func rejectPreserveUnknownFields(_ schema.GroupVersionKind, s *spec.Schema) error {
+	var check func(s *spec.Schema) error
+	check = func(s *spec.Schema) error {
+		if v, ok := s.Extensions.GetBool("x-kubernetes-preserve-unknown-fields"); ok && v {
+			return errPreserveUnknownFields
+		}
+		for _, p := range s.Properties {
+			if err := check(&p); err != nil {
+				return err
+			}
+		}
+		if s.Items != nil && s.Items.Schema != nil {
+			return check(s.Items.Schema)
+		}
+		return nil
+	}
+	return check(s)
+}
+
+func TestPolicyResolver(t *testing.T) {
+	allowed := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	rejected := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "HelmChart"}
+	raw := spec.Schema{}
+	raw.AddExtension("x-kubernetes-preserve-unknown-fields", true)
+	r := &PolicyResolver{
+		Delegate: staticResolver{
+			allowed: objectSchema(map[string]spec.Schema{
+				"spec": *objectSchema(map[string]spec.Schema{"replicas": scalarSchema("integer", "int32")}),
+			}),
+			rejected: objectSchema(map[string]spec.Schema{
+				"spec": *objectSchema(map[string]spec.Schema{"values": raw}),
+			}),
+		},
+		Policy: rejectPreserveUnknownFields,
+	}
+
+	for _, tc := range []struct {
+		name    string
+		gvk     schema.GroupVersionKind
+		wantErr error
+	}{
+		{name: "admitted", gvk: allowed},
+		{name: "rejected", gvk: rejected, wantErr: errPreserveUnknownFields},
+		{name: "not found", gvk: schema.GroupVersionKind{Kind: "Missing"}, wantErr: ErrSchemaNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := r.ResolveSchema(tc.gvk)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if tc.wantErr == nil && s == nil {
+				t.Fatalf("expected a schema, got nil")
+			}
+			if tc.wantErr != nil && s != nil {
+				t.Errorf("expected no schema on error, got %v", s)
+			}
+		})
+	}
+}
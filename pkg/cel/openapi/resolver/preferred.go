@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ResolvePreferred resolves the schema of the kind of the group in the version
+// that the server prefers, as listed by the API groups of Discovery, and
+// returns the GVK that it resolved. If the preferred version does not serve
+// the kind, the other versions of the group are tried in the order the
+// server lists them, as a RESTMapper does. PreferredVersions overrides the
+// versions of a group and their order. The returned error wraps
+// ErrSchemaNotFound if the group is not served or none of its versions
+// serves the kind.
+func (r *ClientDiscoveryResolver) ResolvePreferred(group, kind string) (*spec.Schema, schema.GroupVersionKind, error) {
+	versions, err := r.preferredVersions(group)
+	if err != nil {
+		return nil, schema.GroupVersionKind{}, err
+	}
+	for _, version := range versions {
+		s, source, err := r.ResolveSchemaWithSource(schema.GroupVersionKind{Group: group, Version: version, Kind: kind})
+		if errors.Is(err, ErrSchemaNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, schema.GroupVersionKind{}, err
+		}
+		return s, source.GVK, nil
+	}
+	return nil, schema.GroupVersionKind{}, fmt.Errorf("cannot resolve kind %q of group %q in versions %v: %w", kind, group, versions, ErrSchemaNotFound)
+}
+
+// preferredVersions returns the versions of the group, the preferred one
+// first, from PreferredVersions or else from Discovery.
+func (r *ClientDiscoveryResolver) preferredVersions(group string) ([]string, error) {
+	if versions, ok := r.PreferredVersions[group]; ok {
+		return versions, nil
+	}
+	groups, err := r.Discovery.ServerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list the API groups: %w: %w", ErrDiscoveryUnavailable, err)
+	}
+	for _, g := range groups.Groups {
+		if g.Name != group {
+			continue
+		}
+		versions := []string{g.PreferredVersion.Version}
+		for _, v := range g.Versions {
+			if v.Version != g.PreferredVersion.Version {
+				versions = append(versions, v.Version)
+			}
+		}
+		return versions, nil
+	}
+	return nil, fmt.Errorf("cannot resolve the versions of group %q: %w", group, ErrSchemaNotFound)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/openapi/openapitest"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestResolvePreferred(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		group string
+		kind  string
+		// groupVersions are the group-versions that Discovery lists, the
+		// preferred one of each group first.
+		groupVersions []string
+		preferred     map[string][]string
+		// wantVersion is the version expected to be resolved.
+		wantVersion string
+		wantErr     error
+	}{
+		{name: "preferred", group: "apps", kind: "Deployment", groupVersions: []string{"apps/v1", "apps/v1beta2"}, wantVersion: "v1"},
+		{name: "core", kind: "Pod", groupVersions: []string{"v1"}, wantVersion: "v1"},
+		{name: "preferred not served", group: "apps", kind: "Deployment", groupVersions: []string{"apps/v1beta2", "apps/v1"}, wantVersion: "v1"},
+		{name: "override", group: "apps", kind: "Deployment", preferred: map[string][]string{"apps": {"v1beta2", "v1"}}, wantVersion: "v1"},
+		{name: "missing group", group: "example.com", kind: "Widget", groupVersions: []string{"apps/v1"}, wantErr: ErrSchemaNotFound},
+		{name: "missing kind", group: "apps", kind: "Widget", groupVersions: []string{"apps/v1"}, wantErr: ErrSchemaNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newFakeDiscovery(openapitest.NewEmbeddedFileClient())
+			for _, gv := range tc.groupVersions {
+				d.Resources = append(d.Resources, &metav1.APIResourceList{GroupVersion: gv})
+			}
+			r := &ClientDiscoveryResolver{Discovery: d, PreferredVersions: tc.preferred}
+			s, gvk, err := r.ResolvePreferred(tc.group, tc.kind)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("expected %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := schema.GroupVersionKind{Group: tc.group, Version: tc.wantVersion, Kind: tc.kind}
+			if gvk != expected {
+				t.Errorf("expected %v, got %v", expected, gvk)
+			}
+			if _, ok := s.Properties["spec"]; !ok {
+				t.Errorf("expected the schema of %v, got %v", expected, s)
+			}
+		})
+	}
+}
+
+func TestResolvePreferredDiscoveryUnavailable(t *testing.T) {
+	d := newFakeDiscovery(openapitest.NewEmbeddedFileClient())
+	d.PrependReactor("get", "group", func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+	r := &ClientDiscoveryResolver{Discovery: d}
+	if _, _, err := r.ResolvePreferred("apps", "Deployment"); !errors.Is(err, ErrDiscoveryUnavailable) {
+		t.Errorf("expected ErrDiscoveryUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// PreloadAll resolves every given GVK with r, populating its Refs, so that
+// missing types and dangling Refs surface at startup rather than when the
+// schemas are first needed. The returned error joins the errors of all GVKs
+// that failed to resolve, each of which names the GVK and its cause.
+// Resolving warms the caches of r along the way: the schemas of a
+// CachingResolver, and the documents of a ClientDiscoveryResolver that sets
+// DocumentCacheTTL or uses a cached Discovery, so that later resolutions of
+// these GVKs do not hit the network.
+func PreloadAll(r SchemaResolver, gvks []schema.GroupVersionKind) error {
+	if c, ok := r.(*CachingResolver); ok {
+		// skip copying the schemas, which are not returned.
+		return c.Warm(gvks)
+	}
+	var errs []error
+	for _, gvk := range gvks {
+		if _, err := r.ResolveSchema(gvk); err != nil {
+			errs = append(errs, wrapResolutionError(gvk, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ListingResolver is a SchemaResolver that can enumerate the GVKs it
+// resolves.
+type ListingResolver interface {
+	SchemaResolver
+	GVKLister
+}
+
+// PreloadListed preloads, as with PreloadAll, every GVK that r lists.
+func PreloadListed(r ListingResolver) error {
+	gvks, err := r.GVKs()
+	if err != nil {
+		return fmt.Errorf("cannot list the GVKs to preload: %w", err)
+	}
+	return PreloadAll(r, gvks)
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/openapi/openapitest"
+	"k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestPreloadListed(t *testing.T) {
+	getDefinitions := func(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		return map[string]common.OpenAPIDefinition{
+			"k8s.io/api/core/v1.Pod": {Schema: *objectSchema(map[string]spec.Schema{
+				"spec": {SchemaProps: spec.SchemaProps{Ref: ref("k8s.io/api/core/v1.PodSpec")}},
+			})},
+			"k8s.io/api/core/v1.Service": {Schema: *objectSchema(map[string]spec.Schema{
+				"spec": scalarSchema("string", ""),
+			})},
+		}
+	}
+	err := PreloadListed(NewDefinitionsSchemaResolver(getDefinitions, scheme.Scheme))
+	if !errors.Is(err, ErrSchemaNotFound) {
+		t.Fatalf("expected ErrSchemaNotFound, got %v", err)
+	}
+	if prefix := "cannot resolve /v1, Kind=Pod: "; !strings.HasPrefix(err.Error(), prefix) || strings.Count(err.Error(), prefix) != 1 {
+		t.Errorf("expected the error to be prefixed with %q once, got %v", prefix, err)
+	}
+	if expected := "k8s.io/api/core/v1.PodSpec"; !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected the error to name %q, got %v", expected, err)
+	}
+	if strings.Contains(err.Error(), "Kind=Service") {
+		t.Errorf("expected the error not to name the resolved Service, got %v", err)
+	}
+}
+
+func TestPreloadWarmsDocumentCache(t *testing.T) {
+	client := &countingOpenAPIClient{delegate: openapitest.NewEmbeddedFileClient()}
+	r := &ClientDiscoveryResolver{Discovery: newFakeDiscovery(client), DocumentCacheTTL: time.Minute}
+	gvks := []schema.GroupVersionKind{
+		{Version: "v1", Kind: "Pod"},
+		{Group: "apps", Version: "v1", Kind: "Deployment"},
+	}
+	if err := PreloadAll(r, gvks); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	paths, schemas := client.paths.Load(), client.schemas.Load()
+	for _, gvk := range gvks {
+		if _, err := r.ResolveSchema(gvk); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if client.paths.Load() != paths || client.schemas.Load() != schemas {
+		t.Errorf("expected the preloaded GVKs to resolve from the document cache")
+	}
+}
+
+func TestPreloadWarmsCachingResolver(t *testing.T) {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	missing := schema.GroupVersionKind{Version: "v1", Kind: "Missing"}
+	delegate := newCountingResolver(staticResolver{gvk: objectSchema(nil)})
+	r := NewCachingResolver(delegate)
+	if err := PreloadAll(r, []schema.GroupVersionKind{gvk, missing}); !errors.Is(err, ErrSchemaNotFound) || !strings.Contains(err.Error(), "Kind=Missing") {
+		t.Errorf("expected the error to name %v, got %v", missing, err)
+	}
+	if _, err := r.ResolveSchema(gvk); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls := delegate.calls[gvk]; calls != 1 {
+		t.Errorf("expected the delegate to be called once, got %d", calls)
+	}
+}
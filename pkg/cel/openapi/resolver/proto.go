@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+
+	openapi_v3 "github.com/google/gnostic-models/openapiv3"
+	"google.golang.org/protobuf/proto"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/yaml"
+)
+
+// protoSchemaName is the name of the single component of the documents that
+// wrap schemas for conversion from and to protobuf.
+const protoSchemaName = "schema"
+
+// ToProtoSchema encodes a resolved schema as an OpenAPI v3 Schema message of
+// the protobuf encoding that kube-openapi serves, which is more compact than
+// JSON for relaying schemas between components. Extensions are preserved.
+// The protobuf encoding supports a single type per schema and only scalar
+// defaults: if a schema declares several types, only the first one is
+// encoded, and defaults that are objects or arrays are dropped.
+func ToProtoSchema(s *spec.Schema) ([]byte, error) {
+	doc := map[string]interface{}{
+		"openapi": "3.0.0",
+		"info":    map[string]interface{}{"title": "", "version": ""},
+		"paths":   map[string]interface{}{},
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{protoSchemaName: s},
+		},
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode schema: %w", err)
+	}
+	parsed, err := openapi_v3.ParseDocument(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode schema: %w", err)
+	}
+	for _, named := range parsed.GetComponents().GetSchemas().GetAdditionalProperties() {
+		if named.GetName() == protoSchemaName {
+			return proto.Marshal(named.GetValue().GetSchema())
+		}
+	}
+	return nil, fmt.Errorf("internal error: cannot encode schema: component %q not found", protoSchemaName)
+}
+
+// FromProtoSchema decodes a schema encoded with ToProtoSchema.
+func FromProtoSchema(b []byte) (*spec.Schema, error) {
+	g := new(openapi_v3.Schema)
+	if err := proto.Unmarshal(b, g); err != nil {
+		return nil, fmt.Errorf("cannot decode schema: %w", err)
+	}
+	doc := &openapi_v3.Document{
+		Openapi: "3.0.0",
+		Info:    &openapi_v3.Info{},
+		Paths:   &openapi_v3.Paths{},
+		Components: &openapi_v3.Components{
+			Schemas: &openapi_v3.SchemasOrReferences{
+				AdditionalProperties: []*openapi_v3.NamedSchemaOrReference{{
+					Name:  protoSchemaName,
+					Value: &openapi_v3.SchemaOrReference{Oneof: &openapi_v3.SchemaOrReference_Schema{Schema: g}},
+				}},
+			},
+		},
+	}
+	y, err := doc.YAMLValue("")
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode schema: %w", err)
+	}
+	j, err := yaml.YAMLToJSON(y)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode schema: %w", err)
+	}
+	resp := new(schemaResponse)
+	if err := json.Unmarshal(j, resp); err != nil {
+		return nil, fmt.Errorf("cannot decode schema: %w", err)
+	}
+	s, ok := resp.Components.Schemas[protoSchemaName]
+	if !ok || s == nil {
+		return nil, fmt.Errorf("internal error: cannot decode schema: component %q not found", protoSchemaName)
+	}
+	return s, nil
+}
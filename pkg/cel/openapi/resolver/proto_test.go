@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestProtoSchemaRoundTrip(t *testing.T) {
+	s, err := newEmbeddedDiscoveryResolver().ResolveSchema(schema.GroupVersionKind{Version: "v1", Kind: "Pod"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := ToProtoSchema(s)
+	if err != nil {
+		t.Fatalf("cannot encode: %v", err)
+	}
+	decoded, err := FromProtoSchema(b)
+	if err != nil {
+		t.Fatalf("cannot decode: %v", err)
+	}
+	if _, ok := decoded.Extensions[extGVK]; !ok {
+		t.Errorf("expected the GVK extension to be preserved")
+	}
+	containers := decoded.Properties["spec"].Properties["containers"]
+	if key, _ := containers.Extensions.GetString("x-kubernetes-patch-merge-key"); key != "name" {
+		t.Errorf("expected the patch merge key extension to be preserved, got %v", containers.Extensions)
+	}
+
+	// defaults that are not scalars cannot be encoded.
+	s, err = transformSchema(s, func(s *spec.Schema) (*spec.Schema, error) {
+		switch s.Default.(type) {
+		case map[string]interface{}, []interface{}:
+			result := *s
+			result.Default = nil
+			return &result, nil
+		}
+		return s, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected, got interface{}
+	mustRoundTripJSON(t, s, &expected)
+	mustRoundTripJSON(t, decoded, &got)
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("schema changed after round trip")
+	}
+}
+
+func mustRoundTripJSON(t *testing.T, in, out interface{}) {
+	t.Helper()
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		t.Fatal(err)
+	}
+}
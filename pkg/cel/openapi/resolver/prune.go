@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// PruneUnknownFields resolves the schema of the given GVK and removes, in
+// place, the fields of obj that the schema does not define, as the server
+// prunes the unknown fields of custom resources. The values of a map are
+// pruned by the schema of its additionalProperties, and none is removed if
+// additionalProperties allows any value. The subtrees whose schema has
+// x-kubernetes-preserve-unknown-fields, is an unresolved Ref or defines
+// neither properties nor additionalProperties are left untouched.
+func PruneUnknownFields(r SchemaResolver, obj map[string]interface{}, gvk schema.GroupVersionKind) error {
+	s, err := r.ResolveSchema(gvk)
+	if err != nil {
+		return err
+	}
+	prune(s, obj)
+	return nil
+}
+
+// prune removes the fields of value that s does not define, recursively.
+func prune(s *spec.Schema, value interface{}) {
+	if s == nil || isXPreserveUnknownFields(s) {
+		return
+	}
+	if _, isRef := refOf(s); isRef {
+		return
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if len(s.Properties) == 0 && s.AdditionalProperties == nil {
+			return
+		}
+		for name, field := range value {
+			if prop, ok := s.Properties[name]; ok {
+				prune(&prop, field)
+				continue
+			}
+			switch {
+			case s.AdditionalProperties == nil || !s.AdditionalProperties.Allows:
+				delete(value, name)
+			case s.AdditionalProperties.Schema != nil:
+				prune(s.AdditionalProperties.Schema, field)
+			}
+		}
+	case []interface{}:
+		if s.Items == nil || s.Items.Schema == nil {
+			return
+		}
+		for _, item := range value {
+			prune(s.Items.Schema, item)
+		}
+	}
+}
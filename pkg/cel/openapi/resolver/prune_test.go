@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestPruneUnknownFields(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	obj := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name":    "web",
+			"labels":  map[string]interface{}{"app": "web"},
+			"unknown": "dropped",
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{
+					"name":    "nginx",
+					"image":   "nginx",
+					"unknown": "dropped",
+					"resources": map[string]interface{}{
+						"limits": map[string]interface{}{"cpu": "1"},
+					},
+				},
+			},
+			"unknown": map[string]interface{}{"nested": "dropped"},
+		},
+		"unknown": "dropped",
+	}
+	expected := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name":   "web",
+			"labels": map[string]interface{}{"app": "web"},
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{
+					"name":  "nginx",
+					"image": "nginx",
+					"resources": map[string]interface{}{
+						"limits": map[string]interface{}{"cpu": "1"},
+					},
+				},
+			},
+		},
+	}
+	if err := PruneUnknownFields(newEmbeddedDiscoveryResolver(), obj, pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(obj, expected) {
+		t.Errorf("expected %v, got %v", expected, obj)
+	}
+}
+
+func TestPruneUnknownFieldsPreserved(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	preserved := *objectSchema(map[string]spec.Schema{"known": scalarSchema("string", "")})
+	preserved.Extensions = spec.Extensions{extPreserveUnknownFields: true}
+	values := *objectSchema(nil)
+	values.AdditionalProperties = &spec.SchemaOrBool{Allows: true, Schema: objectSchema(map[string]spec.Schema{"known": scalarSchema("string", "")})}
+	anyValues := *objectSchema(nil)
+	anyValues.AdditionalProperties = &spec.SchemaOrBool{Allows: true}
+	r := staticResolver{gvk: objectSchema(map[string]spec.Schema{
+		"preserved": preserved,
+		"values":    values,
+		"anyValues": anyValues,
+		"opaque":    *objectSchema(nil),
+	})}
+	obj := map[string]interface{}{
+		"preserved": map[string]interface{}{"known": "kept", "unknown": map[string]interface{}{"nested": "kept"}},
+		"values":    map[string]interface{}{"a": map[string]interface{}{"known": "kept", "unknown": "dropped"}},
+		"anyValues": map[string]interface{}{"a": map[string]interface{}{"unknown": "kept"}},
+		"opaque":    map[string]interface{}{"unknown": "kept"},
+		"unknown":   "dropped",
+	}
+	expected := map[string]interface{}{
+		"preserved": map[string]interface{}{"known": "kept", "unknown": map[string]interface{}{"nested": "kept"}},
+		"values":    map[string]interface{}{"a": map[string]interface{}{"known": "kept"}},
+		"anyValues": map[string]interface{}{"a": map[string]interface{}{"unknown": "kept"}},
+		"opaque":    map[string]interface{}{"unknown": "kept"},
+	}
+	if err := PruneUnknownFields(r, obj, gvk); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(obj, expected) {
+		t.Errorf("expected %v, got %v", expected, obj)
+	}
+
+	missing := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Missing"}
+	if err := PruneUnknownFields(r, obj, missing); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ErrSchemaResolution is wrapped and returned if resolving a schema panicked.
+var ErrSchemaResolution = errors.New("schema resolution failed")
+
+// RecoverPanics wraps a resolver so that a panic during resolution, e.g. due
+// to malformed input, is logged along with its stack and returned as an error
+// wrapping ErrSchemaResolution instead of crashing the process.
+func RecoverPanics(delegate SchemaResolver) SchemaResolver {
+	return &recoveringResolver{delegate: delegate}
+}
+
+type recoveringResolver struct {
+	delegate SchemaResolver
+}
+
+func (r *recoveringResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema, passing ctx
+// to the delegate.
+func (r *recoveringResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (s *spec.Schema, err error) {
+	defer recoverResolution(gvk, &err)
+	return r.delegate.ResolveSchemaWithContext(ctx, gvk)
+}
+
+// recoverResolution recovers from a panic while resolving gvk and sets err
+// accordingly. It must be called directly by a deferred statement.
+func recoverResolution(gvk schema.GroupVersionKind, err *error) {
+	if r := recover(); r != nil {
+		klog.ErrorS(nil, "Recovered from panic while resolving schema", "gvk", gvk, "panic", r, "stack", string(debug.Stack()))
+		*err = wrapResolutionError(gvk, fmt.Errorf("%w: panic: %v", ErrSchemaResolution, r))
+	}
+}
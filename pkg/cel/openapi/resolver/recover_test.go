@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/openapi"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// panickingOpenAPIClient is an OpenAPI v3 client that panics when used.
+type panickingOpenAPIClient struct{}
+
+func (panickingOpenAPIClient) Paths() (map[string]openapi.GroupVersion, error) {
+	panic("malformed document")
+}
+
+func TestRecoverPanics(t *testing.T) {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	r := RecoverPanics(FuncResolver(func(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+		var s *spec.Schema
+		return s.Properties["spec"].Items.Schema, nil
+	}))
+	s, err := r.ResolveSchema(gvk)
+	if !errors.Is(err, ErrSchemaResolution) {
+		t.Errorf("expected ErrSchemaResolution, got %v", err)
+	}
+	if s != nil {
+		t.Errorf("expected no schema, got %v", s)
+	}
+}
+
+func TestRecoverPanicsPassesThrough(t *testing.T) {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	r := RecoverPanics(staticResolver{gvk: objectSchema(nil)})
+	if _, err := r.ResolveSchema(gvk); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := r.ResolveSchema(schema.GroupVersionKind{Kind: "Missing"}); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound, got %v", err)
+	}
+}
+
+func TestDiscoveryResolverRecoversPanics(t *testing.T) {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	r := &ClientDiscoveryResolver{Discovery: newFakeDiscovery(panickingOpenAPIClient{})}
+	if _, err := r.ResolveSchema(gvk); !errors.Is(err, ErrSchemaResolution) {
+		t.Errorf("expected ErrSchemaResolution, got %v", err)
+	}
+
+	r.DisablePanicRecovery = true
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic with recovery disabled")
+		}
+	}()
+	_, _ = r.ResolveSchema(gvk)
+}
@@ -17,7 +17,12 @@ limitations under the License.
 package resolver
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/kube-openapi/pkg/validation/spec"
@@ -27,96 +32,499 @@ import (
 // schemaOf is the callback to find the corresponding schema by the ref.
 // This function will not mutate the original schema. If the schema needs to be
 // mutated, a copy will be returned, otherwise it returns the original schema.
+// Likewise, the subtrees without Refs are shared with the schemas of schemaOf
+// rather than copied, so the result must be deep copied before mutating it.
 func PopulateRefs(schemaOf func(ref string) (*spec.Schema, bool), rootRef string) (*spec.Schema, error) {
+	return PopulateRefsWithOptions(schemaOf, rootRef, PopulateRefsOptions{})
+}
+
+// PopulateRefsOptions configures PopulateRefsWithOptions.
+type PopulateRefsOptions struct {
+	// ShareArrayItems makes arrays whose items refer to the same schema share
+	// a single resolved item schema, instead of inlining a copy of it for
+	// every array, e.g. for the many lists of containers or volumes of a
+	// workload. The shared item schemas must not be mutated.
+	ShareArrayItems bool
+	// ArrayItemInlineCap is the number of arrays of the same item type whose
+	// items are still inlined separately if ShareArrayItems is set; the
+	// remaining arrays share one item schema. Defaults to 0, which shares
+	// the item schema among all arrays.
+	ArrayItemInlineCap int
+	// Extensions are the handlers of the extensions of the schemas. Defaults
+	// to NewExtensionRegistry().
+	Extensions *ExtensionRegistry
+	// KeepCircularRefs leaves a Ref to a schema that is being resolved on the
+	// current path, e.g. of the recursive JSONSchemaProps of a
+	// CustomResourceDefinition, as an unresolved Ref, instead of replacing it
+	// with an empty object as placeholder.
+	KeepCircularRefs bool
+	// CollectMissingRefs makes the population continue past Refs that cannot
+	// be resolved, which are left in place, e.g. to diagnose all dangling
+	// Refs of a partial document at once. The error then joins an error
+	// wrapping ErrSchemaNotFound per missing Ref, and is returned along with
+	// the partially populated schema.
+	CollectMissingRefs bool
+	// MaxDepth, if not nil, limits the expansion of Refs by the nesting of
+	// the node, counted through properties, items and additionalProperties:
+	// with 0, the root and its direct properties are resolved and the Refs
+	// further down are left unresolved; every increment resolves one more
+	// level. By default, all Refs are expanded.
+	MaxDepth *int
+	// ValidateStructural checks the populated schema with ValidateStructural
+	// and fails fast with its error, so that callers need not validate every
+	// resolved schema before handing it to CEL. It is not compatible with
+	// the options that leave Refs unresolved, i.e. KeepCircularRefs,
+	// CollectMissingRefs and MaxDepth, if the schema has such Refs.
+	ValidateStructural bool
+	// MergeAllOf merges the members of an allOf that composes partial
+	// schemas, as third-party schemas do, into the node that declares it,
+	// after populating the Refs of each member: their properties, required
+	// fields, type and other keywords are combined, and conflicting values
+	// of a keyword fail with an error wrapping ErrAllOfConflict. By default,
+	// allOf is only expected to wrap a single Ref, as with kube-openapi, and
+	// other members are left unmerged.
+	MergeAllOf bool
+	// StripMetadata clears the description, title, example and external
+	// documentation of every node of the populated schema, as with the
+	// StripMetadata resolver, which CEL does not use.
+	StripMetadata bool
+}
+
+// PopulateRefsWithOptions replaces Refs like PopulateRefs, configured by opts.
+func PopulateRefsWithOptions(schemaOf func(ref string) (*spec.Schema, bool), rootRef string, opts PopulateRefsOptions) (*spec.Schema, error) {
+	return populateRefsWithCache(schemaOf, rootRef, opts, nil)
+}
+
+// populateRefsWithCache replaces Refs like PopulateRefsWithOptions, sharing
+// the populated Ref sites with other calls through cache, if not nil. The
+// options must be cacheable.
+func populateRefsWithCache(schemaOf func(ref string) (*spec.Schema, bool), rootRef string, opts PopulateRefsOptions, cache *refCache) (*spec.Schema, error) {
 	visitedRefs := sets.New[string]()
 	rootSchema, ok := schemaOf(rootRef)
 	visitedRefs.Insert(rootRef)
 	if !ok {
 		return nil, fmt.Errorf("internal error: cannot resolve Ref for root schema %q: %w", rootRef, ErrSchemaNotFound)
 	}
-	return populateRefs(schemaOf, visitedRefs, rootSchema)
+	var sharing *itemSharing
+	if opts.ShareArrayItems {
+		sharing = &itemSharing{
+			inlineCap: opts.ArrayItemInlineCap,
+			inlined:   map[string]int{},
+			shared:    map[string]*spec.Schema{},
+		}
+	}
+	extensions := opts.Extensions
+	if extensions == nil {
+		extensions = defaultExtensions
+	}
+	p := &populator{
+		schemaOf:         schemaOf,
+		visited:          visitedRefs,
+		sharing:          sharing,
+		extensions:       extensions,
+		keepCircularRefs: opts.KeepCircularRefs,
+		mergeAllOf:       opts.MergeAllOf,
+		cache:            cache,
+		maxDepth:         -1,
+	}
+	if opts.MaxDepth != nil {
+		p.maxDepth = *opts.MaxDepth
+	}
+	if opts.CollectMissingRefs {
+		p.missing = sets.New[string]()
+	}
+	s, err := p.populateRefs(rootSchema, 0)
+	if err != nil {
+		return nil, err
+	}
+	s, err = extensions.process(s)
+	if err == nil && opts.StripMetadata {
+		s, err = stripMetadata(s)
+	}
+	if err == nil && opts.ValidateStructural {
+		if err := ValidateStructural(s); err != nil {
+			return nil, fmt.Errorf("cannot populate Refs of %q: %w", rootRef, err)
+		}
+	}
+	if err != nil || p.missing.Len() == 0 {
+		return s, err
+	}
+	errs := make([]error, 0, p.missing.Len())
+	for _, ref := range sets.List(p.missing) {
+		errs = append(errs, fmt.Errorf("cannot resolve Ref %q: %w", ref, ErrSchemaNotFound))
+	}
+	return s, errors.Join(errs...)
+}
+
+// refCache holds the populated schemas of Ref sites, to share them across
+// PopulateRefs over the same schemas with the same options, e.g. the
+// ObjectMeta that almost every object refers to. The shared sites must not be
+// mutated.
+type refCache struct {
+	lock sync.RWMutex
+	// opts are the options the sites were populated with.
+	opts PopulateRefsOptions
+	// sites are the populated schemas by referencing site.
+	sites map[string]*spec.Schema
+}
+
+// forOptions returns the cache, dropping the populated sites unless they
+// were populated with opts.
+func (c *refCache) forOptions(opts PopulateRefsOptions) *refCache {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.sites == nil || c.opts != opts {
+		c.opts = opts
+		c.sites = map[string]*spec.Schema{}
+	}
+	return c
+}
+
+func (c *refCache) get(site string) (*spec.Schema, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	s, ok := c.sites[site]
+	return s, ok
+}
+
+func (c *refCache) put(site string, s *spec.Schema) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.sites[site] = s
+}
+
+// cacheable checks if the populated Ref sites are independent of the
+// PopulateRefs they are populated in, so that they can be shared through a
+// refCache: the missing Refs are collected per call, the expansion depth of
+// a site depends on its path, and the arrays inlined up to the
+// ArrayItemInlineCap must not share their items.
+func (o PopulateRefsOptions) cacheable() bool {
+	return !o.CollectMissingRefs && o.MaxDepth == nil && !o.ShareArrayItems
+}
+
+// itemSharing tracks the array item schemas shared during one PopulateRefs.
+type itemSharing struct {
+	inlineCap int
+	// inlined counts the arrays whose items were inlined, by referencing
+	// site.
+	inlined map[string]int
+	// shared holds the resolved item schemas to share, by referencing site.
+	shared map[string]*spec.Schema
+}
+
+// populator holds the state of one PopulateRefs.
+type populator struct {
+	schemaOf func(ref string) (*spec.Schema, bool)
+	// visited holds the Refs being resolved on the current path.
+	visited    sets.Set[string]
+	sharing    *itemSharing
+	extensions *ExtensionRegistry
+	// keepCircularRefs leaves circular Refs unresolved.
+	keepCircularRefs bool
+	// missing collects the Refs that cannot be resolved, if not nil.
+	missing sets.Set[string]
+	// maxDepth is the deepest nesting of the direct properties of the root
+	// at which Refs are expanded, or negative if unlimited.
+	maxDepth int
+	// mergeAllOf merges the members of composing allOfs.
+	mergeAllOf bool
+	// placeholders counts the placeholders returned for circular refs, or
+	// the circular refs kept unresolved, or the refs left unresolved below
+	// maxDepth. A schema resolved while one was met depends on the path it
+	// was resolved at, and is not shared.
+	placeholders int
+	// cache holds the populated Ref sites shared across PopulateRefs, if not
+	// nil.
+	cache *refCache
+}
+
+// populateRefs populates the Refs of schema, which is nested depth levels
+// below the root, sharing the result through the cache if schema is the
+// referencing site of a Ref.
+func (p *populator) populateRefs(schema *spec.Schema, depth int) (*spec.Schema, error) {
+	if p.cache == nil {
+		return p.populate(schema, depth)
+	}
+	// a circular ref is replaced with a placeholder rather than its schema.
+	if ref, isRef := refOf(schema); !isRef || p.visited.Has(ref) {
+		return p.populate(schema, depth)
+	}
+	site, ok := p.siteKey(schema)
+	if !ok {
+		return p.populate(schema, depth)
+	}
+	if cached, ok := p.cache.get(site); ok {
+		return cached, nil
+	}
+	placeholders := p.placeholders
+	populated, err := p.populate(schema, depth)
+	if err != nil {
+		return nil, err
+	}
+	if p.placeholders == placeholders {
+		p.cache.put(site, populated)
+	}
+	return populated, nil
 }
 
-func populateRefs(schemaOf func(ref string) (*spec.Schema, bool), visited sets.Set[string], schema *spec.Schema) (*spec.Schema, error) {
+// populate populates the Refs of schema like populateRefs, without the cache
+// for schema itself.
+func (p *populator) populate(schema *spec.Schema, depth int) (*spec.Schema, error) {
+	if p.mergeAllOf && composesAllOf(schema) {
+		merged, err := p.mergeMembers(schema, depth)
+		if err != nil {
+			return nil, err
+		}
+		return p.populateRefs(merged, depth)
+	}
 	result := *schema
 	changed := false
 
 	ref, isRef := refOf(schema)
 	if isRef {
-		if visited.Has(ref) {
+		if p.maxDepth >= 0 && depth > p.maxDepth+1 {
+			// the result depends on the path, like for circular refs.
+			p.placeholders++
+			return schema, nil
+		}
+		if p.visited.Has(ref) {
+			p.placeholders++
+			if p.keepCircularRefs {
+				return schema, nil
+			}
 			return &spec.Schema{
 				// for circular ref, return an empty object as placeholder
 				SchemaProps: spec.SchemaProps{Type: []string{"object"}},
 			}, nil
 		}
-		visited.Insert(ref)
+		p.visited.Insert(ref)
 		// restore visited state at the end of the recursion.
 		defer func() {
-			visited.Delete(ref)
+			p.visited.Delete(ref)
 		}()
 		// replace the whole schema with the referred one.
-		resolved, ok := schemaOf(ref)
+		resolved, ok := p.schemaOf(ref)
+		if !ok && p.missing != nil {
+			p.missing.Insert(ref)
+			return schema, nil
+		}
 		if !ok {
 			return nil, fmt.Errorf("internal error: cannot resolve Ref %q: %w", ref, ErrSchemaNotFound)
 		}
 		result = *resolved
 		changed = true
+		// a default declared at the referencing site, e.g. next to a Ref
+		// wrapped in allOf, takes precedence over that of the referred schema.
+		if schema.Default != nil {
+			result.Default = schema.Default
+		}
+		// so does the description, which allOf wrapping exists to preserve
+		// and which describes the field rather than its type.
+		if len(schema.Description) > 0 {
+			result.Description = schema.Description
+		}
+		// so do the extensions whose handlers say so; the other extensions
+		// of the site are kept unless the referred schema declares them too.
+		p.extensions.overrideRef(schema, &result)
+		// a field may be nullable even if its type is not, which is declared
+		// next to its Ref, or next to the Ref wrapped in allOf.
+		if isNullableSite(schema) {
+			result.Nullable = true
+		}
+		// the referred schema may compose an allOf itself.
+		if p.mergeAllOf && composesAllOf(&result) {
+			merged, err := p.mergeMembers(&result, depth)
+			if err != nil {
+				return nil, err
+			}
+			result = *merged
+		}
 	}
-	// schema is an object, populate its properties and additionalProperties
-	props := make(map[string]spec.Schema, len(schema.Properties))
-	propsChanged := false
+	// some generators declare nullability as a "null" type rather than with
+	// nullable, which CEL and the apiserver do not understand.
+	if types, nullable := nonNullTypes(result.Type); nullable {
+		result.Type = types
+		result.Nullable = true
+		changed = true
+	}
+	// schema is an object, populate its properties and additionalProperties;
+	// the properties are only copied once one of them changes.
+	var props map[string]spec.Schema
 	for name, prop := range result.Properties {
-		populated, err := populateRefs(schemaOf, visited, &prop)
+		populated, err := p.populateRefs(&prop, depth+1)
 		if err != nil {
 			return nil, err
 		}
-		if populated != &prop {
-			propsChanged = true
+		if populated == &prop {
+			continue
+		}
+		if props == nil {
+			props = copyProperties(result.Properties)
 		}
 		props[name] = *populated
 	}
-	if propsChanged {
+	if props != nil {
 		changed = true
 		result.Properties = props
 	}
 	if result.AdditionalProperties != nil && result.AdditionalProperties.Schema != nil {
-		populated, err := populateRefs(schemaOf, visited, result.AdditionalProperties.Schema)
+		populated, err := p.populateRefs(result.AdditionalProperties.Schema, depth+1)
 		if err != nil {
 			return nil, err
 		}
 		if populated != result.AdditionalProperties.Schema {
 			changed = true
-			result.AdditionalProperties.Schema = populated
+			// copy before assigning, result shares the pointer with schema.
+			additionalProperties := *result.AdditionalProperties
+			additionalProperties.Schema = populated
+			result.AdditionalProperties = &additionalProperties
 		}
 	}
 	// schema is a list, populate its items
 	if result.Items != nil && result.Items.Schema != nil {
-		populated, err := populateRefs(schemaOf, visited, result.Items.Schema)
+		populated, err := p.populateItems(result.Items.Schema, depth+1)
 		if err != nil {
 			return nil, err
 		}
 		if populated != result.Items.Schema {
 			changed = true
-			result.Items.Schema = populated
+			items := *result.Items
+			items.Schema = populated
+			result.Items = &items
 		}
 	}
 	if changed {
-		return &result, nil
+		// copied so that result is only allocated if the node changed.
+		populated := result
+		return &populated, nil
 	}
 	return schema, nil
 }
 
+// populateItems populates the Refs of the item schema of an array, sharing it
+// with other arrays of the same item type according to sharing, if not nil.
+func (p *populator) populateItems(items *spec.Schema, depth int) (*spec.Schema, error) {
+	sharing := p.sharing
+	if _, isRef := refOf(items); sharing == nil || !isRef {
+		return p.populateRefs(items, depth)
+	}
+	site, ok := p.siteKey(items)
+	if !ok {
+		return p.populateRefs(items, depth)
+	}
+	if shared, ok := sharing.shared[site]; ok {
+		return shared, nil
+	}
+	if sharing.inlined[site] < sharing.inlineCap {
+		sharing.inlined[site]++
+		return p.populateRefs(items, depth)
+	}
+	placeholders := p.placeholders
+	populated, err := p.populateRefs(items, depth)
+	if err != nil {
+		return nil, err
+	}
+	if p.placeholders == placeholders {
+		sharing.shared[site] = populated
+	}
+	return populated, nil
+}
+
+// siteKey returns the key of a Ref site: its Ref and the keywords of the site
+// that override those of the referred schema in populate, since e.g. two
+// sites of the same Ref with different defaults populate differently. A site
+// whose allOf is merged with MergeAllOf depends on all of its keywords, and
+// has no key.
+func (p *populator) siteKey(site *spec.Schema) (string, bool) {
+	ref, isRef := refOf(site)
+	if !isRef || (p.mergeAllOf && composesAllOf(site)) {
+		return "", false
+	}
+	var b strings.Builder
+	b.Grow(len(ref) + len(site.Description) + 8)
+	b.WriteString(ref)
+	// the description is length-prefixed so that it cannot be confused with
+	// the other overrides.
+	b.WriteByte(0)
+	b.WriteString(strconv.Itoa(len(site.Description)))
+	b.WriteByte(':')
+	b.WriteString(site.Description)
+	if isNullableSite(site) {
+		b.WriteByte('n')
+	}
+	// defaults and extensions are rare at Ref sites and only marshaled if
+	// set.
+	if site.Default != nil || len(site.Extensions) > 0 {
+		overrides, err := json.Marshal([]any{site.Default, site.Extensions})
+		if err != nil {
+			return "", false
+		}
+		b.Write(overrides)
+	}
+	return b.String(), true
+}
+
+// isNullableSite checks if a referencing site declares nullable, itself or
+// in the allOf that wraps its Ref.
+func isNullableSite(site *spec.Schema) bool {
+	if site.Nullable {
+		return true
+	}
+	for _, allOf := range site.AllOf {
+		if allOf.Nullable {
+			return true
+		}
+	}
+	return false
+}
+
+// nonNullTypes returns the types without "null", and whether "null" was
+// among them.
+func nonNullTypes(types spec.StringOrArray) (spec.StringOrArray, bool) {
+	if !types.Contains("null") {
+		return types, false
+	}
+	var result spec.StringOrArray
+	for _, t := range types {
+		if t != "null" {
+			result = append(result, t)
+		}
+	}
+	return result, true
+}
+
 func refOf(schema *spec.Schema) (string, bool) {
 	if schema.Ref.GetURL() != nil {
 		return schema.Ref.String(), true
 	}
 	// A Ref may be wrapped in allOf to preserve its description
 	// see https://github.com/kubernetes/kubernetes/issues/106387
-	// For kube-openapi, allOf is only used for wrapping a Ref.
+	// For kube-openapi, allOf is only used for wrapping a Ref; allOfs that
+	// compose partial schemas are merged beforehand with MergeAllOf.
 	for _, allOf := range schema.AllOf {
 		if ref, isRef := refOf(&allOf); isRef {
 			return ref, isRef
 		}
 	}
+	// CRDs and third-party tools may wrap a Ref in oneOf or anyOf, too.
+	for _, union := range [][]spec.Schema{schema.OneOf, schema.AnyOf} {
+		if ref, isRef := unionRefOf(union); isRef {
+			return ref, isRef
+		}
+	}
 	return "", false
 }
+
+// unionRefOf returns the Ref wrapped by the branches of a oneOf or anyOf if
+// they all refer to the same schema, which the union then collapses to.
+func unionRefOf(union []spec.Schema) (string, bool) {
+	var result string
+	for i := range union {
+		ref, isRef := refOf(&union[i])
+		if !isRef || (len(result) > 0 && ref != result) {
+			return "", false
+		}
+		result = ref
+	}
+	return result, len(result) > 0
+}
@@ -0,0 +1,461 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/utils/ptr"
+)
+
+func TestPopulateRefsDoesNotMutate(t *testing.T) {
+	ref := func(name string) *spec.Schema {
+		return &spec.Schema{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef(name)}}
+	}
+	schemas := map[string]*spec.Schema{
+		"Root": {SchemaProps: spec.SchemaProps{
+			Type:                 []string{"object"},
+			Items:                &spec.SchemaOrArray{Schema: ref("Item")},
+			AdditionalProperties: &spec.SchemaOrBool{Allows: true, Schema: ref("Item")},
+		}},
+		"Item": objectSchema(map[string]spec.Schema{"name": scalarSchema("string", "")}),
+	}
+	root := schemas["Root"]
+	s, err := PopulateRefs(func(ref string) (*spec.Schema, bool) {
+		s, ok := schemas[ref]
+		return s, ok
+	}, "Root")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := s.Items.Schema.Properties["name"]; !ok {
+		t.Errorf("expected items to be populated, got %v", s.Items.Schema)
+	}
+	if _, ok := s.AdditionalProperties.Schema.Properties["name"]; !ok {
+		t.Errorf("expected additionalProperties to be populated, got %v", s.AdditionalProperties.Schema)
+	}
+	if root.Items.Schema.Ref.GetURL() == nil || root.AdditionalProperties.Schema.Ref.GetURL() == nil {
+		t.Errorf("expected the original schema not to be mutated")
+	}
+}
+
+func TestPopulateRefsMergesSiteExtensions(t *testing.T) {
+	const extOwner = "x-clusternet-owner"
+	ports := &spec.Schema{SchemaProps: spec.SchemaProps{
+		Type:  []string{"array"},
+		Items: &spec.SchemaOrArray{Schema: objectSchema(map[string]spec.Schema{"port": scalarSchema("integer", "int32")})},
+	}}
+	ports.AddExtension(extListType, "atomic")
+	ports.AddExtension(extOwner, "agent")
+	site := spec.Schema{SchemaProps: spec.SchemaProps{
+		AllOf: []spec.Schema{{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef("Ports")}}},
+	}}
+	site.AddExtension(extListType, "map")
+	site.AddExtension(extListMapKeys, []interface{}{"port"})
+	site.AddExtension(extPreserveUnknownFields, true)
+	site.AddExtension(extOwner, "hub")
+	schemas := map[string]*spec.Schema{
+		"Root":  objectSchema(map[string]spec.Schema{"ports": site}),
+		"Ports": ports,
+	}
+	s, err := PopulateRefs(func(ref string) (*spec.Schema, bool) {
+		s, ok := schemas[ref]
+		return s, ok
+	}, "Root")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resolved := s.Properties["ports"]
+	if resolved.Items == nil {
+		t.Fatalf("expected the Ref to be populated, got %v", resolved)
+	}
+	if listType, _ := resolved.Extensions.GetString(extListType); listType != "map" {
+		t.Errorf("expected the list type of the referencing site to take precedence, got %q", listType)
+	}
+	var keys []string
+	if err := resolved.Extensions.GetObject(extListMapKeys, &keys); err != nil || len(keys) != 1 || keys[0] != "port" {
+		t.Errorf("expected the list map keys of the referencing site, got %v", resolved.Extensions[extListMapKeys])
+	}
+	if preserve, _ := resolved.Extensions.GetBool(extPreserveUnknownFields); !preserve {
+		t.Errorf("expected the extension of the referencing site to be kept, got %v", resolved.Extensions)
+	}
+	if owner, _ := resolved.Extensions.GetString(extOwner); owner != "agent" {
+		t.Errorf("expected the referred schema to take precedence for an extension without handler, got %q", owner)
+	}
+	if listType, _ := ports.Extensions.GetString(extListType); listType != "atomic" || len(ports.Extensions) != 2 {
+		t.Errorf("expected the referred schema not to be mutated, got %v", ports.Extensions)
+	}
+}
+
+func TestPopulateRefsKeepsListMapKeys(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	for _, tc := range []struct {
+		name string
+		opts PopulateRefsOptions
+	}{
+		{name: "inlined items"},
+		{name: "shared items", opts: PopulateRefsOptions{ShareArrayItems: true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newEmbeddedDiscoveryResolver()
+			r.RefOptions = tc.opts
+			s, err := r.ResolveSchema(pod)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, path := range []string{"spec.containers[*].ports", "spec.initContainers[*].ports"} {
+				ports, ok := lookupPath(s, path)
+				if !ok {
+					t.Fatalf("expected %s in the resolved schema", path)
+				}
+				if listType := getXListType(ports); listType != "map" {
+					t.Errorf("expected %s to be a list of type map, got %q", path, listType)
+				}
+				if keys, expected := getXListMapKeys(ports), []string{"containerPort", "protocol"}; !reflect.DeepEqual(keys, expected) {
+					t.Errorf("expected the map keys of %s to be %v, got %v", path, expected, keys)
+				}
+				if ports.Items == nil || ports.Items.Schema == nil || ports.Items.Schema.Ref.GetURL() != nil {
+					t.Fatalf("expected the items of %s to be populated, got %v", path, ports.Items)
+				}
+				for _, key := range []string{"containerPort", "protocol"} {
+					if _, ok := ports.Items.Schema.Properties[key]; !ok {
+						t.Errorf("expected the map key %q to be a property of the items of %s", key, path)
+					}
+				}
+			}
+			containers, _ := lookupPath(s, "spec.containers")
+			if key, _ := containers.Extensions.GetString("x-kubernetes-patch-merge-key"); key != "name" {
+				t.Errorf("expected the merge key of the containers to be kept, got %v", containers.Extensions)
+			}
+		})
+	}
+}
+
+func TestShareArrayItems(t *testing.T) {
+	ref := func(name string) *spec.Schema {
+		return &spec.Schema{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef(name)}}
+	}
+	arrayOf := func(name string) spec.Schema {
+		return spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"array"}, Items: &spec.SchemaOrArray{Schema: ref(name)}}}
+	}
+	schemas := map[string]*spec.Schema{
+		"Spec": objectSchema(map[string]spec.Schema{
+			"containers":     arrayOf("Container"),
+			"initContainers": arrayOf("Container"),
+			"sidecars":       arrayOf("Container"),
+			"volumes":        arrayOf("Volume"),
+		}),
+		"Container": objectSchema(map[string]spec.Schema{"image": scalarSchema("string", "")}),
+		// Volume refers to itself, so its resolution depends on where it is
+		// resolved and it is never shared.
+		"Volume": objectSchema(map[string]spec.Schema{"nested": arrayOf("Volume")}),
+	}
+	schemaOf := func(ref string) (*spec.Schema, bool) {
+		s, ok := schemas[ref]
+		return s, ok
+	}
+	distinctItems := func(s *spec.Schema) int {
+		items := map[*spec.Schema]bool{}
+		for _, name := range []string{"containers", "initContainers", "sidecars"} {
+			items[s.Properties[name].Items.Schema] = true
+		}
+		return len(items)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		opts     PopulateRefsOptions
+		expected int
+	}{
+		{name: "disabled", expected: 3},
+		{name: "share always", opts: PopulateRefsOptions{ShareArrayItems: true}, expected: 1},
+		{name: "inline once", opts: PopulateRefsOptions{ShareArrayItems: true, ArrayItemInlineCap: 1}, expected: 2},
+		{name: "inline all", opts: PopulateRefsOptions{ShareArrayItems: true, ArrayItemInlineCap: 3}, expected: 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := PopulateRefsWithOptions(schemaOf, "Spec", tc.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := distinctItems(s); got != tc.expected {
+				t.Errorf("expected %d distinct item schemas, got %d", tc.expected, got)
+			}
+			if _, ok := s.Properties["containers"].Items.Schema.Properties["image"]; !ok {
+				t.Errorf("expected the item schema to be populated, got %v", s.Properties["containers"].Items.Schema)
+			}
+			volume := s.Properties["volumes"].Items.Schema
+			if _, ok := volume.Properties["nested"]; !ok {
+				t.Errorf("expected the self-referencing item schema to be populated, got %v", volume)
+			}
+		})
+	}
+}
+
+func TestDiscoveryShareArrayItems(t *testing.T) {
+	r := newEmbeddedDiscoveryResolver()
+	r.RefOptions = PopulateRefsOptions{ShareArrayItems: true}
+	s, err := r.ResolveSchema(schema.GroupVersionKind{Version: "v1", Kind: "Pod"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	podSpec := s.Properties["spec"]
+	if podSpec.Properties["containers"].Items.Schema != podSpec.Properties["initContainers"].Items.Schema {
+		t.Errorf("expected containers and initContainers to share one item schema")
+	}
+}
+
+func TestPopulateRefsCollectMissingRefs(t *testing.T) {
+	ref := func(name string) spec.Schema {
+		return spec.Schema{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef(name)}}
+	}
+	schemas := map[string]*spec.Schema{
+		"Root": objectSchema(map[string]spec.Schema{
+			"spec":   ref("Spec"),
+			"status": ref("Status"),
+			"other":  ref("Status"),
+		}),
+		"Spec": objectSchema(map[string]spec.Schema{
+			"template": ref("Template"),
+			"replicas": scalarSchema("integer", "int32"),
+		}),
+	}
+	schemaOf := func(ref string) (*spec.Schema, bool) {
+		s, ok := schemas[ref]
+		return s, ok
+	}
+
+	if _, err := PopulateRefs(schemaOf, "Root"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Fatalf("expected ErrSchemaNotFound, got %v", err)
+	}
+
+	s, err := PopulateRefsWithOptions(schemaOf, "Root", PopulateRefsOptions{CollectMissingRefs: true})
+	if !errors.Is(err, ErrSchemaNotFound) {
+		t.Fatalf("expected ErrSchemaNotFound, got %v", err)
+	}
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) || len(joined.Unwrap()) != 2 {
+		t.Fatalf("expected an error per missing Ref, got %v", err)
+	}
+	for _, missing := range []string{"Status", "Template"} {
+		if !strings.Contains(err.Error(), strconv.Quote(missing)) {
+			t.Errorf("expected the error to name %q, got %v", missing, err)
+		}
+	}
+	if s == nil {
+		t.Fatalf("expected the partially populated schema")
+	}
+	if _, ok := s.Properties["spec"].Properties["replicas"]; !ok {
+		t.Errorf("expected the resolvable Refs to be populated, got %v", s.Properties["spec"])
+	}
+	if status := s.Properties["status"]; status.Ref.String() != "Status" {
+		t.Errorf("expected the missing Ref to be left in place, got %v", status)
+	}
+	if template := s.Properties["spec"].Properties["template"]; template.Ref.String() != "Template" {
+		t.Errorf("expected the missing Ref to be left in place, got %v", template)
+	}
+}
+
+func TestPopulateRefsMaxDepth(t *testing.T) {
+	ref := func(name string) spec.Schema {
+		return spec.Schema{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef(name)}}
+	}
+	schemas := map[string]*spec.Schema{
+		"Root": objectSchema(map[string]spec.Schema{
+			"spec": ref("Spec"),
+		}),
+		"Spec": objectSchema(map[string]spec.Schema{
+			"template": ref("Template"),
+		}),
+		"Template": objectSchema(map[string]spec.Schema{
+			"containers": {SchemaProps: spec.SchemaProps{
+				Type:  []string{"array"},
+				Items: &spec.SchemaOrArray{Schema: ptr.To(ref("Container"))},
+			}},
+		}),
+		"Container": objectSchema(map[string]spec.Schema{
+			"name": scalarSchema("string", ""),
+		}),
+	}
+	schemaOf := func(ref string) (*spec.Schema, bool) {
+		s, ok := schemas[ref]
+		return s, ok
+	}
+	// the nodes in the order of their nesting, by the Ref they are declared by.
+	nodes := func(s *spec.Schema) []spec.Schema {
+		specSchema := s.Properties["spec"]
+		template := specSchema.Properties["template"]
+		var container spec.Schema
+		if containers, ok := template.Properties["containers"]; ok {
+			container = *containers.Items.Schema
+		}
+		return []spec.Schema{specSchema, template, container}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		maxDepth *int
+		// wantResolved is the number of resolved Refs along the path.
+		wantResolved int
+	}{
+		{name: "unlimited", wantResolved: 3},
+		{name: "direct properties", maxDepth: ptr.To(0), wantResolved: 1},
+		{name: "one more level", maxDepth: ptr.To(1), wantResolved: 2},
+		{name: "items count as a level", maxDepth: ptr.To(2), wantResolved: 2},
+		{name: "through items", maxDepth: ptr.To(3), wantResolved: 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, share := range []bool{false, true} {
+				s, err := PopulateRefsWithOptions(schemaOf, "Root", PopulateRefsOptions{MaxDepth: tc.maxDepth, ShareArrayItems: share})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				for i, node := range nodes(s)[:tc.wantResolved] {
+					if _, isRef := refOf(&node); isRef || len(node.Properties) == 0 {
+						t.Errorf("expected the Ref at depth %d to be resolved, got %v", i, node)
+					}
+				}
+				if tc.wantResolved < 3 {
+					if node := nodes(s)[tc.wantResolved]; node.Ref.GetURL() == nil {
+						t.Errorf("expected the Ref at depth %d to be left unresolved, got %v", tc.wantResolved, node)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPopulateRefsNormalizesNullable(t *testing.T) {
+	ref := func(name string) spec.Schema {
+		return spec.Schema{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef(name)}}
+	}
+	wrapped := spec.Schema{SchemaProps: spec.SchemaProps{AllOf: []spec.Schema{ref("Item")}, Nullable: true}}
+	wrapped.Description = "the wrapped item"
+	sibling := ref("Item")
+	sibling.Nullable = true
+	nullType := scalarSchema("string", "")
+	nullType.Type = append(nullType.Type, "null")
+	schemas := map[string]*spec.Schema{
+		"Root": objectSchema(map[string]spec.Schema{
+			"wrapped":  wrapped,
+			"sibling":  sibling,
+			"plain":    ref("Item"),
+			"nullType": nullType,
+		}),
+		"Item": objectSchema(map[string]spec.Schema{"name": scalarSchema("string", "")}),
+	}
+	s, err := PopulateRefs(func(ref string) (*spec.Schema, bool) {
+		s, ok := schemas[ref]
+		return s, ok
+	}, "Root")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, tc := range []struct {
+		name         string
+		wantNullable bool
+		wantType     string
+	}{
+		{name: "wrapped", wantNullable: true, wantType: "object"},
+		{name: "sibling", wantNullable: true, wantType: "object"},
+		{name: "plain", wantType: "object"},
+		{name: "nullType", wantNullable: true, wantType: "string"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			prop := s.Properties[tc.name]
+			if prop.Nullable != tc.wantNullable {
+				t.Errorf("expected nullable %v, got %v", tc.wantNullable, prop.Nullable)
+			}
+			if len(prop.Type) != 1 || prop.Type[0] != tc.wantType {
+				t.Errorf("expected type %q, got %v", tc.wantType, prop.Type)
+			}
+		})
+	}
+	if _, ok := s.Properties["wrapped"].Properties["name"]; !ok {
+		t.Errorf("expected the wrapped Ref to be resolved, got %v", s.Properties["wrapped"])
+	}
+	if schemas["Item"].Nullable || len(schemas["Root"].Properties["nullType"].Type) != 2 {
+		t.Errorf("expected the original schemas not to be mutated")
+	}
+}
+
+func TestRefOf(t *testing.T) {
+	ref := func(name string) spec.Schema {
+		return spec.Schema{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef(name)}}
+	}
+	for _, tc := range []struct {
+		name    string
+		schema  spec.Schema
+		wantRef string
+	}{
+		{name: "ref", schema: ref("A"), wantRef: "A"},
+		{name: "ref alongside other keywords", schema: func() spec.Schema {
+			s := ref("A")
+			s.Description = "a field"
+			s.Type = []string{"object"}
+			return s
+		}(), wantRef: "A"},
+		{name: "allOf", schema: spec.Schema{SchemaProps: spec.SchemaProps{AllOf: []spec.Schema{ref("A")}}}, wantRef: "A"},
+		{name: "allOf with several refs", schema: spec.Schema{SchemaProps: spec.SchemaProps{AllOf: []spec.Schema{ref("A"), ref("B")}}}, wantRef: "A"},
+		{name: "oneOf", schema: spec.Schema{SchemaProps: spec.SchemaProps{OneOf: []spec.Schema{ref("A")}}}, wantRef: "A"},
+		{name: "anyOf", schema: spec.Schema{SchemaProps: spec.SchemaProps{AnyOf: []spec.Schema{ref("A")}}}, wantRef: "A"},
+		{name: "anyOf with the same ref", schema: spec.Schema{SchemaProps: spec.SchemaProps{AnyOf: []spec.Schema{ref("A"), ref("A")}}}, wantRef: "A"},
+		{name: "oneOf with distinct refs", schema: spec.Schema{SchemaProps: spec.SchemaProps{OneOf: []spec.Schema{ref("A"), ref("B")}}}},
+		{name: "oneOf with a ref and a type", schema: spec.Schema{SchemaProps: spec.SchemaProps{OneOf: []spec.Schema{ref("A"), scalarSchema("string", "")}}}},
+		{name: "typed union", schema: spec.Schema{SchemaProps: spec.SchemaProps{OneOf: []spec.Schema{scalarSchema("integer", ""), scalarSchema("string", "")}}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ref, isRef := refOf(&tc.schema)
+			if ref != tc.wantRef || isRef != (len(tc.wantRef) > 0) {
+				t.Errorf("expected ref %q, got %q, %v", tc.wantRef, ref, isRef)
+			}
+		})
+	}
+}
+
+func TestPopulateRefsUnwrapsUnions(t *testing.T) {
+	schemas := map[string]*spec.Schema{
+		"Root": objectSchema(map[string]spec.Schema{
+			"spec": {SchemaProps: spec.SchemaProps{
+				Description: "the spec",
+				OneOf:       []spec.Schema{{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef("Spec")}}},
+			}},
+		}),
+		"Spec": objectSchema(map[string]spec.Schema{"replicas": scalarSchema("integer", "int32")}),
+	}
+	s, err := PopulateRefs(func(ref string) (*spec.Schema, bool) {
+		s, ok := schemas[ref]
+		return s, ok
+	}, "Root")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	populated := s.Properties["spec"]
+	if _, ok := populated.Properties["replicas"]; !ok || len(populated.OneOf) != 0 {
+		t.Errorf("expected the oneOf to collapse to the referred schema, got %v", populated)
+	}
+	if populated.Description != "the spec" {
+		t.Errorf("expected the description of the referencing site, got %q", populated.Description)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ResilientResolver prefers resolving schemas from the live OpenAPI v3
+// discovery and falls back to the compiled definitions of the built-in types
+// on any failure of the discovery, e.g. transport errors or recovered panics
+// (ErrSchemaResolution), not only if the schema is not found. This keeps
+// admission working during an outage of the OpenAPI endpoint of the
+// apiserver. Unlike ChainResolver, which only moves on if the schema is not
+// found, ResilientResolver may thus mask discovery errors for the types the
+// definitions know; the origin of each schema is reported by
+// ResolveSchemaWithSource. With PreferDefinitions, the order is reversed for
+// the types the definitions know.
+type ResilientResolver struct {
+	// Discovery resolves the schemas from the live discovery. If nil, the
+	// schemas are resolved from the Definitions only.
+	Discovery *ClientDiscoveryResolver
+	// Definitions are the fallback. If nil, the errors of the Discovery are
+	// returned as they are.
+	Definitions *DefinitionsSchemaResolver
+	// PreferDefinitions resolves the types the Definitions know from the
+	// Definitions first, and falls back to the Discovery on any failure.
+	// Other types are resolved from the Discovery only.
+	PreferDefinitions bool
+}
+
+var _ SourceResolver = (*ResilientResolver)(nil)
+
+func (r *ResilientResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema, passing ctx
+// to the resolvers. The resolver does not fall back once ctx is done.
+func (r *ResilientResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	s, _, err := r.resolve(ctx, gvk)
+	return s, err
+}
+
+// ResolveSchemaWithSource resolves the schema like ResolveSchema and also
+// returns its provenance, whose Origin is DiagnosticSourceDefinitions if the
+// resolver fell back to the definitions.
+// If the definitions do not know the GVK either, the error of the discovery
+// is returned.
+func (r *ResilientResolver) ResolveSchemaWithSource(gvk schema.GroupVersionKind) (*spec.Schema, ResolveSource, error) {
+	return r.resolve(context.Background(), gvk)
+}
+
+// resolve implements ResolveSchemaWithContext and ResolveSchemaWithSource.
+func (r *ResilientResolver) resolve(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, ResolveSource, error) {
+	if r.Discovery == nil {
+		if r.Definitions == nil {
+			return nil, ResolveSource{}, wrapResolutionError(gvk, fmt.Errorf("neither discovery nor definitions are configured: %w", ErrSchemaNotFound))
+		}
+		return r.resolveFromDefinitions(ctx, gvk)
+	}
+	if r.Definitions == nil {
+		return r.Discovery.resolve(ctx, gvk)
+	}
+	if _, ok := r.Definitions.gvkToRef[gvk]; !ok {
+		return r.Discovery.resolve(ctx, gvk)
+	}
+	if r.PreferDefinitions {
+		s, source, err := r.resolveFromDefinitions(ctx, gvk)
+		if err == nil || ctx.Err() != nil {
+			return s, source, err
+		}
+		klog.V(2).InfoS("Falling back to discovery after failing to resolve schema from the compiled definitions", "gvk", gvk, "err", err)
+		s, source, fallbackErr := r.Discovery.resolve(ctx, gvk)
+		if fallbackErr != nil {
+			return nil, ResolveSource{}, wrapResolutionError(gvk, fmt.Errorf("from definitions: %w, nor from discovery: %w", unwrapResolutionError(gvk, err), unwrapResolutionError(gvk, fallbackErr)))
+		}
+		return s, source, nil
+	}
+	s, source, err := r.Discovery.resolve(ctx, gvk)
+	if err == nil || ctx.Err() != nil {
+		return s, source, err
+	}
+	if !errors.Is(err, ErrSchemaNotFound) {
+		klog.V(2).InfoS("Falling back to the compiled definitions after failing to resolve schema from discovery", "gvk", gvk, "err", err)
+	}
+	s, source, fallbackErr := r.resolveFromDefinitions(ctx, gvk)
+	if fallbackErr != nil {
+		return nil, ResolveSource{}, wrapResolutionError(gvk, fmt.Errorf("from discovery: %w, nor from definitions: %w", unwrapResolutionError(gvk, err), unwrapResolutionError(gvk, fallbackErr)))
+	}
+	return s, source, nil
+}
+
+// resolveFromDefinitions resolves the schema of gvk from the Definitions.
+func (r *ResilientResolver) resolveFromDefinitions(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, ResolveSource, error) {
+	s, err := r.Definitions.ResolveSchemaWithContext(ctx, gvk)
+	if err != nil {
+		return nil, ResolveSource{}, err
+	}
+	return s, ResolveSource{GVK: gvk, Origin: DiagnosticSourceDefinitions}, nil
+}
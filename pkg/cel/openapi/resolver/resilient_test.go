@@ -0,0 +1,205 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/openapi/openapitest"
+	"k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestResilientResolver(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	subscription := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	scheme := runtime.NewScheme()
+	configMap := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	scheme.AddKnownTypeWithName(pod, &corev1.Pod{})
+	scheme.AddKnownTypeWithName(configMap, &corev1.ConfigMap{})
+	definitions := NewDefinitionsSchemaResolver(func(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		return map[string]common.OpenAPIDefinition{
+			"k8s.io/api/core/v1.Pod": {Schema: *objectSchema(map[string]spec.Schema{
+				"fromDefinitions": scalarSchema("string", ""),
+			})},
+			// the definition of ConfigMap cannot be resolved.
+			"k8s.io/api/core/v1.ConfigMap": {Schema: *objectSchema(map[string]spec.Schema{
+				"data": {SchemaProps: spec.SchemaProps{Ref: ref("k8s.io/api/core/v1.Missing")}},
+			})},
+		}
+	}, scheme)
+	errTransport := errors.New("connection refused")
+
+	unavailable := func() *ClientDiscoveryResolver {
+		client := openapitest.NewFakeClient()
+		client.ForcedErr = errTransport
+		return &ClientDiscoveryResolver{Discovery: newFakeDiscovery(client)}
+	}
+
+	for _, tc := range []struct {
+		name      string
+		discovery *ClientDiscoveryResolver
+		// withoutDefinitions leaves the Definitions unset.
+		withoutDefinitions bool
+		preferDefinitions  bool
+		gvk                schema.GroupVersionKind
+		expectedOrigin     DiagnosticSource
+		expectedErr        error
+		expectedMsg        string
+	}{
+		{
+			name:           "discovery",
+			discovery:      newEmbeddedDiscoveryResolver(),
+			gvk:            pod,
+			expectedOrigin: DiagnosticSourceDiscovery,
+		},
+		{
+			name:           "fallback on transport error",
+			discovery:      unavailable(),
+			gvk:            pod,
+			expectedOrigin: DiagnosticSourceDefinitions,
+		},
+		{
+			name: "fallback on group-version transport error",
+			discovery: func() *ClientDiscoveryResolver {
+				client := openapitest.NewFakeClient()
+				client.PathsMap["api/v1"] = openapitest.FakeGroupVersion{ForcedErr: errTransport}
+				return &ClientDiscoveryResolver{Discovery: newFakeDiscovery(client)}
+			}(),
+			gvk:            pod,
+			expectedOrigin: DiagnosticSourceDefinitions,
+		},
+		{
+			name:           "fallback on panic",
+			discovery:      &ClientDiscoveryResolver{Discovery: newFakeDiscovery(panickingOpenAPIClient{})},
+			gvk:            pod,
+			expectedOrigin: DiagnosticSourceDefinitions,
+		},
+		{
+			name:           "fallback on not found",
+			discovery:      newDocumentsDiscoveryResolver(nil),
+			gvk:            pod,
+			expectedOrigin: DiagnosticSourceDefinitions,
+		},
+		{
+			name:        "discovery and definitions fail",
+			discovery:   unavailable(),
+			gvk:         configMap,
+			expectedErr: errTransport,
+			expectedMsg: `cannot resolve /v1, Kind=ConfigMap: from discovery: cannot list the OpenAPI v3 paths: discovery unavailable: connection refused, nor from definitions: internal error: cannot resolve Ref "k8s.io/api/core/v1.Missing": schema not found`,
+		},
+		{
+			name:              "prefer definitions",
+			discovery:         newEmbeddedDiscoveryResolver(),
+			preferDefinitions: true,
+			gvk:               pod,
+			expectedOrigin:    DiagnosticSourceDefinitions,
+		},
+		{
+			name:              "prefer definitions with fallback to discovery",
+			discovery:         newEmbeddedDiscoveryResolver(),
+			preferDefinitions: true,
+			gvk:               configMap,
+			expectedOrigin:    DiagnosticSourceDiscovery,
+		},
+		{
+			name:              "prefer definitions for a type unknown to the definitions",
+			discovery:         newDocumentsDiscoveryResolver(nil),
+			preferDefinitions: true,
+			gvk:               subscription,
+			expectedErr:       ErrSchemaNotFound,
+		},
+		{
+			name:              "prefer definitions, both fail",
+			discovery:         unavailable(),
+			preferDefinitions: true,
+			gvk:               configMap,
+			expectedErr:       errTransport,
+			expectedMsg:       `cannot resolve /v1, Kind=ConfigMap: from definitions: internal error: cannot resolve Ref "k8s.io/api/core/v1.Missing": schema not found, nor from discovery: cannot list the OpenAPI v3 paths: discovery unavailable: connection refused`,
+		},
+		{
+			name:        "no fallback for a type unknown to the definitions",
+			discovery:   unavailable(),
+			gvk:         subscription,
+			expectedErr: errTransport,
+		},
+		{
+			name:        "not found",
+			discovery:   newDocumentsDiscoveryResolver(nil),
+			gvk:         subscription,
+			expectedErr: ErrSchemaNotFound,
+		},
+		{
+			name:               "discovery only",
+			discovery:          newEmbeddedDiscoveryResolver(),
+			withoutDefinitions: true,
+			gvk:                pod,
+			expectedOrigin:     DiagnosticSourceDiscovery,
+		},
+		{
+			name:               "discovery only on transport error",
+			discovery:          unavailable(),
+			withoutDefinitions: true,
+			gvk:                pod,
+			expectedErr:        errTransport,
+		},
+		{
+			name:           "definitions only",
+			gvk:            pod,
+			expectedOrigin: DiagnosticSourceDefinitions,
+		},
+		{
+			name:        "definitions only for a type unknown to the definitions",
+			gvk:         subscription,
+			expectedErr: ErrSchemaNotFound,
+		},
+		{
+			name:               "neither",
+			withoutDefinitions: true,
+			gvk:                pod,
+			expectedErr:        ErrSchemaNotFound,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &ResilientResolver{Discovery: tc.discovery, Definitions: definitions, PreferDefinitions: tc.preferDefinitions}
+			if tc.withoutDefinitions {
+				r.Definitions = nil
+			}
+			s, source, err := r.ResolveSchemaWithSource(tc.gvk)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err != nil {
+				if len(tc.expectedMsg) > 0 && err.Error() != tc.expectedMsg {
+					t.Errorf("expected error %q, got %q", tc.expectedMsg, err.Error())
+				}
+				return
+			}
+			if source.Origin != tc.expectedOrigin {
+				t.Errorf("expected origin %q, got %q", tc.expectedOrigin, source.Origin)
+			}
+			_, fromDefinitions := s.Properties["fromDefinitions"]
+			if expected := tc.expectedOrigin == DiagnosticSourceDefinitions; fromDefinitions != expected {
+				t.Errorf("expected the schema from the definitions to be %v, got %v", expected, fromDefinitions)
+			}
+		})
+	}
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package resolver
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -31,9 +33,55 @@ type SchemaResolver interface {
 	// The function returns a non-nil error if the schema cannot be found or fail
 	// to resolve. The returned error wraps ErrSchemaNotFound if the resolution is
 	// attempted but the corresponding schema cannot be found.
+	// It is equivalent to ResolveSchemaWithContext with context.Background().
 	ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error)
+
+	// ResolveSchemaWithContext resolves the schema like ResolveSchema and
+	// aborts the resolution once ctx is done, e.g. when the request that
+	// needs the schema times out. The returned error then wraps ctx.Err(),
+	// which distinguishes it from ErrSchemaNotFound.
+	ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error)
 }
 
 // ErrSchemaNotFound is wrapped and returned if the schema cannot be located
 // by the resolver.
 var ErrSchemaNotFound = fmt.Errorf("schema not found")
+
+// resolutionError is an error of the resolution of a GVK.
+type resolutionError struct {
+	gvk schema.GroupVersionKind
+	err error
+}
+
+func (e *resolutionError) Error() string {
+	return fmt.Sprintf("cannot resolve %v: %v", e.gvk, e.err)
+}
+
+func (e *resolutionError) Unwrap() error {
+	return e.err
+}
+
+// wrapResolutionError returns err, if not nil, prefixed with the GVK whose
+// resolution failed as "cannot resolve <gvk>: ", unless it already names that
+// GVK this way, so that every error of a resolver tells which GVK failed.
+// The wrapped errors, e.g. ErrSchemaNotFound, are kept for errors.Is.
+func wrapResolutionError(gvk schema.GroupVersionKind, err error) error {
+	if err == nil {
+		return nil
+	}
+	var wrapped *resolutionError
+	if errors.As(err, &wrapped) && wrapped.gvk == gvk {
+		return err
+	}
+	return &resolutionError{gvk: gvk, err: err}
+}
+
+// unwrapResolutionError returns the error that err wraps if err is the
+// resolution error of gvk, and err otherwise, to add context to err before
+// wrapping it again with wrapResolutionError.
+func unwrapResolutionError(gvk schema.GroupVersionKind, err error) error {
+	if wrapped, ok := err.(*resolutionError); ok && wrapped.gvk == gvk {
+		return wrapped.err
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/openapi/openapitest"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestWrapResolutionError(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	service := schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	if err := wrapResolutionError(pod, nil); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	err := wrapResolutionError(pod, fmt.Errorf("no document: %w", ErrSchemaNotFound))
+	if expected := `cannot resolve /v1, Kind=Pod: no document: schema not found`; err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+	if !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound to be wrapped, got %v", err)
+	}
+	if again := wrapResolutionError(pod, err); again != err {
+		t.Errorf("expected an error naming the GVK to be kept, got %v", again)
+	}
+	// e.g. a Service whose resolution failed on its Pod selector.
+	if nested := wrapResolutionError(service, err); !strings.HasPrefix(nested.Error(), "cannot resolve /v1, Kind=Service: cannot resolve /v1, Kind=Pod: ") {
+		t.Errorf("expected the error of another GVK to be wrapped, got %v", nested)
+	}
+}
+
+func TestResolutionErrorsNameGVK(t *testing.T) {
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	malformed := openapitest.NewFakeClient()
+	malformed.PathsMap["apis/apps/v1"] = openapitest.FakeGroupVersion{GVSpec: []byte("{")}
+	unavailable := openapitest.NewFakeClient()
+	unavailable.ForcedErr = errors.New("connection refused")
+	definitions := NewDefinitionsSchemaResolver(embeddedDefinitions(t, "apis/apps/v1"), scheme.Scheme)
+	errList := errors.New("list failed")
+	failingList := func() ([]*unstructured.Unstructured, error) {
+		return nil, errList
+	}
+	emptyList := func() ([]*unstructured.Unstructured, error) {
+		return nil, nil
+	}
+	unservedVersion := NewCRDSchemaResolver(newCRD("apiextensions.k8s.io/v1", deployment.Group, deployment.Kind, map[string]interface{}{
+		"versions": []interface{}{map[string]interface{}{"name": "v1beta1"}},
+	}))
+
+	for _, tc := range []struct {
+		name     string
+		resolver SchemaResolver
+		ctx      context.Context
+		wantErr  error
+	}{
+		{name: "discovery unavailable", resolver: &ClientDiscoveryResolver{Discovery: newFakeDiscovery(unavailable)}, wantErr: ErrDiscoveryUnavailable},
+		{name: "malformed document", resolver: &ClientDiscoveryResolver{Discovery: newFakeDiscovery(malformed)}},
+		{name: "discovery not found", resolver: &ClientDiscoveryResolver{Discovery: newFakeDiscovery(openapitest.NewFakeClient())}, wantErr: ErrSchemaNotFound},
+		{name: "discovery canceled", resolver: newEmbeddedDiscoveryResolver(), ctx: canceled, wantErr: context.Canceled},
+		{name: "definitions not found", resolver: NewDefinitionsSchemaResolver(embeddedDefinitions(t, "api/v1"), scheme.Scheme), wantErr: ErrSchemaNotFound},
+		{name: "definitions canceled", resolver: definitions, ctx: canceled, wantErr: context.Canceled},
+		{name: "APIServices unavailable", resolver: &APIServiceResolver{APIServices: failingList}, wantErr: errList},
+		{name: "no APIService", resolver: &APIServiceResolver{APIServices: emptyList}, wantErr: ErrSchemaNotFound},
+		{name: "CRDs unavailable", resolver: &CRDSchemaResolver{CRDs: failingList}, wantErr: errList},
+		{name: "no CRD", resolver: &CRDSchemaResolver{CRDs: emptyList}, wantErr: ErrSchemaNotFound},
+		{name: "CRD version not served", resolver: unservedVersion, wantErr: ErrSchemaNotFound},
+		{name: "CRD canceled", resolver: unservedVersion, ctx: canceled, wantErr: context.Canceled},
+		{name: "no bytes", resolver: NewBytesResolver(nil), wantErr: ErrSchemaNotFound},
+		{name: "malformed bytes", resolver: NewBytesResolver(map[string][]byte{"apis/apps/v1": []byte("{")})},
+		{name: "bytes not declaring the kind", resolver: NewBytesResolver(map[string][]byte{"apis/apps/v1": newDocument(map[string]*spec.Schema{"io.k8s.api.apps.v1.Other": objectSchema(nil)})}), wantErr: ErrSchemaNotFound},
+		{name: "no file", resolver: NewFileSchemaResolver(nil), wantErr: ErrSchemaNotFound},
+		{name: "file canceled", resolver: NewFileSchemaResolver(nil), ctx: canceled, wantErr: context.Canceled},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := tc.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			_, err := tc.resolver.ResolveSchemaWithContext(ctx, deployment)
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			prefix := fmt.Sprintf("cannot resolve %v: ", deployment)
+			if !strings.HasPrefix(err.Error(), prefix) || strings.Count(err.Error(), prefix) != 1 {
+				t.Errorf("expected the error to be prefixed with %q once, got %q", prefix, err.Error())
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Errorf("expected %v to be wrapped, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resolvertest provides a fake SchemaResolver for testing.
+package resolvertest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/cel/openapi/resolver"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// FakeResolver is a resolver.SchemaResolver that resolves the schemas
+// registered with Register, and records the GVKs it was asked to resolve.
+// It is safe for concurrent use.
+type FakeResolver struct {
+	lock    sync.Mutex
+	schemas map[schema.GroupVersionKind]*spec.Schema
+	errs    map[schema.GroupVersionKind]error
+	calls   []schema.GroupVersionKind
+}
+
+var _ resolver.SchemaResolver = (*FakeResolver)(nil)
+
+// NewFakeResolver creates a FakeResolver without schemas.
+func NewFakeResolver() *FakeResolver {
+	return &FakeResolver{
+		schemas: make(map[schema.GroupVersionKind]*spec.Schema),
+		errs:    make(map[schema.GroupVersionKind]error),
+	}
+}
+
+// Register sets the schema of the GVK, replacing any previous one. The
+// schema is copied, so later changes to s are not visible to resolutions.
+func (f *FakeResolver) Register(gvk schema.GroupVersionKind, s *spec.Schema) {
+	copied, err := deepCopy(s)
+	if err != nil {
+		panic(err)
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.schemas[gvk] = copied
+}
+
+// SetError makes the resolutions of the GVK fail with err, which takes
+// precedence over a registered schema, or succeed again if err is nil.
+func (f *FakeResolver) SetError(gvk schema.GroupVersionKind, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if err == nil {
+		delete(f.errs, gvk)
+		return
+	}
+	f.errs[gvk] = err
+}
+
+// ResolveSchema returns a copy of the schema registered for the GVK, or the
+// error set for it. It fails with an error wrapping
+// resolver.ErrSchemaNotFound if neither is.
+func (f *FakeResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return f.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema unless ctx
+// is already done.
+func (f *FakeResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	f.lock.Lock()
+	f.calls = append(f.calls, gvk)
+	s, ok := f.schemas[gvk]
+	err := f.errs[gvk]
+	f.lock.Unlock()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, fmt.Errorf("cannot resolve %v: %w", gvk, ctxErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("cannot resolve %v: %w", gvk, resolver.ErrSchemaNotFound)
+	}
+	return deepCopy(s)
+}
+
+// Calls returns the GVKs that the resolver was asked to resolve, in order.
+func (f *FakeResolver) Calls() []schema.GroupVersionKind {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]schema.GroupVersionKind(nil), f.calls...)
+}
+
+// Reset forgets the recorded calls.
+func (f *FakeResolver) Reset() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.calls = nil
+}
+
+func deepCopy(s *spec.Schema) (*spec.Schema, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("cannot deep copy schema: %w", err)
+	}
+	result := new(spec.Schema)
+	if err := json.Unmarshal(b, result); err != nil {
+		return nil, fmt.Errorf("cannot deep copy schema: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolvertest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/cel/openapi/resolver"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestFakeResolver(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	service := schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	missing := schema.GroupVersionKind{Version: "v1", Kind: "Missing"}
+	registered := &spec.Schema{SchemaProps: spec.SchemaProps{
+		Type:       []string{"object"},
+		Properties: map[string]spec.Schema{"spec": *spec.StringProperty()},
+	}}
+	injected := errors.New("injected")
+	f := NewFakeResolver()
+	f.Register(pod, registered)
+	f.Register(service, registered)
+	f.SetError(service, injected)
+
+	s, err := f.ResolveSchema(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(s, registered) {
+		t.Errorf("expected the registered schema, got %v", s)
+	}
+	s.Properties["status"] = *spec.StringProperty()
+	registered.Properties["metadata"] = *spec.StringProperty()
+	if again, _ := f.ResolveSchema(pod); len(again.Properties) != 1 {
+		t.Errorf("expected the registered schema to be copied, got %v", again)
+	}
+	if _, err := f.ResolveSchema(service); err != injected {
+		t.Errorf("expected the injected error, got %v", err)
+	}
+	if _, err := f.ResolveSchema(missing); !errors.Is(err, resolver.ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound, got %v", err)
+	}
+	f.SetError(service, nil)
+	if _, err := f.ResolveSchema(service); err != nil {
+		t.Errorf("unexpected error after clearing the injected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := f.ResolveSchemaWithContext(ctx, pod); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	if expected := []schema.GroupVersionKind{pod, pod, service, missing, service, pod}; !reflect.DeepEqual(f.Calls(), expected) {
+		t.Errorf("expected calls %v, got %v", expected, f.Calls())
+	}
+	f.Reset()
+	if calls := f.Calls(); len(calls) != 0 {
+		t.Errorf("expected no calls after Reset, got %v", calls)
+	}
+}
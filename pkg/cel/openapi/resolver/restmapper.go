@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// RESTMapperResolver wraps a SchemaResolver and resolves the schemas of
+// resources by mapping them to their kinds with a RESTMapper, e.g. the one of
+// a controller-runtime client.
+type RESTMapperResolver struct {
+	Delegate SchemaResolver
+	Mapper   meta.RESTMapper
+}
+
+var _ SchemaResolver = (*RESTMapperResolver)(nil)
+
+// ResolveSchema resolves the schema of the GVK with the delegate.
+func (r *RESTMapperResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema, passing ctx
+// to the delegate.
+func (r *RESTMapperResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.Delegate.ResolveSchemaWithContext(ctx, gvk)
+}
+
+// ResolveSchemaForResource maps the resource to its kind and resolves the
+// schema of that kind. The group and version of the resource may be partial,
+// in which case the mapper picks the kind. If the mapper knows no such
+// resource, the returned error wraps both ErrSchemaNotFound and the error of
+// the mapper; other errors of the mapper, e.g. for ambiguous resources, are
+// wrapped as is, so that meta.IsNoMatchError and meta.IsAmbiguousError
+// work on the result.
+func (r *RESTMapperResolver) ResolveSchemaForResource(gvr schema.GroupVersionResource) (*spec.Schema, error) {
+	gvk, err := r.Mapper.KindFor(gvr)
+	if meta.IsNoMatchError(err) {
+		return nil, fmt.Errorf("cannot map resource %v to a kind: %w: %w", gvr, ErrSchemaNotFound, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot map resource %v to a kind: %w", gvr, err)
+	}
+	return r.Delegate.ResolveSchema(gvk)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRESTMapperResolver(t *testing.T) {
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	deploymentBeta := schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Deployment"}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(deployment, meta.RESTScopeNamespace)
+	mapper.Add(deploymentBeta, meta.RESTScopeNamespace)
+	expected := objectSchema(nil)
+	r := &RESTMapperResolver{
+		Delegate: staticResolver{deployment: expected, deploymentBeta: objectSchema(nil)},
+		Mapper:   mapper,
+	}
+
+	for _, tc := range []struct {
+		name    string
+		gvr     schema.GroupVersionResource
+		check   func(error) bool
+		wantErr string
+	}{
+		{name: "fully qualified", gvr: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}},
+		{name: "partial version", gvr: schema.GroupVersionResource{Group: "apps", Resource: "deployments"}},
+		{name: "singular", gvr: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployment"}},
+		{
+			name:    "unknown",
+			gvr:     schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "widgets"},
+			check:   func(err error) bool { return errors.Is(err, ErrSchemaNotFound) && meta.IsNoMatchError(err) },
+			wantErr: "ErrSchemaNotFound and a no match error",
+		},
+		{
+			name:    "ambiguous",
+			gvr:     schema.GroupVersionResource{Resource: "deployments"},
+			check:   meta.IsAmbiguousError,
+			wantErr: "an ambiguous error",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := r.ResolveSchemaForResource(tc.gvr)
+			if tc.check != nil {
+				if !tc.check(err) {
+					t.Errorf("expected %s, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s != expected {
+				t.Errorf("expected the schema of %v, got %v", deployment, s)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ResolveSource describes the provenance of a resolved schema.
+type ResolveSource struct {
+	// GVK is the GVK whose schema was resolved. It differs from the requested
+	// GVK if the resolver substituted another version.
+	GVK schema.GroupVersionKind
+	// Substituted is true if the schema of another GVK than the requested one
+	// was resolved.
+	Substituted bool
+	// Origin is where the schema was resolved from, e.g. discovery or the
+	// compiled definitions. It is empty if the resolver does not report it.
+	Origin DiagnosticSource
+	// Endpoint is the path of the endpoint that served the document the
+	// schema was resolved from, e.g. /openapi/v3/apis/apps/v1, or
+	// /openapi/v2 with AllowV2Fallback. It is empty unless the schema was
+	// resolved from discovery.
+	Endpoint string
+	// SpecVersion is the OpenAPI version of the document at Endpoint, e.g.
+	// to confirm that a cluster serves OpenAPI v3.
+	SpecVersion SpecVersion
+}
+
+// SpecVersion is a version of the OpenAPI specification.
+type SpecVersion string
+
+const (
+	SpecVersionV2 SpecVersion = "v2"
+	SpecVersionV3 SpecVersion = "v3"
+)
+
+// v2Endpoint is the path of the OpenAPI v2 document.
+const v2Endpoint = "/openapi/v2"
+
+// discoverySource returns the source of a schema of gvk resolved from the
+// OpenAPI v3 document of its group-version.
+func discoverySource(gvk schema.GroupVersionKind, substituted bool) ResolveSource {
+	return ResolveSource{
+		GVK:         gvk,
+		Substituted: substituted,
+		Origin:      DiagnosticSourceDiscovery,
+		Endpoint:    "/openapi/v3/" + resourcePathFromGV(gvk.GroupVersion()),
+		SpecVersion: SpecVersionV3,
+	}
+}
+
+// SourceResolver is implemented by resolvers that report the provenance of
+// the schemas they resolve.
+type SourceResolver interface {
+	SchemaResolver
+
+	// ResolveSchemaWithSource resolves the schema like ResolveSchema and also
+	// returns its provenance.
+	ResolveSchemaWithSource(gvk schema.GroupVersionKind) (*spec.Schema, ResolveSource, error)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestClosestVersionFallback(t *testing.T) {
+	v1beta1 := schema.GroupVersionKind{Group: "apps", Version: "v1beta1", Kind: "Deployment"}
+	v1alpha1 := schema.GroupVersionKind{Group: "apps", Version: "v1alpha1", Kind: "Deployment"}
+	beta := withGVK(*objectSchema(map[string]spec.Schema{"beta": scalarSchema("string", "")}), v1beta1)
+	alpha := withGVK(*objectSchema(map[string]spec.Schema{"alpha": scalarSchema("string", "")}), v1alpha1)
+	r := newDocumentsDiscoveryResolver(map[string][]byte{
+		"apis/apps/v1beta1":  newDocument(map[string]*spec.Schema{"io.k8s.api.apps.v1beta1.Deployment": &beta}),
+		"apis/apps/v1alpha1": newDocument(map[string]*spec.Schema{"io.k8s.api.apps.v1alpha1.Deployment": &alpha}),
+	})
+	requested := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+
+	if _, err := r.ResolveSchema(requested); !errors.Is(err, ErrSchemaNotFound) {
+		t.Fatalf("expected ErrSchemaNotFound without fallback, got %v", err)
+	}
+
+	r.ClosestVersionFallback = true
+	s, source, err := r.ResolveSchemaWithSource(requested)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := s.Properties["beta"]; !ok {
+		t.Errorf("expected the schema of v1beta1, got %v", s)
+	}
+	if expected := (ResolveSource{GVK: v1beta1, Substituted: true, Origin: DiagnosticSourceDiscovery, Endpoint: "/openapi/v3/apis/apps/v1beta1", SpecVersion: SpecVersionV3}); source != expected {
+		t.Errorf("expected source %v, got %v", expected, source)
+	}
+
+	_, source, err = r.ResolveSchemaWithSource(v1alpha1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := (ResolveSource{GVK: v1alpha1, Origin: DiagnosticSourceDiscovery, Endpoint: "/openapi/v3/apis/apps/v1alpha1", SpecVersion: SpecVersionV3}); source != expected {
+		t.Errorf("expected source %v, got %v", expected, source)
+	}
+
+	if _, err := r.ResolveSchema(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Missing"}); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound for an unknown kind, got %v", err)
+	}
+}
+
+func TestClosestVersions(t *testing.T) {
+	got := closestVersions("v1beta1", []string{"v1alpha1", "v2", "v1", "v1beta2", "v1beta1"})
+	expected := []string{"v1beta2", "v1alpha1", "v1", "v2"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ErrInvalidListMapKeys is wrapped and returned in strict mode if a list of
+// type map declares a map key that is not a property of its items.
+var ErrInvalidListMapKeys = errors.New("invalid list map keys")
+
+// ErrInvalidEnum is wrapped and returned in strict mode if an enum value does
+// not match the type of its schema.
+var ErrInvalidEnum = errors.New("invalid enum")
+
+// ErrRequiredFieldMissing is wrapped and returned in strict mode if an object
+// requires a field that it neither defines as a property nor permits through
+// additionalProperties or x-kubernetes-preserve-unknown-fields.
+var ErrRequiredFieldMissing = errors.New("required field missing")
+
+// StrictResolver wraps a SchemaResolver and validates every resolved schema
+// with ValidateStrict, so that malformed schemas, for example from untrusted
+// member clusters, are rejected before they reach CEL.
+type StrictResolver struct {
+	Delegate SchemaResolver
+}
+
+var _ SchemaResolver = (*StrictResolver)(nil)
+
+// ResolveSchema resolves the schema with the delegate and returns it only if
+// it passes strict validation.
+func (r *StrictResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema, passing ctx
+// to the delegate.
+func (r *StrictResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	s, err := r.Delegate.ResolveSchemaWithContext(ctx, gvk)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateStrict(s); err != nil {
+		return nil, fmt.Errorf("schema of %v is invalid: %w", gvk, err)
+	}
+	return s, nil
+}
+
+// ValidateStrict performs strict checks over a resolved schema and returns the
+// first violation found, annotated with the path of the offending node.
+func ValidateStrict(s *spec.Schema) error {
+	return WalkSchema(s, func(path string, node *spec.Schema) error {
+		if err := validateListMapKeys(path, node); err != nil {
+			return err
+		}
+		if err := validateRequired(path, node); err != nil {
+			return err
+		}
+		return validateEnum(path, node)
+	})
+}
+
+// validateRequired checks that every required field of an object is either a
+// property or permitted as an unknown field.
+func validateRequired(path string, s *spec.Schema) error {
+	if len(s.Required) == 0 {
+		return nil
+	}
+	if isXPreserveUnknownFields(s) || (s.AdditionalProperties != nil && (s.AdditionalProperties.Allows || s.AdditionalProperties.Schema != nil)) {
+		return nil
+	}
+	for _, name := range s.Required {
+		if _, ok := s.Properties[name]; !ok {
+			return fmt.Errorf("%s: required field %q is not a property: %w", path, name, ErrRequiredFieldMissing)
+		}
+	}
+	return nil
+}
+
+// validateListMapKeys checks that every map key of a list of type map names
+// a property of the item schema.
+func validateListMapKeys(path string, s *spec.Schema) error {
+	if getXListType(s) != "map" {
+		return nil
+	}
+	keys := getXListMapKeys(s)
+	if len(keys) == 0 {
+		return fmt.Errorf("%s: list of type map declares no map keys: %w", path, ErrInvalidListMapKeys)
+	}
+	if s.Items == nil || s.Items.Schema == nil {
+		return fmt.Errorf("%s: list of type map declares no item schema: %w", path, ErrInvalidListMapKeys)
+	}
+	for _, key := range keys {
+		if _, ok := s.Items.Schema.Properties[key]; !ok {
+			return fmt.Errorf("%s: map key %q is not a property of the items: %w", path, key, ErrInvalidListMapKeys)
+		}
+	}
+	return nil
+}
+
+// validateEnum checks that every enum value matches the declared type.
+// Schemas that declare no type accept any value.
+func validateEnum(path string, s *spec.Schema) error {
+	if len(s.Type) == 0 {
+		return nil
+	}
+	for _, v := range s.Enum {
+		if v == nil && s.Nullable {
+			continue
+		}
+		ok := false
+		for _, typ := range s.Type {
+			if enumValueHasType(v, typ) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf("%s: enum value %v (%T) is not of type %v: %w", path, v, v, s.Type, ErrInvalidEnum)
+		}
+	}
+	return nil
+}
+
+func enumValueHasType(v interface{}, typ string) bool {
+	switch typ {
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "number":
+		switch v.(type) {
+		case float64, float32, int, int32, int64, json.Number:
+			return true
+		}
+		return false
+	case "integer":
+		switch n := v.(type) {
+		case int, int32, int64:
+			return true
+		case float64:
+			return n == math.Trunc(n)
+		case json.Number:
+			_, err := n.Int64()
+			return err == nil
+		}
+		return false
+	}
+	// unknown types are left to other validations.
+	return true
+}
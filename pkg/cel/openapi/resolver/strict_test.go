@@ -0,0 +1,143 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// listMapSchema returns a list of type map over items with the given properties.
+func listMapSchema(keys []string, itemProps ...string) spec.Schema {
+	props := make(map[string]spec.Schema, len(itemProps))
+	for _, name := range itemProps {
+		props[name] = scalarSchema("string", "")
+	}
+	s := spec.Schema{SchemaProps: spec.SchemaProps{
+		Type:  []string{"array"},
+		Items: &spec.SchemaOrArray{Schema: objectSchema(props)},
+	}}
+	s.AddExtension(extListType, "map")
+	mapKeys := make([]any, 0, len(keys))
+	for _, key := range keys {
+		mapKeys = append(mapKeys, key)
+	}
+	s.AddExtension(extListMapKeys, mapKeys)
+	return s
+}
+
+func enumSchema(typ string, values ...interface{}) spec.Schema {
+	s := scalarSchema(typ, "")
+	s.Enum = values
+	return s
+}
+
+func TestValidateStrict(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		schema  *spec.Schema
+		wantErr error
+	}{
+		{
+			name: "valid list map keys",
+			schema: objectSchema(map[string]spec.Schema{
+				"containers": listMapSchema([]string{"name"}, "name", "image"),
+			}),
+		},
+		{
+			name: "list map key is not an item property",
+			schema: objectSchema(map[string]spec.Schema{
+				"ports": listMapSchema([]string{"containerPort", "protocol"}, "containerPort"),
+			}),
+			wantErr: ErrInvalidListMapKeys,
+		},
+		{
+			name: "nested list map key is not an item property",
+			schema: objectSchema(map[string]spec.Schema{
+				"spec": *objectSchema(map[string]spec.Schema{
+					"containers": listMapSchema([]string{"id"}, "name"),
+				}),
+			}),
+			wantErr: ErrInvalidListMapKeys,
+		},
+		{
+			name: "consistent enum",
+			schema: objectSchema(map[string]spec.Schema{
+				"protocol": enumSchema("string", "TCP", "UDP"),
+				"replicas": enumSchema("integer", float64(1), float64(3)),
+			}),
+		},
+		{
+			name: "numeric value in string enum",
+			schema: objectSchema(map[string]spec.Schema{
+				"spec": *objectSchema(map[string]spec.Schema{
+					"protocol": enumSchema("string", "TCP", float64(6)),
+				}),
+			}),
+			wantErr: ErrInvalidEnum,
+		},
+		{
+			name: "fractional value in integer enum",
+			schema: objectSchema(map[string]spec.Schema{
+				"replicas": enumSchema("integer", float64(1.5)),
+			}),
+			wantErr: ErrInvalidEnum,
+		},
+		{
+			name: "consistent required fields",
+			schema: objectSchema(map[string]spec.Schema{
+				"spec": *objectSchema(map[string]spec.Schema{
+					"selector": scalarSchema("string", ""),
+				}, "selector"),
+			}, "spec"),
+		},
+		{
+			name: "required field without property",
+			schema: objectSchema(map[string]spec.Schema{
+				"spec": *objectSchema(map[string]spec.Schema{
+					"selector": scalarSchema("string", ""),
+				}, "selector", "template"),
+			}),
+			wantErr: ErrRequiredFieldMissing,
+		},
+		{
+			name: "required field permitted by additionalProperties",
+			schema: &spec.Schema{SchemaProps: spec.SchemaProps{
+				Type:                 []string{"object"},
+				Required:             []string{"app"},
+				AdditionalProperties: &spec.SchemaOrBool{Allows: true, Schema: &spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"string"}}}},
+			}},
+		},
+		{
+			name: "required field permitted by preserve unknown fields",
+			schema: func() *spec.Schema {
+				s := objectSchema(nil, "anything")
+				s.AddExtension(extPreserveUnknownFields, true)
+				return s
+			}(),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateStrict(tc.schema)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// StripMetadata returns a SchemaResolver that resolves schemas with the given
+// resolver and then clears the description, title, example and external
+// documentation of every node, including the members of allOf, anyOf, oneOf
+// and not, which CEL does not use and which make up much of the size of
+// large schemas. It composes with any resolver; ClientDiscoveryResolver and
+// DefinitionsSchemaResolver can strip them as well with
+// PopulateRefsOptions.StripMetadata.
+func StripMetadata(delegate SchemaResolver) SchemaResolver {
+	return &metadataStrippingResolver{delegate: delegate}
+}
+
+type metadataStrippingResolver struct {
+	delegate SchemaResolver
+}
+
+func (r *metadataStrippingResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema, passing ctx
+// to the delegate.
+func (r *metadataStrippingResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	s, err := r.delegate.ResolveSchemaWithContext(ctx, gvk)
+	if err != nil {
+		return nil, err
+	}
+	return stripMetadata(s)
+}
+
+// stripMetadata returns s without the metadata of its nodes, see
+// StripMetadata. The nodes that have none are shared with s.
+func stripMetadata(s *spec.Schema) (*spec.Schema, error) {
+	return transformSchema(s, stripNodeMetadata)
+}
+
+func stripNodeMetadata(s *spec.Schema) (*spec.Schema, error) {
+	hasMetadata := len(s.Description) > 0 || len(s.Title) > 0 || s.Example != nil || s.ExternalDocs != nil
+	if !hasMetadata && len(s.AllOf) == 0 && len(s.AnyOf) == 0 && len(s.OneOf) == 0 && s.Not == nil {
+		return s, nil
+	}
+	result := *s
+	result.Description, result.Title, result.Example, result.ExternalDocs = "", "", nil, nil
+	// transformSchema does not descend into the combinators.
+	var err error
+	if result.AllOf, err = stripMembers(s.AllOf); err != nil {
+		return nil, err
+	}
+	if result.AnyOf, err = stripMembers(s.AnyOf); err != nil {
+		return nil, err
+	}
+	if result.OneOf, err = stripMembers(s.OneOf); err != nil {
+		return nil, err
+	}
+	if s.Not != nil {
+		if result.Not, err = stripMetadata(s.Not); err != nil {
+			return nil, err
+		}
+	}
+	return &result, nil
+}
+
+// stripMembers returns a copy of the members of a combinator without their
+// metadata.
+func stripMembers(members []spec.Schema) ([]spec.Schema, error) {
+	if len(members) == 0 {
+		return members, nil
+	}
+	result := make([]spec.Schema, len(members))
+	for i := range members {
+		stripped, err := stripMetadata(&members[i])
+		if err != nil {
+			return nil, err
+		}
+		result[i] = *stripped
+	}
+	return result, nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"encoding/json"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// metadataPaths returns the paths of the nodes of s, including the members
+// of combinators, that declare a description, title, example or external
+// documentation.
+func metadataPaths(s *spec.Schema) []string {
+	var paths []string
+	var visit func(path string, node *spec.Schema) error
+	visit = func(path string, node *spec.Schema) error {
+		if len(node.Description) > 0 || len(node.Title) > 0 || node.Example != nil || node.ExternalDocs != nil {
+			paths = append(paths, path)
+		}
+		for _, members := range [][]spec.Schema{node.AllOf, node.AnyOf, node.OneOf} {
+			for i := range members {
+				_ = WalkSchema(&members[i], visit)
+			}
+		}
+		return nil
+	}
+	_ = WalkSchema(s, visit)
+	return paths
+}
+
+func TestStripMetadata(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	r := newEmbeddedDiscoveryResolver()
+	original, err := r.ResolveSchema(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(metadataPaths(original)) == 0 {
+		t.Fatalf("expected the schema of %v to have descriptions", pod)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		resolver SchemaResolver
+	}{
+		{name: "resolver", resolver: StripMetadata(newEmbeddedDiscoveryResolver())},
+		{name: "option", resolver: &ClientDiscoveryResolver{
+			Discovery:  newEmbeddedDiscoveryResolver().Discovery,
+			RefOptions: PopulateRefsOptions{StripMetadata: true},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := tc.resolver.ResolveSchema(pod)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if paths := metadataPaths(s); len(paths) > 0 {
+				t.Errorf("expected no metadata, got some at %v", paths)
+			}
+			// e.g. the IntOrString of the ports keeps its members.
+			port, ok := lookupPath(s, "spec.containers[*].ports[*].containerPort")
+			if !ok || !port.Type.Contains("integer") {
+				t.Errorf("expected the types to be kept, got %v", port)
+			}
+		})
+	}
+}
+
+func TestStripMetadataCombinators(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	member := func(typ string) spec.Schema {
+		s := scalarSchema(typ, "")
+		s.Description = typ + " member"
+		return s
+	}
+	size := spec.Schema{SchemaProps: spec.SchemaProps{
+		Description: "the size",
+		Title:       "Size",
+		OneOf:       []spec.Schema{member("integer"), member("string")},
+	}}
+	size.Example = 1
+	size.ExternalDocs = &spec.ExternalDocumentation{URL: "https://example.com/size"}
+	wrapped := spec.Schema{SchemaProps: spec.SchemaProps{
+		AllOf: []spec.Schema{*objectSchema(map[string]spec.Schema{"name": member("string")})},
+	}}
+	original := objectSchema(map[string]spec.Schema{"size": size, "wrapped": wrapped})
+	original.Description = "a widget"
+
+	s, err := StripMetadata(staticResolver{gvk: original}).ResolveSchema(gvk)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if paths := metadataPaths(s); len(paths) > 0 {
+		t.Errorf("expected no metadata, got some at %v", paths)
+	}
+	if got := len(s.Properties["size"].OneOf); got != 2 {
+		t.Errorf("expected the members of oneOf to be kept, got %d", got)
+	}
+	// the resolved schema is not mutated.
+	if original.Description != "a widget" || original.Properties["size"].OneOf[0].Description != "integer member" {
+		t.Errorf("expected the resolved schema to be kept, got %v", original)
+	}
+}
+
+func BenchmarkStripMetadata(b *testing.B) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	for _, bc := range []struct {
+		name  string
+		strip bool
+	}{
+		{name: "kept"},
+		{name: "stripped", strip: true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			r := &ClientDiscoveryResolver{
+				Discovery:  newEmbeddedDiscoveryResolver().Discovery,
+				RefOptions: PopulateRefsOptions{StripMetadata: bc.strip},
+			}
+			var s *spec.Schema
+			var err error
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if s, err = r.ResolveSchema(pod); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+			b.StopTimer()
+			encoded, err := json.Marshal(s)
+			if err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+			b.ReportMetric(float64(len(encoded)), "schema-bytes")
+		})
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ErrNotStructural is wrapped and returned by ValidateStructural if a
+// resolved schema is not structural.
+var ErrNotStructural = errors.New("schema is not structural")
+
+// ValidateStructural checks that a resolved schema is structural in the sense
+// of the apiserver, which CEL expects: every Ref is resolved, every node
+// declares a type unless it is x-kubernetes-int-or-string, an int-or-string,
+// a union of typed alternatives or preserves unknown fields, keywords that
+// structural schemas do not support are not set, and the value validations of
+// allOf, anyOf, oneOf and not do not declare types, defaults, descriptions or
+// nullability. It returns an error wrapping ErrNotStructural and the aggregate
+// of all violations found, each a field error annotated with the path of the
+// offending node in the notation of WalkSchema.
+func ValidateStructural(s *spec.Schema) error {
+	var errs field.ErrorList
+	_ = WalkSchema(s, func(path string, node *spec.Schema) error {
+		errs = append(errs, validateStructuralNode(structuralPath(path), node)...)
+		return nil
+	})
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrNotStructural, errs.ToAggregate())
+}
+
+// structuralPath returns the field path of a node at the given path of
+// WalkSchema.
+func structuralPath(path string) *field.Path {
+	if len(path) == 0 {
+		return field.NewPath("<root>")
+	}
+	return field.NewPath(path)
+}
+
+func validateStructuralNode(fldPath *field.Path, s *spec.Schema) field.ErrorList {
+	var errs field.ErrorList
+	errs = append(errs, validateStructuralKeywords(fldPath, s)...)
+	if isTypeless(s) {
+		errs = append(errs, field.Required(fldPath.Child("type"), "must not be empty for specified object fields"))
+	}
+	if len(s.Type) > 1 {
+		errs = append(errs, field.Invalid(fldPath.Child("type"), s.Type, "must be a single type"))
+	}
+	if len(s.Properties) > 0 && s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		errs = append(errs, field.Forbidden(fldPath.Child("additionalProperties"), "must not be set together with properties"))
+	}
+	errs = append(errs, validateJunctors(fldPath, s, len(s.Type) == 0)...)
+	return errs
+}
+
+// validateJunctors validates the value validations of allOf, anyOf, oneOf
+// and not of a node. If typedUnions is set, the alternatives of anyOf and
+// oneOf may declare a type, as the alternatives of a Quantity or an
+// IntOrString of a built-in type do, which stand in for the type of the node.
+func validateJunctors(fldPath *field.Path, s *spec.Schema, typedUnions bool) field.ErrorList {
+	var errs field.ErrorList
+	for i := range s.AllOf {
+		errs = append(errs, validateValueValidation(fldPath.Child("allOf").Index(i), &s.AllOf[i], false)...)
+	}
+	for i := range s.AnyOf {
+		errs = append(errs, validateValueValidation(fldPath.Child("anyOf").Index(i), &s.AnyOf[i], typedUnions && isTypedUnion(s.AnyOf))...)
+	}
+	for i := range s.OneOf {
+		errs = append(errs, validateValueValidation(fldPath.Child("oneOf").Index(i), &s.OneOf[i], typedUnions && isTypedUnion(s.OneOf))...)
+	}
+	if s.Not != nil {
+		errs = append(errs, validateValueValidation(fldPath.Child("not"), s.Not, false)...)
+	}
+	return errs
+}
+
+// validateStructuralKeywords checks that a node sets none of the keywords
+// that structural schemas do not support.
+func validateStructuralKeywords(fldPath *field.Path, s *spec.Schema) field.ErrorList {
+	var errs field.ErrorList
+	if s.Ref.GetURL() != nil {
+		errs = append(errs, field.Forbidden(fldPath.Child("$ref"), "must be resolved"))
+	}
+	if len(s.ID) > 0 {
+		errs = append(errs, field.Forbidden(fldPath.Child("id"), "id is not supported"))
+	}
+	if len(s.Schema) > 0 {
+		errs = append(errs, field.Forbidden(fldPath.Child("$schema"), "$schema is not supported"))
+	}
+	if len(s.Definitions) > 0 {
+		errs = append(errs, field.Forbidden(fldPath.Child("definitions"), "definitions are not supported"))
+	}
+	if len(s.PatternProperties) > 0 {
+		errs = append(errs, field.Forbidden(fldPath.Child("patternProperties"), "patternProperties are not supported"))
+	}
+	if s.AdditionalItems != nil {
+		errs = append(errs, field.Forbidden(fldPath.Child("additionalItems"), "additionalItems are not supported"))
+	}
+	if s.Items != nil && len(s.Items.Schemas) > 0 {
+		errs = append(errs, field.Forbidden(fldPath.Child("items"), "must be a single schema rather than a tuple"))
+	}
+	return errs
+}
+
+// validateValueValidation checks a schema nested in allOf, anyOf, oneOf or
+// not, which may only constrain the values of the node it is nested in, and
+// the schemas nested in it in turn. allowType permits the schema to declare a
+// type as an alternative of a typed union.
+func validateValueValidation(fldPath *field.Path, s *spec.Schema, allowType bool) field.ErrorList {
+	var errs field.ErrorList
+	errs = append(errs, validateStructuralKeywords(fldPath, s)...)
+	if len(s.Type) > 0 && !allowType {
+		errs = append(errs, field.Forbidden(fldPath.Child("type"), "must not be set in value validations"))
+	}
+	if s.Default != nil {
+		errs = append(errs, field.Forbidden(fldPath.Child("default"), "must not be set in value validations"))
+	}
+	if len(s.Description) > 0 {
+		errs = append(errs, field.Forbidden(fldPath.Child("description"), "must not be set in value validations"))
+	}
+	if s.Nullable {
+		errs = append(errs, field.Forbidden(fldPath.Child("nullable"), "must not be set in value validations"))
+	}
+	if s.AdditionalProperties != nil {
+		errs = append(errs, field.Forbidden(fldPath.Child("additionalProperties"), "must not be set in value validations"))
+	}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop := s.Properties[name]
+		errs = append(errs, validateValueValidation(fldPath.Child("properties").Key(name), &prop, false)...)
+	}
+	if s.Items != nil && s.Items.Schema != nil {
+		errs = append(errs, validateValueValidation(fldPath.Child("items"), s.Items.Schema, false)...)
+	}
+	errs = append(errs, validateJunctors(fldPath, s, false)...)
+	return errs
+}
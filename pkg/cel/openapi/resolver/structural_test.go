@@ -0,0 +1,161 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestValidateStructural(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		schema *spec.Schema
+		// wantErrs are the paths of the expected field errors, in order.
+		wantErrs []string
+	}{
+		{
+			name: "structural",
+			schema: objectSchema(map[string]spec.Schema{
+				"spec": *objectSchema(map[string]spec.Schema{
+					"replicas":   scalarSchema("integer", "int32"),
+					"containers": listMapSchema([]string{"name"}, "name", "image"),
+				}),
+			}),
+		},
+		{
+			name: "stand-ins for a type",
+			schema: objectSchema(map[string]spec.Schema{
+				"port":     {SchemaProps: spec.SchemaProps{Format: "int-or-string"}},
+				"quantity": {SchemaProps: spec.SchemaProps{OneOf: []spec.Schema{scalarSchema("string", ""), scalarSchema("number", "")}}},
+				"raw": func() spec.Schema {
+					var s spec.Schema
+					s.AddExtension(extPreserveUnknownFields, true)
+					return s
+				}(),
+			}),
+		},
+		{
+			name: "unresolved ref",
+			schema: objectSchema(map[string]spec.Schema{
+				"spec": {SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef("#/components/schemas/io.k8s.api.apps.v1.DeploymentSpec")}},
+			}),
+			wantErrs: []string{"spec.$ref", "spec.type"},
+		},
+		{
+			name: "missing types",
+			schema: objectSchema(map[string]spec.Schema{
+				"spec": *objectSchema(map[string]spec.Schema{
+					"replicas": {},
+				}),
+				"items": {SchemaProps: spec.SchemaProps{
+					Type:  []string{"array"},
+					Items: &spec.SchemaOrArray{Schema: &spec.Schema{}},
+				}},
+			}),
+			wantErrs: []string{"items[*].type", "spec.replicas.type"},
+		},
+		{
+			name: "disallowed keywords",
+			schema: &spec.Schema{SchemaProps: spec.SchemaProps{
+				Type:              []string{"object"},
+				PatternProperties: map[string]spec.Schema{"^x-": scalarSchema("string", "")},
+				Definitions:       spec.Definitions{"a": scalarSchema("string", "")},
+			}},
+			wantErrs: []string{"<root>.definitions", "<root>.patternProperties"},
+		},
+		{
+			name: "types in value validations",
+			schema: objectSchema(map[string]spec.Schema{
+				"replicas": {SchemaProps: spec.SchemaProps{
+					Type:  []string{"integer"},
+					AllOf: []spec.Schema{scalarSchema("integer", "")},
+				}},
+			}),
+			wantErrs: []string{"replicas.allOf[0].type"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateStructural(tc.schema)
+			if len(tc.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrNotStructural) {
+				t.Fatalf("expected ErrNotStructural, got %v", err)
+			}
+			var agg utilerrors.Aggregate
+			if !errors.As(err, &agg) {
+				t.Fatalf("expected an aggregate of field errors, got %v", err)
+			}
+			var fieldPaths []string
+			for _, err := range agg.Errors() {
+				var fieldErr *field.Error
+				if !errors.As(err, &fieldErr) {
+					t.Fatalf("expected a field error, got %v", err)
+				}
+				fieldPaths = append(fieldPaths, fieldErr.Field)
+			}
+			if !reflect.DeepEqual(fieldPaths, tc.wantErrs) {
+				t.Errorf("expected errors for %v, got %v", tc.wantErrs, err)
+			}
+		})
+	}
+}
+
+func TestValidateStructuralOption(t *testing.T) {
+	r := newEmbeddedDiscoveryResolver()
+	r.RefOptions.ValidateStructural = true
+	for _, gvk := range []schema.GroupVersionKind{
+		{Version: "v1", Kind: "Pod"},
+		{Group: "apps", Version: "v1", Kind: "Deployment"},
+	} {
+		if _, err := r.ResolveSchema(gvk); err != nil {
+			t.Errorf("expected the built-in %v to be structural, got %v", gvk, err)
+		}
+	}
+
+	gvk := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	subscription := withGVK(*objectSchema(map[string]spec.Schema{
+		"spec": {},
+	}), gvk)
+	r = newDocumentsDiscoveryResolver(map[string][]byte{
+		"apis/apps.clusternet.io/v1alpha1": newDocument(map[string]*spec.Schema{
+			"io.clusternet.apis.apps.v1alpha1.Subscription": &subscription,
+		}),
+	})
+	if _, err := r.ResolveSchema(gvk); err != nil {
+		t.Fatalf("expected no validation by default, got %v", err)
+	}
+	r.RefOptions.ValidateStructural = true
+	s, err := r.ResolveSchema(gvk)
+	if !errors.Is(err, ErrNotStructural) || !strings.Contains(err.Error(), "spec.type") {
+		t.Errorf("expected ErrNotStructural for spec.type, got %v", err)
+	}
+	if s != nil {
+		t.Errorf("expected no schema, got %v", s)
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ErrUnsupportedSubresource is wrapped and returned by
+// ResolveSubresourceSchema if the effective schema of a subresource is not
+// known.
+var ErrUnsupportedSubresource = errors.New("unsupported subresource")
+
+// scaleGVK is the kind served by the scale subresource of every resource
+// that has one.
+var scaleGVK = schema.GroupVersionKind{Group: "autoscaling", Version: "v1", Kind: "Scale"}
+
+// ResolveSubresourceSchema resolves the effective schema of the given
+// subresource of the resource of the given GVK, which may differ from the
+// schema of the resource itself. The scale subresource serves the
+// autoscaling/v1 Scale kind, whose schema is returned whatever the GVK; the
+// status subresource returns the status property of the schema of the GVK,
+// whose Refs are populated, or an error wrapping ErrFieldNotFound if it has
+// none. An empty subresource resolves the schema of the GVK itself. Other
+// subresources return an error wrapping ErrUnsupportedSubresource.
+func ResolveSubresourceSchema(r SchemaResolver, gvk schema.GroupVersionKind, subresource string) (*spec.Schema, error) {
+	switch subresource {
+	case "":
+		return r.ResolveSchema(gvk)
+	case "scale":
+		return r.ResolveSchema(scaleGVK)
+	case "status":
+		return ResolveSubSchema(r, gvk, "status")
+	default:
+		return nil, fmt.Errorf("cannot resolve subresource %q of %v: %w", subresource, gvk, ErrUnsupportedSubresource)
+	}
+}
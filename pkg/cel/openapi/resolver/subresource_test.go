@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestResolveSubresourceSchema(t *testing.T) {
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	configMap := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	r := staticResolver{
+		deployment: objectSchema(map[string]spec.Schema{
+			"spec":   *objectSchema(map[string]spec.Schema{"replicas": scalarSchema("integer", "int32")}),
+			"status": *objectSchema(map[string]spec.Schema{"readyReplicas": scalarSchema("integer", "int32")}),
+		}),
+		configMap: objectSchema(map[string]spec.Schema{"data": *objectSchema(nil)}),
+		scaleGVK: objectSchema(map[string]spec.Schema{
+			"spec": *objectSchema(map[string]spec.Schema{"replicas": scalarSchema("integer", "int32")}),
+		}),
+	}
+
+	for _, tc := range []struct {
+		name        string
+		gvk         schema.GroupVersionKind
+		subresource string
+		// wantProp is a property expected in the schema.
+		wantProp string
+		// wantErr is the error expected to be wrapped, if any.
+		wantErr error
+	}{
+		{name: "resource", gvk: deployment, wantProp: "status"},
+		{name: "scale", gvk: deployment, subresource: "scale", wantProp: "spec"},
+		{name: "status", gvk: deployment, subresource: "status", wantProp: "readyReplicas"},
+		{name: "no status", gvk: configMap, subresource: "status", wantErr: ErrFieldNotFound},
+		{name: "unsupported", gvk: deployment, subresource: "rollback", wantErr: ErrUnsupportedSubresource},
+		{name: "missing", gvk: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Missing"}, subresource: "status", wantErr: ErrSchemaNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := ResolveSubresourceSchema(r, tc.gvk, tc.subresource)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("expected %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok := s.Properties[tc.wantProp]; !ok {
+				t.Errorf("expected property %q, got %v", tc.wantProp, s)
+			}
+		})
+	}
+}
+
+func TestResolveSubresourceSchemaPopulatesStatus(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	s, err := ResolveSubresourceSchema(newEmbeddedDiscoveryResolver(), pod, "status")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conditions, ok := s.Properties["conditions"]
+	if !ok || conditions.Items == nil || conditions.Items.Schema == nil {
+		t.Fatalf("expected the conditions of the status, got %v", s)
+	}
+	if _, ok := conditions.Items.Schema.Properties["type"]; !ok {
+		t.Errorf("expected the Refs of the status to be populated, got %v", conditions.Items.Schema)
+	}
+}
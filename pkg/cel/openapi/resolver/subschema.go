@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ErrFieldNotFound is wrapped and returned by ResolveSubSchema if a segment
+// of the field path does not exist in the schema.
+var ErrFieldNotFound = errors.New("field not found")
+
+// indexPattern matches the indexing of an array or a map in a field path,
+// e.g. "[0]", "[*]" or "['app']".
+var indexPattern = regexp.MustCompile(`\[[^\]]*\]`)
+
+// ResolveSubSchema resolves the schema of the given GVK and returns the
+// sub-schema at the dot-separated field path, e.g.
+// "spec.template.spec.containers[*].image". An indexing segment, in brackets,
+// descends into the items of an array or the values of a map, whichever the
+// schema declares; so does a segment that is not a property of a map, which
+// is taken as a map key, e.g. "metadata.labels.app". An empty path returns
+// the whole schema. The returned sub-schema shares its nodes with the resolved
+// schema, which must not be mutated.
+func ResolveSubSchema(r SchemaResolver, gvk schema.GroupVersionKind, fieldPath string) (*spec.Schema, error) {
+	s, err := r.ResolveSchema(gvk)
+	if err != nil {
+		return nil, err
+	}
+	node := s
+	for _, segment := range splitPath(indexPattern.ReplaceAllString(fieldPath, "[*]")) {
+		if len(segment) == 0 {
+			continue
+		}
+		child, ok := subSchema(node, segment)
+		if !ok {
+			return nil, fmt.Errorf("cannot resolve field path %q of %v: %q: %w", fieldPath, gvk, segment, ErrFieldNotFound)
+		}
+		node = child
+	}
+	return node, nil
+}
+
+// subSchema returns the child of s denoted by a segment of a field path.
+func subSchema(s *spec.Schema, segment string) (*spec.Schema, bool) {
+	if segment != "[*]" {
+		if prop, ok := s.Properties[segment]; ok {
+			return &prop, true
+		}
+	} else if s.Items != nil && s.Items.Schema != nil {
+		return s.Items.Schema, true
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		return s.AdditionalProperties.Schema, true
+	}
+	return nil, false
+}
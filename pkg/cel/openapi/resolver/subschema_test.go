@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestResolveSubSchema(t *testing.T) {
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	r := newEmbeddedDiscoveryResolver()
+
+	for _, tc := range []struct {
+		name      string
+		fieldPath string
+		// wantProp is a property expected in the sub-schema.
+		wantProp string
+		// wantType is the expected type of the sub-schema.
+		wantType string
+		// wantSegment is the segment expected to be named by the error.
+		wantSegment string
+	}{
+		{name: "root", wantProp: "spec"},
+		{name: "array", fieldPath: "spec.template.spec.containers", wantType: "array"},
+		{name: "array items", fieldPath: "spec.template.spec.containers[*]", wantProp: "image"},
+		{name: "array index", fieldPath: "spec.template.spec.containers[0].ports[1]", wantProp: "containerPort"},
+		{name: "scalar", fieldPath: "spec.template.spec.containers[*].image", wantType: "string"},
+		{name: "map key", fieldPath: "metadata.labels.app", wantType: "string"},
+		{name: "map index", fieldPath: "metadata.labels['app']", wantType: "string"},
+		{name: "missing field", fieldPath: "spec.template.spec.nope.image", wantSegment: "nope"},
+		{name: "index of an object", fieldPath: "spec.template[0]", wantSegment: "[*]"},
+		{name: "field of a scalar", fieldPath: "spec.replicas.value", wantSegment: "value"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := ResolveSubSchema(r, deployment, tc.fieldPath)
+			if len(tc.wantSegment) > 0 {
+				if !errors.Is(err, ErrFieldNotFound) || !strings.Contains(err.Error(), `"`+tc.wantSegment+`"`) {
+					t.Errorf("expected ErrFieldNotFound naming %q, got %v", tc.wantSegment, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(tc.wantProp) > 0 {
+				if _, ok := s.Properties[tc.wantProp]; !ok {
+					t.Errorf("expected property %q with the Refs populated, got %v", tc.wantProp, s)
+				}
+			}
+			if len(tc.wantType) > 0 && !s.Type.Contains(tc.wantType) {
+				t.Errorf("expected type %q, got %v", tc.wantType, s.Type)
+			}
+		})
+	}
+
+	if _, err := ResolveSubSchema(r, schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Missing"}, "spec"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+
+	openapi_v2 "github.com/google/gnostic-models/openapiv2"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/openapi"
+	"k8s.io/client-go/openapi/openapitest"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// fakeDiscovery serves the given OpenAPI v3 client on top of the fake
+// discovery of client-go, whose OpenAPIV3 is not implemented, and the OpenAPI
+// v2 document openAPIV2, if set, or fails it with openAPIV2Err.
+type fakeDiscovery struct {
+	*fakediscovery.FakeDiscovery
+	openAPIV3    openapi.Client
+	openAPIV2    *openapi_v2.Document
+	openAPIV2Err error
+}
+
+func newFakeDiscovery(client openapi.Client) *fakeDiscovery {
+	return &fakeDiscovery{
+		FakeDiscovery: &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}},
+		openAPIV3:     client,
+	}
+}
+
+func (d *fakeDiscovery) OpenAPIV3() openapi.Client {
+	return d.openAPIV3
+}
+
+func (d *fakeDiscovery) OpenAPISchema() (*openapi_v2.Document, error) {
+	if d.openAPIV2Err != nil {
+		return nil, d.openAPIV2Err
+	}
+	if d.openAPIV2 == nil {
+		return d.FakeDiscovery.OpenAPISchema()
+	}
+	return d.openAPIV2, nil
+}
+
+// newEmbeddedDiscoveryResolver returns a ClientDiscoveryResolver that serves
+// the OpenAPI v3 documents embedded in client-go, which include, among others,
+// the core and the apps group-versions.
+func newEmbeddedDiscoveryResolver() *ClientDiscoveryResolver {
+	return &ClientDiscoveryResolver{Discovery: newFakeDiscovery(openapitest.NewEmbeddedFileClient())}
+}
+
+// staticResolver resolves schemas from a fixed map, for testing.
+type staticResolver map[schema.GroupVersionKind]*spec.Schema
+
+func (r staticResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	s, ok := r[gvk]
+	if !ok {
+		return nil, wrapResolutionError(gvk, ErrSchemaNotFound)
+	}
+	return s, nil
+}
+
+func (r staticResolver) ResolveSchemaWithContext(_ context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchema(gvk)
+}
+
+func objectSchema(props map[string]spec.Schema, required ...string) *spec.Schema {
+	return &spec.Schema{SchemaProps: spec.SchemaProps{
+		Type:       []string{"object"},
+		Properties: props,
+		Required:   required,
+	}}
+}
+
+func scalarSchema(typ, format string) spec.Schema {
+	return spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{typ}, Format: format}}
+}
+
+// withGVK adds the x-kubernetes-group-version-kind extension to the schema,
+// in the same shape as the OpenAPI definition namer produces.
+func withGVK(s spec.Schema, gvks ...schema.GroupVersionKind) spec.Schema {
+	ext := make([]any, 0, len(gvks))
+	for _, gvk := range gvks {
+		ext = append(ext, map[string]any{
+			"group":   gvk.Group,
+			"version": gvk.Version,
+			"kind":    gvk.Kind,
+		})
+	}
+	s.AddExtension(extGVK, ext)
+	return s
+}
+
+// countingResolver counts the calls to its delegate, for testing.
+type countingResolver struct {
+	delegate SchemaResolver
+	calls    map[schema.GroupVersionKind]int
+}
+
+func newCountingResolver(delegate SchemaResolver) *countingResolver {
+	return &countingResolver{delegate: delegate, calls: make(map[schema.GroupVersionKind]int)}
+}
+
+func (r *countingResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+func (r *countingResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	r.calls[gvk]++
+	return r.delegate.ResolveSchemaWithContext(ctx, gvk)
+}
+
+// newDocument returns an OpenAPI v3 document with the given component
+// schemas, as served by discovery.
+func newDocument(schemas map[string]*spec.Schema) []byte {
+	resp := new(schemaResponse)
+	resp.Components.Schemas = schemas
+	b, err := json.Marshal(resp)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// newDocumentsDiscoveryResolver returns a ClientDiscoveryResolver that serves
+// the given documents keyed by the group-version path.
+func newDocumentsDiscoveryResolver(docs map[string][]byte) *ClientDiscoveryResolver {
+	client := openapitest.NewFakeClient()
+	for path, doc := range docs {
+		client.PathsMap[path] = openapitest.FakeGroupVersion{GVSpec: doc}
+	}
+	return &ClientDiscoveryResolver{Discovery: newFakeDiscovery(client)}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// transformSchema recursively applies fn to the schema tree rooted at s and
+// returns the transformed tree. fn is applied in post-order: it is called
+// with a node whose properties, additionalProperties and items have already
+// been transformed, and returns the replacement for that node, or the node
+// itself if it should be kept unchanged.
+// The input is never mutated. Subtrees that fn leaves unchanged are shared
+// between the input and the result, and fn must copy a node before modifying it.
+func transformSchema(s *spec.Schema, fn func(s *spec.Schema) (*spec.Schema, error)) (*spec.Schema, error) {
+	result := *s
+	changed := false
+
+	if len(s.Properties) > 0 {
+		// the properties are only copied once one of them changes.
+		var props map[string]spec.Schema
+		for name, prop := range s.Properties {
+			transformed, err := transformSchema(&prop, fn)
+			if err != nil {
+				return nil, err
+			}
+			if transformed == &prop {
+				continue
+			}
+			if props == nil {
+				props = copyProperties(s.Properties)
+			}
+			props[name] = *transformed
+		}
+		if props != nil {
+			changed = true
+			result.Properties = props
+		}
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		transformed, err := transformSchema(s.AdditionalProperties.Schema, fn)
+		if err != nil {
+			return nil, err
+		}
+		if transformed != s.AdditionalProperties.Schema {
+			changed = true
+			additionalProperties := *s.AdditionalProperties
+			additionalProperties.Schema = transformed
+			result.AdditionalProperties = &additionalProperties
+		}
+	}
+	if s.Items != nil && s.Items.Schema != nil {
+		transformed, err := transformSchema(s.Items.Schema, fn)
+		if err != nil {
+			return nil, err
+		}
+		if transformed != s.Items.Schema {
+			changed = true
+			items := *s.Items
+			items.Schema = transformed
+			result.Items = &items
+		}
+	}
+	if changed {
+		// copied so that result is only allocated if the node changed.
+		node := result
+		return fn(&node)
+	}
+	return fn(s)
+}
+
+// copyProperties returns a shallow copy of the properties of a schema.
+func copyProperties(props map[string]spec.Schema) map[string]spec.Schema {
+	if props == nil {
+		return nil
+	}
+	result := make(map[string]spec.Schema, len(props))
+	for name, prop := range props {
+		result[name] = prop
+	}
+	return result
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// CollapseSingletonUnions returns a SchemaResolver that resolves schemas with
+// the given resolver and then replaces every node that merely wraps a single
+// member in oneOf or anyOf with that member. A node only wraps its member if
+// it declares no type, properties, items, additionalProperties or other
+// combinators of its own; its description and default are kept if the member
+// declares none.
+// Since this changes the structure of the schema, it is not applied by
+// default.
+func CollapseSingletonUnions(delegate SchemaResolver) SchemaResolver {
+	return &singletonUnionResolver{delegate: delegate}
+}
+
+type singletonUnionResolver struct {
+	delegate SchemaResolver
+}
+
+func (r *singletonUnionResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema, passing ctx
+// to the delegate.
+func (r *singletonUnionResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	s, err := r.delegate.ResolveSchemaWithContext(ctx, gvk)
+	if err != nil {
+		return nil, err
+	}
+	return transformSchema(s, collapseSingletonUnion)
+}
+
+func collapseSingletonUnion(s *spec.Schema) (*spec.Schema, error) {
+	var member spec.Schema
+	switch {
+	case len(s.OneOf) == 1 && len(s.AnyOf) == 0:
+		member = s.OneOf[0]
+	case len(s.AnyOf) == 1 && len(s.OneOf) == 0:
+		member = s.AnyOf[0]
+	default:
+		return s, nil
+	}
+	if len(s.Type) > 0 || len(s.Properties) > 0 || s.Items != nil || s.AdditionalProperties != nil || len(s.AllOf) > 0 || s.Not != nil {
+		return s, nil
+	}
+	// the member may be a wrapper itself, and its subtrees are not yet
+	// transformed. member is a copy, so the result may be modified.
+	collapsed, err := transformSchema(&member, collapseSingletonUnion)
+	if err != nil {
+		return nil, err
+	}
+	if len(collapsed.Description) == 0 {
+		collapsed.Description = s.Description
+	}
+	if collapsed.Default == nil {
+		collapsed.Default = s.Default
+	}
+	return collapsed, nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestCollapseSingletonUnions(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	member := *objectSchema(map[string]spec.Schema{"name": scalarSchema("string", "")})
+	single := spec.Schema{SchemaProps: spec.SchemaProps{
+		Description: "the feed",
+		AnyOf:       []spec.Schema{member},
+	}}
+	nested := spec.Schema{SchemaProps: spec.SchemaProps{
+		OneOf: []spec.Schema{{SchemaProps: spec.SchemaProps{AnyOf: []spec.Schema{scalarSchema("string", "")}}}},
+	}}
+	pair := spec.Schema{SchemaProps: spec.SchemaProps{
+		AnyOf: []spec.Schema{scalarSchema("integer", ""), scalarSchema("string", "")},
+	}}
+	typed := spec.Schema{SchemaProps: spec.SchemaProps{
+		Type:  []string{"object"},
+		AnyOf: []spec.Schema{member},
+	}}
+	delegate := staticResolver{gvk: objectSchema(map[string]spec.Schema{
+		"feed": single,
+		"spec": *objectSchema(map[string]spec.Schema{
+			"name":    nested,
+			"port":    pair,
+			"feeds":   {SchemaProps: spec.SchemaProps{Type: []string{"array"}, Items: &spec.SchemaOrArray{Schema: &single}}},
+			"overlay": typed,
+		}),
+	})}
+
+	s, err := CollapseSingletonUnions(delegate).ResolveSchema(gvk)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedFeed := member
+	expectedFeed.Description = "the feed"
+	if feed := s.Properties["feed"]; !reflect.DeepEqual(feed, expectedFeed) {
+		t.Errorf("expected single-member anyOf to collapse to %v, got %v", expectedFeed, feed)
+	}
+	if items := s.Properties["spec"].Properties["feeds"].Items.Schema; !reflect.DeepEqual(*items, expectedFeed) {
+		t.Errorf("expected single-member anyOf in items to collapse to %v, got %v", expectedFeed, items)
+	}
+	if name := s.Properties["spec"].Properties["name"]; !reflect.DeepEqual(name, scalarSchema("string", "")) {
+		t.Errorf("expected nested singleton unions to collapse, got %v", name)
+	}
+	if port := s.Properties["spec"].Properties["port"]; !reflect.DeepEqual(port, pair) {
+		t.Errorf("expected two-member anyOf to be left intact, got %v", port)
+	}
+	if overlay := s.Properties["spec"].Properties["overlay"]; !reflect.DeepEqual(overlay, typed) {
+		t.Errorf("expected a union next to a type to be left intact, got %v", overlay)
+	}
+	if feed := delegate[gvk].Properties["feed"]; len(feed.AnyOf) != 1 {
+		t.Errorf("expected the delegate's schema not to be mutated")
+	}
+}
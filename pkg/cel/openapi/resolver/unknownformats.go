@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// knownFormats are the formats that are recognized by the CEL type adapter or
+// defined by OpenAPI for numbers.
+var knownFormats = sets.New(
+	"byte", "date", "date-time", "duration", "int-or-string",
+	"int32", "int64", "float", "double",
+)
+
+// ErrUnknownFormat is wrapped and returned by UnknownFormatResolver with
+// UnknownFormatError if a schema declares a format that is not known.
+var ErrUnknownFormat = errors.New("unknown format")
+
+// UnknownFormatPolicy controls how UnknownFormatResolver treats formats that
+// are not known, e.g. new formats served by the apiservers of member clusters.
+type UnknownFormatPolicy int
+
+const (
+	// UnknownFormatKeep preserves unknown formats.
+	UnknownFormatKeep UnknownFormatPolicy = iota
+	// UnknownFormatDrop clears unknown formats, so that the fields are treated
+	// as plain values of their type.
+	UnknownFormatDrop
+	// UnknownFormatError fails the resolution of schemas with unknown formats.
+	UnknownFormatError
+)
+
+// UnknownFormatResolver wraps a SchemaResolver and applies Policy to the
+// unknown formats of the resolved schemas. The formats recognized by the CEL
+// type adapter, i.e. byte, date, date-time, duration and int-or-string, and
+// the OpenAPI number formats int32, int64, float and double are known, as are
+// those in Formats.
+type UnknownFormatResolver struct {
+	Delegate SchemaResolver
+	Policy   UnknownFormatPolicy
+	// Formats are additional known formats.
+	Formats sets.Set[string]
+}
+
+var _ SchemaResolver = (*UnknownFormatResolver)(nil)
+
+func (r *UnknownFormatResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema, passing ctx
+// to the delegate.
+func (r *UnknownFormatResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	s, err := r.Delegate.ResolveSchemaWithContext(ctx, gvk)
+	if err != nil {
+		return nil, err
+	}
+	switch r.Policy {
+	case UnknownFormatDrop:
+		return transformSchema(s, func(node *spec.Schema) (*spec.Schema, error) {
+			if r.isKnown(node.Format) {
+				return node, nil
+			}
+			result := *node
+			result.Format = ""
+			return &result, nil
+		})
+	case UnknownFormatError:
+		err := WalkSchema(s, func(path string, node *spec.Schema) error {
+			if !r.isKnown(node.Format) {
+				return fmt.Errorf("field %q: %w %q", path, ErrUnknownFormat, node.Format)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("schema of %v is invalid: %w", gvk, err)
+		}
+	}
+	return s, nil
+}
+
+func (r *UnknownFormatResolver) isKnown(format string) bool {
+	return len(format) == 0 || knownFormats.Has(format) || r.Formats.Has(format)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestUnknownFormatResolver(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	delegate := staticResolver{gvk: objectSchema(map[string]spec.Schema{
+		"spec": *objectSchema(map[string]spec.Schema{
+			"window":   scalarSchema("string", "x-fictional-interval"),
+			"deadline": scalarSchema("string", "date-time"),
+			"replicas": scalarSchema("integer", "int32"),
+		}),
+	})}
+
+	for _, tc := range []struct {
+		name           string
+		policy         UnknownFormatPolicy
+		formats        sets.Set[string]
+		expectedFormat string
+		wantErr        error
+	}{
+		{name: "keep", policy: UnknownFormatKeep, expectedFormat: "x-fictional-interval"},
+		{name: "drop", policy: UnknownFormatDrop, expectedFormat: ""},
+		{name: "error", policy: UnknownFormatError, wantErr: ErrUnknownFormat},
+		{name: "error with additional known format", policy: UnknownFormatError, formats: sets.New("x-fictional-interval"), expectedFormat: "x-fictional-interval"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &UnknownFormatResolver{Delegate: delegate, Policy: tc.policy, Formats: tc.formats}
+			s, err := r.ResolveSchema(gvk)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			specProps := s.Properties["spec"].Properties
+			if got := specProps["window"].Format; got != tc.expectedFormat {
+				t.Errorf("expected format %q, got %q", tc.expectedFormat, got)
+			}
+			if got := specProps["deadline"].Format; got != "date-time" {
+				t.Errorf("expected known format to be kept, got %q", got)
+			}
+			if got := specProps["replicas"].Format; got != "int32" {
+				t.Errorf("expected known format to be kept, got %q", got)
+			}
+			if got := delegate[gvk].Properties["spec"].Properties["window"].Format; got != "x-fictional-interval" {
+				t.Errorf("expected the delegate's schema not to be mutated, got format %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/yaml"
+)
+
+// v2Path is the key of the OpenAPI v2 document in the document cache, which
+// is never the resource path of a group-version.
+const v2Path = "openapi/v2"
+
+// definitionsRefPrefix is the prefix of the Refs of OpenAPI v2 documents, the
+// counterpart of refPrefix.
+const definitionsRefPrefix = "#/definitions/"
+
+// v2Response is the part of an OpenAPI v2 document that the resolver uses.
+type v2Response struct {
+	Definitions map[string]*spec.Schema `json:"definitions"`
+}
+
+// resolveFromV2 resolves the schema of gvk from the OpenAPI v2 document of
+// Discovery, as with AllowV2Fallback.
+func (r *ClientDiscoveryResolver) resolveFromV2(ctx context.Context, gvk schema.GroupVersionKind, diag *ResolveDiagnostics) (*spec.Schema, ResolveSource, error) {
+	s, err := r.resolveFromV2Document(ctx, gvk, diag)
+	if errors.Is(err, ErrSchemaNotFound) && r.refresh(gvk.GroupVersion()) {
+		s, err = r.resolveFromV2Document(ctx, gvk, diag)
+	}
+	if err != nil {
+		return nil, ResolveSource{}, err
+	}
+	return s, ResolveSource{GVK: gvk, Origin: DiagnosticSourceDiscovery, Endpoint: v2Endpoint, SpecVersion: SpecVersionV2}, nil
+}
+
+// resolveFromV2Document resolves the schema of gvk from the OpenAPI v2
+// document of Discovery, without retrying on a miss.
+func (r *ClientDiscoveryResolver) resolveFromV2Document(ctx context.Context, gvk schema.GroupVersionKind, diag *ResolveDiagnostics) (*spec.Schema, error) {
+	resp, size, err := r.v2Document(ctx)
+	if err != nil {
+		return nil, err
+	}
+	schemaOf := func(ref string) (*spec.Schema, bool) {
+		s, ok := resp.Components.Schemas[schemaName(ref, r.RefPrefixes...)]
+		return s, ok
+	}
+	return r.resolveFromDocument(ctx, resp, size, schemaOf, gvk, diag)
+}
+
+// v2Document returns the OpenAPI v2 document of Discovery, with its
+// definitions as the component schemas, fetching it if it is not cached. Its
+// size is that of its JSON form, since client-go parses the protobuf form.
+func (r *ClientDiscoveryResolver) v2Document(ctx context.Context) (*schemaResponse, int64, error) {
+	caching := r.cachesDocuments()
+	if caching {
+		r.lock.Lock()
+		cached, ok := r.documents[v2Path]
+		fresh := ok && r.now().Sub(cached.fetched) < r.DocumentCacheTTL
+		r.lock.Unlock()
+		if fresh {
+			return cached.resp, cached.size, nil
+		}
+	}
+	b, err := r.fetch(ctx, "the OpenAPI v2 document", func() ([]byte, error) {
+		doc, err := r.Discovery.OpenAPISchema()
+		if err != nil {
+			return nil, fmt.Errorf("cannot fetch the OpenAPI v2 document: %w: %w", ErrDiscoveryUnavailable, err)
+		}
+		y, err := doc.YAMLValue("")
+		if err != nil {
+			return nil, err
+		}
+		return yaml.YAMLToJSON(y)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	v2 := new(v2Response)
+	if err := json.Unmarshal(b, v2); err != nil {
+		return nil, 0, err
+	}
+	resp := new(schemaResponse)
+	resp.Components.Schemas = v2.Definitions
+	if caching {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		if r.documents == nil {
+			r.documents = make(map[string]*cachedDocument)
+		}
+		r.documents[v2Path] = &cachedDocument{resp: resp, size: int64(len(b)), fetched: r.now()}
+	}
+	return resp, int64(len(b)), nil
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	openapi_v2 "github.com/google/gnostic-models/openapiv2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/openapi/openapitest"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+// v2Document is an OpenAPI v2 document with the definitions of Pod, whose
+// Refs use the v2 prefix.
+const v2Document = `{
+  "swagger": "2.0",
+  "info": {"title": "Kubernetes", "version": "v1.14.0"},
+  "paths": {},
+  "definitions": {
+    "io.k8s.api.core.v1.Pod": {
+      "type": "object",
+      "properties": {
+        "spec": {"$ref": "#/definitions/io.k8s.api.core.v1.PodSpec"}
+      },
+      "x-kubernetes-group-version-kind": [{"group": "", "version": "v1", "kind": "Pod"}]
+    },
+    "io.k8s.api.core.v1.PodSpec": {
+      "type": "object",
+      "properties": {
+        "nodeName": {"type": "string"}
+      }
+    }
+  }
+}`
+
+func isSchemaNotFound(err error) bool {
+	return errors.Is(err, ErrSchemaNotFound)
+}
+
+func TestClientDiscoveryResolverV2Fallback(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	doc, err := openapi_v2.ParseDocument([]byte(v2Document))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	notServed := apierrors.NewNotFound(schema.GroupResource{}, "openapi/v3")
+
+	for _, tc := range []struct {
+		name     string
+		fallback bool
+		// pathsErr is the error of listing the OpenAPI v3 paths.
+		pathsErr error
+		// docs are the OpenAPI v3 documents by group-version path.
+		docs map[string][]byte
+		// wantErr checks the expected error, if any.
+		wantErr func(error) bool
+		// wantV2 is whether the schema is expected to come from v2.
+		wantV2 bool
+	}{
+		{name: "v3 not served", fallback: true, pathsErr: notServed, wantV2: true},
+		{name: "v3 not served without fallback", pathsErr: notServed, wantErr: apierrors.IsNotFound},
+		{
+			name:     "group-version not served by v3",
+			fallback: true,
+			docs:     map[string][]byte{"apis/apps/v1": newDocument(nil)},
+			wantV2:   true,
+		},
+		{
+			name:    "group-version not served by v3 without fallback",
+			docs:    map[string][]byte{"apis/apps/v1": newDocument(nil)},
+			wantErr: isSchemaNotFound,
+		},
+		{
+			name:     "kind not served by v3",
+			fallback: true,
+			docs:     map[string][]byte{"api/v1": newDocument(nil)},
+			wantErr:  isSchemaNotFound,
+		},
+		{
+			name:     "v3 served",
+			fallback: true,
+			docs: map[string][]byte{"api/v1": newDocument(map[string]*spec.Schema{
+				"io.k8s.api.core.v1.Pod": func() *spec.Schema {
+					s := withGVK(*objectSchema(nil), pod)
+					return &s
+				}(),
+			})},
+		},
+		{
+			name:     "other errors",
+			fallback: true,
+			pathsErr: apierrors.NewInternalError(errors.New("boom")),
+			wantErr:  apierrors.IsInternalError,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := openapitest.NewFakeClient()
+			client.ForcedErr = tc.pathsErr
+			for path, doc := range tc.docs {
+				client.PathsMap[path] = openapitest.FakeGroupVersion{GVSpec: doc}
+			}
+			d := newFakeDiscovery(client)
+			d.openAPIV2 = doc
+			r := &ClientDiscoveryResolver{Discovery: d, AllowV2Fallback: tc.fallback}
+			s, source, err := r.ResolveSchemaWithSource(pod)
+			if tc.wantErr != nil {
+				if !tc.wantErr(err) {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_, fromV2 := s.Properties["spec"].Properties["nodeName"]
+			if fromV2 != tc.wantV2 {
+				t.Errorf("expected the schema from v2 to be %v with its Refs populated, got %v", tc.wantV2, s)
+			}
+			expected := ResolveSource{GVK: pod, Origin: DiagnosticSourceDiscovery, Endpoint: "/openapi/v3/api/v1", SpecVersion: SpecVersionV3}
+			if tc.wantV2 {
+				expected.Endpoint, expected.SpecVersion = "/openapi/v2", SpecVersionV2
+			}
+			if source != expected {
+				t.Errorf("expected source %v, got %v", expected, source)
+			}
+		})
+	}
+}
+
+func TestClientDiscoveryResolverV2RefreshOnMiss(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	service := schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	stale, err := openapi_v2.ParseDocument([]byte(v2Document))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fresh, err := openapi_v2.ParseDocument([]byte(strings.Replace(v2Document, `"definitions": {`, `"definitions": {
+    "io.k8s.api.core.v1.Service": {
+      "type": "object",
+      "x-kubernetes-group-version-kind": [{"group": "", "version": "v1", "kind": "Service"}]
+    },`, 1)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := openapitest.NewFakeClient()
+	client.ForcedErr = apierrors.NewNotFound(schema.GroupResource{}, "openapi/v3")
+	d := newFakeDiscovery(client)
+	d.openAPIV2 = stale
+	fakeClock := testingclock.NewFakePassiveClock(time.Now())
+	r := &ClientDiscoveryResolver{Discovery: d, AllowV2Fallback: true, DocumentCacheTTL: time.Hour, clock: fakeClock}
+
+	if _, err := r.ResolveSchema(pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the Service is installed after the v2 document was cached.
+	d.openAPIV2 = fresh
+	if _, err := r.ResolveSchema(service); !errors.Is(err, ErrSchemaNotFound) {
+		t.Fatalf("expected ErrSchemaNotFound from the cached document without RefreshOnMiss, got %v", err)
+	}
+	r.RefreshOnMiss = true
+	s, source, err := r.ResolveSchemaWithSource(service)
+	if err != nil {
+		t.Fatalf("expected the Service to resolve after a refresh, got %v", err)
+	}
+	if s == nil || source.SpecVersion != SpecVersionV2 {
+		t.Errorf("expected the schema from the v2 document, got %v from %v", s, source)
+	}
+
+	// misses within the interval are served from the cached document.
+	d.openAPIV2 = stale
+	missing := schema.GroupVersionKind{Version: "v1", Kind: "Missing"}
+	if _, err := r.ResolveSchema(missing); !errors.Is(err, ErrSchemaNotFound) {
+		t.Fatalf("expected ErrSchemaNotFound, got %v", err)
+	}
+	if _, err := r.ResolveSchema(service); err != nil {
+		t.Errorf("expected the cached document not to be refreshed again within the interval, got %v", err)
+	}
+}
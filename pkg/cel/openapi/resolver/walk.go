@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// WalkSchema performs a deterministic depth-first walk over a resolved schema
+// and calls visit with the path and the schema of each node, starting with the
+// root whose path is empty. Properties are visited in sorted order and their
+// paths are dot-separated, e.g. "spec.replicas"; the items of an array and the
+// additionalProperties of a map are denoted by "[*]", e.g. "spec.containers[*]".
+// The walk stops at the first error returned by visit, which is returned.
+// Nodes must not be mutated by visit.
+func WalkSchema(s *spec.Schema, visit func(path string, node *spec.Schema) error) error {
+	return walkSchema("", s, visit)
+}
+
+// errSkipNode is returned by visit functions internal to this package to skip
+// the descendants of a node without stopping the walk.
+var errSkipNode = errors.New("skip node")
+
+// errStopWalk is returned by visit functions internal to this package to stop
+// the walk early.
+var errStopWalk = errors.New("stop walk")
+
+func walkSchema(path string, s *spec.Schema, visit func(path string, node *spec.Schema) error) error {
+	if err := visit(path, s); err != nil {
+		if err == errSkipNode {
+			return nil
+		}
+		return err
+	}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop := s.Properties[name]
+		if err := walkSchema(childPath(path, name), &prop, visit); err != nil {
+			return err
+		}
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		if err := walkSchema(path+"[*]", s.AdditionalProperties.Schema, visit); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil && s.Items.Schema != nil {
+		if err := walkSchema(path+"[*]", s.Items.Schema, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupPath returns the node at the given path of a schema, in the notation
+// of WalkSchema.
+func lookupPath(s *spec.Schema, path string) (*spec.Schema, bool) {
+	var found *spec.Schema
+	_ = WalkSchema(s, func(p string, node *spec.Schema) error {
+		if p == path {
+			found = node
+			return errStopWalk
+		}
+		if !strings.HasPrefix(path, p) {
+			return errSkipNode
+		}
+		return nil
+	})
+	return found, found != nil
+}
+
+func childPath(path, name string) string {
+	if len(path) == 0 {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestWalkSchema(t *testing.T) {
+	s := objectSchema(map[string]spec.Schema{
+		"metadata": *objectSchema(map[string]spec.Schema{
+			"name": scalarSchema("string", ""),
+			"labels": {SchemaProps: spec.SchemaProps{
+				Type:                 []string{"object"},
+				AdditionalProperties: &spec.SchemaOrBool{Allows: true, Schema: &spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"string"}}}},
+			}},
+		}),
+		"spec": *objectSchema(map[string]spec.Schema{
+			"containers": {SchemaProps: spec.SchemaProps{
+				Type: []string{"array"},
+				Items: &spec.SchemaOrArray{Schema: objectSchema(map[string]spec.Schema{
+					"name":  scalarSchema("string", ""),
+					"image": scalarSchema("string", ""),
+				})},
+			}},
+			"replicas": scalarSchema("integer", "int32"),
+		}),
+	})
+
+	var visited []string
+	err := WalkSchema(s, func(path string, _ *spec.Schema) error {
+		visited = append(visited, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"",
+		"metadata",
+		"metadata.labels",
+		"metadata.labels[*]",
+		"metadata.name",
+		"spec",
+		"spec.containers",
+		"spec.containers[*]",
+		"spec.containers[*].image",
+		"spec.containers[*].name",
+		"spec.replicas",
+	}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("expected paths %v, got %v", expected, visited)
+	}
+
+	stop := errors.New("stop")
+	visited = nil
+	err = WalkSchema(s, func(path string, _ *spec.Schema) error {
+		visited = append(visited, path)
+		if path == "metadata.labels" {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("expected the error of visit, got %v", err)
+	}
+	if len(visited) != 3 {
+		t.Errorf("expected the walk to stop at the first error, visited %v", visited)
+	}
+}
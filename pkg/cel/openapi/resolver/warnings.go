@@ -0,0 +1,107 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// WarningResolver wraps a SchemaResolver and reports the non-structural
+// constructs of the resolved schemas as warnings, without failing the
+// resolution like StrictResolver does. This gives actionable feedback on
+// questionable schemas, e.g. those federated from member clusters.
+type WarningResolver struct {
+	Delegate SchemaResolver
+}
+
+var _ SchemaResolver = (*WarningResolver)(nil)
+
+// ResolveSchema resolves the schema with the delegate, ignoring warnings.
+func (r *WarningResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.ResolveSchemaWithContext(context.Background(), gvk)
+}
+
+// ResolveSchemaWithContext resolves the schema like ResolveSchema, passing ctx
+// to the delegate.
+func (r *WarningResolver) ResolveSchemaWithContext(ctx context.Context, gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	return r.Delegate.ResolveSchemaWithContext(ctx, gvk)
+}
+
+// ResolveSchemaWithWarnings resolves the schema with the delegate and also
+// returns its SchemaWarnings.
+func (r *WarningResolver) ResolveSchemaWithWarnings(gvk schema.GroupVersionKind) (*spec.Schema, []string, error) {
+	s, err := r.Delegate.ResolveSchema(gvk)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, SchemaWarnings(s), nil
+}
+
+// SchemaWarnings returns warnings about the non-structural constructs of a
+// resolved schema, in the order of WalkSchema: a oneOf or anyOf at the root,
+// nodes that declare no type, and refs left unresolved. Each warning has the
+// form `field "<path>": <message>`, with the path in the notation of
+// WalkSchema.
+func SchemaWarnings(s *spec.Schema) []string {
+	var warnings []string
+	if len(s.OneOf) > 0 {
+		warnings = append(warnings, fmt.Sprintf("field %q: oneOf at the root", ""))
+	}
+	if len(s.AnyOf) > 0 {
+		warnings = append(warnings, fmt.Sprintf("field %q: anyOf at the root", ""))
+	}
+	_ = WalkSchema(s, func(path string, node *spec.Schema) error {
+		if ref, isRef := refOf(node); isRef {
+			warnings = append(warnings, fmt.Sprintf("field %q: unresolved ref %q", path, ref))
+		} else if isTypeless(node) {
+			warnings = append(warnings, fmt.Sprintf("field %q: no type", path))
+		}
+		return nil
+	})
+	return warnings
+}
+
+// isTypeless checks if the schema declares no type and is neither an
+// int-or-string, a union of typed alternatives, such as a Quantity, nor
+// preserves unknown fields, which all stand in for a type.
+func isTypeless(s *spec.Schema) bool {
+	if len(s.Type) > 0 || s.Format == "int-or-string" {
+		return false
+	}
+	if isTypedUnion(s.OneOf) || isTypedUnion(s.AnyOf) {
+		return false
+	}
+	if intOrString, ok := s.Extensions.GetBool(extIntOrString); ok && intOrString {
+		return false
+	}
+	preserve, ok := s.Extensions.GetBool(extPreserveUnknownFields)
+	return !ok || !preserve
+}
+
+// isTypedUnion checks if there are alternatives and all of them declare a type.
+func isTypedUnion(alternatives []spec.Schema) bool {
+	for _, alternative := range alternatives {
+		if len(alternative.Type) == 0 {
+			return false
+		}
+	}
+	return len(alternatives) > 0
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestResolveSchemaWithWarnings(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps.clusternet.io", Version: "v1alpha1", Kind: "Subscription"}
+	preserved := spec.Schema{}
+	preserved.AddExtension(extPreserveUnknownFields, true)
+	s := objectSchema(map[string]spec.Schema{
+		"spec": *objectSchema(map[string]spec.Schema{
+			"anything": {},
+			"port":     {SchemaProps: spec.SchemaProps{Format: "int-or-string"}},
+			"raw":      preserved,
+			"template": {SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef("#/components/schemas/io.k8s.api.core.v1.PodTemplateSpec")}},
+		}),
+	})
+	s.OneOf = []spec.Schema{*objectSchema(nil, "spec"), *objectSchema(nil, "status")}
+	r := &WarningResolver{Delegate: staticResolver{gvk: s}}
+
+	resolved, warnings, err := r.ResolveSchemaWithWarnings(gvk)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved != s {
+		t.Errorf("expected the schema of the delegate, got %v", resolved)
+	}
+	expected := []string{
+		`field "": oneOf at the root`,
+		`field "spec.anything": no type`,
+		`field "spec.template": unresolved ref "#/components/schemas/io.k8s.api.core.v1.PodTemplateSpec"`,
+	}
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("expected warnings %q, got %q", expected, warnings)
+	}
+}
+
+func TestSchemaWarningsOfBuiltinTypes(t *testing.T) {
+	r := &WarningResolver{Delegate: newEmbeddedDiscoveryResolver()}
+	for _, gvk := range workloadKinds {
+		_, warnings, err := r.ResolveSchemaWithWarnings(gvk)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(warnings) > 0 {
+			t.Errorf("expected no warnings for %v, got %q", gvk, warnings)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/kube-openapi/pkg/handler3"
+)
+
+// HashFunc returns the current hash of the OpenAPI document of each
+// group-version, keyed by the path of the group-version, e.g. "apis/apps/v1".
+type HashFunc func() (map[string]string, error)
+
+// GroupVersionInvalidator is implemented by caches that can drop their
+// entries for a group-version, e.g. CachingResolver.
+type GroupVersionInvalidator interface {
+	InvalidateGroupVersion(gv schema.GroupVersion)
+}
+
+// OpenAPIV3IndexHashes returns a HashFunc that reads the hashes from the
+// OpenAPI v3 index of the server, which is cheap to fetch compared to the
+// documents themselves.
+func OpenAPIV3IndexHashes(d discovery.DiscoveryInterface) HashFunc {
+	return func() (map[string]string, error) {
+		b, err := d.RESTClient().Get().AbsPath("/openapi/v3").Do(context.TODO()).Raw()
+		if err != nil {
+			return nil, err
+		}
+		index := new(handler3.OpenAPIV3Discovery)
+		if err := json.Unmarshal(b, index); err != nil {
+			return nil, err
+		}
+		hashes := make(map[string]string, len(index.Paths))
+		for path, gv := range index.Paths {
+			u, err := url.Parse(gv.ServerRelativeURL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid URL of %q: %w", path, err)
+			}
+			hashes[path] = u.Query().Get("hash")
+		}
+		return hashes, nil
+	}
+}
+
+// DiscoveryWatcher polls the hashes of the OpenAPI documents and, when the
+// document of a group-version changes, invalidates the entries of that
+// group-version in the given caches. Entries of unchanged group-versions
+// are kept.
+type DiscoveryWatcher struct {
+	hashes HashFunc
+	caches []GroupVersionInvalidator
+
+	// last is only accessed by the polling goroutine.
+	last map[string]string
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDiscoveryWatcher creates a DiscoveryWatcher and starts polling the
+// hashes every interval in the background until Close is called.
+func NewDiscoveryWatcher(hashes HashFunc, interval time.Duration, caches ...GroupVersionInvalidator) *DiscoveryWatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &DiscoveryWatcher{
+		hashes: hashes,
+		caches: caches,
+		cancel: cancel,
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		wait.UntilWithContext(ctx, func(context.Context) {
+			w.check()
+		}, interval)
+	}()
+	return w
+}
+
+// Close stops the watcher and waits for the polling to terminate.
+func (w *DiscoveryWatcher) Close() {
+	w.cancel()
+	w.wg.Wait()
+}
+
+// check fetches the hashes and invalidates the group-versions whose hash
+// changed, appeared, or disappeared since the previous check. The first
+// check only records the hashes.
+func (w *DiscoveryWatcher) check() {
+	hashes, err := w.hashes()
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("cannot check OpenAPI document hashes: %w", err))
+		return
+	}
+	if w.last != nil {
+		for path, hash := range hashes {
+			if last, ok := w.last[path]; !ok || last != hash {
+				w.invalidate(path)
+			}
+		}
+		for path := range w.last {
+			if _, ok := hashes[path]; !ok {
+				w.invalidate(path)
+			}
+		}
+	}
+	w.last = hashes
+}
+
+func (w *DiscoveryWatcher) invalidate(path string) {
+	gv, ok := gvFromResourcePath(path)
+	if !ok {
+		return
+	}
+	for _, c := range w.caches {
+		c.InvalidateGroupVersion(gv)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/openapi/openapitest"
+)
+
+func TestDiscoveryWatcher(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	job := schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}
+	delegate := newCountingResolver(staticResolver{
+		pod:        objectSchema(nil),
+		deployment: objectSchema(nil),
+		job:        objectSchema(nil),
+	})
+	c := NewCachingResolver(delegate)
+	if err := c.Warm([]schema.GroupVersionKind{pod, deployment, job}); err != nil {
+		t.Fatal(err)
+	}
+
+	hashes := map[string]string{"api/v1": "a", "apis/apps/v1": "b", "apis/batch/v1": "c"}
+	w := &DiscoveryWatcher{
+		hashes: func() (map[string]string, error) {
+			result := make(map[string]string, len(hashes))
+			for k, v := range hashes {
+				result[k] = v
+			}
+			return result, nil
+		},
+		caches: []GroupVersionInvalidator{c},
+	}
+	w.check()
+	hashes["apis/apps/v1"] = "changed"
+	w.check()
+
+	for _, gvk := range []schema.GroupVersionKind{pod, deployment, job} {
+		if _, err := c.ResolveSchema(gvk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := map[schema.GroupVersionKind]int{pod: 1, deployment: 2, job: 1}
+	for gvk, calls := range expected {
+		if delegate.calls[gvk] != calls {
+			t.Errorf("expected %d resolutions of %v, got %d", calls, gvk, delegate.calls[gvk])
+		}
+	}
+
+	// the background watcher stops on Close.
+	polled := make(chan struct{}, 1)
+	bg := NewDiscoveryWatcher(func() (map[string]string, error) {
+		select {
+		case polled <- struct{}{}:
+		default:
+		}
+		return map[string]string{}, nil
+	}, time.Millisecond, c)
+	<-polled
+	bg.Close()
+}
+
+func TestDiscoveryWatcherDocumentCache(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	client := &countingOpenAPIClient{delegate: openapitest.NewEmbeddedFileClient()}
+	r := &ClientDiscoveryResolver{Discovery: newFakeDiscovery(client), DocumentCacheTTL: time.Hour}
+	c := NewCachingResolver(r)
+	if err := c.Warm([]schema.GroupVersionKind{pod, deployment}); err != nil {
+		t.Fatal(err)
+	}
+	paths, schemas := client.paths.Load(), client.schemas.Load()
+
+	hashes := map[string]string{"api/v1": "a", "apis/apps/v1": "b"}
+	w := &DiscoveryWatcher{
+		hashes: func() (map[string]string, error) {
+			result := make(map[string]string, len(hashes))
+			for k, v := range hashes {
+				result[k] = v
+			}
+			return result, nil
+		},
+		// the documents are dropped before the schemas resolved from them.
+		caches: []GroupVersionInvalidator{r, c},
+	}
+	w.check()
+	hashes["apis/apps/v1"] = "changed"
+	w.check()
+
+	for _, gvk := range []schema.GroupVersionKind{pod, deployment} {
+		if _, err := c.ResolveSchema(gvk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// only the paths and the document of apps/v1 are fetched again.
+	if got := client.paths.Load() - paths; got != 1 {
+		t.Errorf("expected 1 listing of the paths, got %d", got)
+	}
+	if got := client.schemas.Load() - schemas; got != 1 {
+		t.Errorf("expected 1 fetched document, got %d", got)
+	}
+	if _, err := r.ResolveSchema(pod); err != nil {
+		t.Fatal(err)
+	}
+	if got := client.schemas.Load() - schemas; got != 1 {
+		t.Errorf("expected the document of v1 to remain cached, got %d fetched documents", got)
+	}
+}